@@ -0,0 +1,220 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/token"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// signatureFee returns the base fee of the instructions in lamports, priority fees are not included.
+func (a *SolanaApi) signatureFee(ctx context.Context, c *client.Client, feePayer common.PublicKey, instructions []types.Instruction) (uint64, error) {
+	return messageFee(ctx, c, types.NewMessage(types.NewMessageParam{
+		FeePayer:     feePayer,
+		Instructions: instructions,
+	}))
+}
+
+// messageFee returns the base fee of the message in lamports. The fee does not depend on the blockhash,
+// a fresh one is used because getFeeForMessage does not accept durable nonces.
+func messageFee(ctx context.Context, c *client.Client, message types.Message) (uint64, error) {
+	res, err := c.GetLatestBlockhash(ctx)
+	if err != nil {
+		log.Printf("failed to get latest blockhash, err: %v\n", err)
+		return 0, err
+	}
+
+	message.RecentBlockHash = res.Blockhash
+
+	fee, err := c.GetFeeForMessage(ctx, message)
+	if err != nil {
+		log.Printf("failed to get fee for message, err: %v\n", err)
+		return 0, err
+	}
+
+	if fee == nil {
+		return 0, fmt.Errorf("blockhash %s is not found", res.Blockhash)
+	}
+
+	return *fee, nil
+}
+
+// maxMultipleAccounts is the number of accounts getMultipleAccounts accepts per request.
+const maxMultipleAccounts = 100
+
+type balanceCheck struct {
+	from     common.PublicKey
+	feePayer common.PublicKey
+	fee      uint64
+	// lamports sent to each recipient
+	lamports map[common.PublicKey]uint64
+	// token amounts sent by mint and the owners whose token accounts may have to be created
+	tokens          map[common.PublicKey]uint64
+	tokenRecipients map[common.PublicKey][]common.PublicKey
+}
+
+func newBalanceCheck(from, feePayer common.PublicKey) *balanceCheck {
+	return &balanceCheck{
+		from:            from,
+		feePayer:        feePayer,
+		lamports:        make(map[common.PublicKey]uint64),
+		tokens:          make(map[common.PublicKey]uint64),
+		tokenRecipients: make(map[common.PublicKey][]common.PublicKey),
+	}
+}
+
+// addTransfer records a transfer built by transferInstructions, an empty mint stands for lamports.
+func (check *balanceCheck) addTransfer(to string, mint string, amount uint64) {
+	recipient := common.PublicKeyFromString(to)
+
+	if mint == "" {
+		check.lamports[recipient] += amount
+		return
+	}
+
+	mintAddress := common.PublicKeyFromString(mint)
+	check.tokens[mintAddress] += amount
+	check.tokenRecipients[mintAddress] = append(check.tokenRecipients[mintAddress], recipient)
+}
+
+// checkBalances makes sure the sender can pay the amounts, the fee payer can pay the fees and the
+// token accounts it creates, and that neither of them nor a newly created recipient is left with a
+// balance below the rent-exempt minimum.
+func (a *SolanaApi) checkBalances(ctx context.Context, c *client.Client, check *balanceCheck) error {
+	rent, err := c.GetMinimumBalanceForRentExemption(ctx, 0)
+	if err != nil {
+		log.Printf("failed to get rent exemption, err: %v\n", err)
+		return err
+	}
+
+	debits := map[common.PublicKey]uint64{
+		check.from: 0,
+	}
+	for _, amount := range check.lamports {
+		debits[check.from] += amount
+	}
+
+	accountsRent, err := a.tokenAccountsRent(ctx, c, check)
+	if err != nil {
+		return err
+	}
+	debits[check.feePayer] += check.fee + accountsRent
+
+	for account, debit := range debits {
+		balance, err := c.GetBalance(ctx, account.ToBase58())
+		if err != nil {
+			log.Printf("error get balance\n")
+			return err
+		}
+
+		if balance < debit {
+			log.Printf("insufficient amount, balance: %v, required: %v\n", balance, debit)
+			return _types.WrapErr(_types.ErrInsufficientBalance,
+				fmt.Errorf("balance of %s is %d lamports, required %d lamports", account.ToBase58(), balance, debit))
+		}
+
+		// an account can be emptied completely but not left rent paying
+		if remaining := balance - debit; remaining != 0 && remaining < rent {
+			return _types.WrapErr(_types.ErrBelowRentExemption,
+				fmt.Errorf("%s would keep %d lamports, rent exemption requires %d lamports", account.ToBase58(), remaining, rent))
+		}
+	}
+
+	for recipient, amount := range check.lamports {
+		if amount == 0 || recipient == check.from || recipient == check.feePayer {
+			continue
+		}
+
+		recipientBalance, err := c.GetBalance(ctx, recipient.ToBase58())
+		if err != nil {
+			log.Printf("error get balance\n")
+			return err
+		}
+
+		// the transfer creates the recipient account
+		if recipientBalance == 0 && amount < rent {
+			return _types.WrapErr(_types.ErrBelowRentExemption,
+				fmt.Errorf("transfer of %d lamports cannot create %s, rent exemption requires %d lamports", amount, recipient.ToBase58(), rent))
+		}
+	}
+
+	for mint, amount := range check.tokens {
+		source, err := associatedTokenAddress(check.from, mint)
+		if err != nil {
+			return err
+		}
+
+		balance, err := c.GetTokenAccountBalance(ctx, source.ToBase58())
+		if err != nil {
+			log.Printf("failed to get token balance of %s, err: %v\n", source.ToBase58(), err)
+			return _types.WrapErr(_types.ErrInsufficientBalance,
+				fmt.Errorf("token account %s of mint %s is not available: %w", source.ToBase58(), mint.ToBase58(), err))
+		}
+
+		if balance.Amount < amount {
+			return _types.WrapErr(_types.ErrInsufficientBalance,
+				fmt.Errorf("token balance of %s is %d, required %d", source.ToBase58(), balance.Amount, amount))
+		}
+	}
+
+	return nil
+}
+
+// tokenAccountsRent returns the lamports the fee payer spends on recipient token accounts that do not exist yet.
+func (a *SolanaApi) tokenAccountsRent(ctx context.Context, c *client.Client, check *balanceCheck) (uint64, error) {
+	seen := make(map[common.PublicKey]bool)
+
+	var addresses []string
+	for mint, recipients := range check.tokenRecipients {
+		for _, recipient := range recipients {
+			address, err := associatedTokenAddress(recipient, mint)
+			if err != nil {
+				return 0, err
+			}
+
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+			addresses = append(addresses, address.ToBase58())
+		}
+	}
+
+	if len(addresses) == 0 {
+		return 0, nil
+	}
+
+	var missing uint64
+	for start := 0; start < len(addresses); start += maxMultipleAccounts {
+		end := min(start+maxMultipleAccounts, len(addresses))
+
+		accounts, err := c.GetMultipleAccounts(ctx, addresses[start:end])
+		if err != nil {
+			log.Printf("failed to get token accounts, err: %v\n", err)
+			return 0, err
+		}
+
+		for _, account := range accounts {
+			if account.Lamports == 0 {
+				missing++
+			}
+		}
+	}
+
+	if missing == 0 {
+		return 0, nil
+	}
+
+	rent, err := c.GetMinimumBalanceForRentExemption(ctx, token.TokenAccountSize)
+	if err != nil {
+		log.Printf("failed to get rent exemption, err: %v\n", err)
+		return 0, err
+	}
+
+	return missing * rent, nil
+}
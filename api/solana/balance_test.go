@@ -0,0 +1,256 @@
+package solana_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/solana"
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/token"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+const (
+	// rent exemption of an account without data and of a token account
+	accountRent      = 890_880
+	tokenAccountRent = 2_039_280
+)
+
+// newRPCServerFunc answers every call with the result of handle, an error is returned as a JSON-RPC error.
+func newRPCServerFunc(t *testing.T, handle func(method string, params []json.RawMessage) (any, error)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := handle(req.Method, req.Params)
+		if err != nil {
+			_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "error": map[string]any{"code": -32602, "message": err.Error()}})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+}
+
+// balanceChain keeps the lamports of accounts and the amounts of token accounts, the ones it does not
+// know do not exist.
+type balanceChain struct {
+	lamports map[common.PublicKey]uint64
+	tokens   map[common.PublicKey]uint64
+}
+
+func (c *balanceChain) handle(t *testing.T) func(method string, params []json.RawMessage) (any, error) {
+	withContext := func(value any) any {
+		return map[string]any{"context": map[string]any{"slot": 300_000_000}, "value": value}
+	}
+	account := func(param json.RawMessage) common.PublicKey {
+		var address string
+		if err := json.Unmarshal(param, &address); err != nil {
+			t.Errorf("address param %s: %v", param, err)
+		}
+		return common.PublicKeyFromString(address)
+	}
+
+	return func(method string, params []json.RawMessage) (any, error) {
+		switch method {
+		case "getMinimumBalanceForRentExemption":
+			var size uint64
+			_ = json.Unmarshal(params[0], &size)
+			if size == token.TokenAccountSize {
+				return tokenAccountRent, nil
+			}
+			return accountRent, nil
+		case "getBalance":
+			return withContext(c.lamports[account(params[0])]), nil
+		case "getTokenAccountBalance":
+			amount, ok := c.tokens[account(params[0])]
+			if !ok {
+				return nil, errors.New("could not find account")
+			}
+			return withContext(map[string]any{"amount": strconv.FormatUint(amount, 10), "decimals": 6, "uiAmountString": ""}), nil
+		case "getMultipleAccounts":
+			var addresses []string
+			_ = json.Unmarshal(params[0], &addresses)
+
+			accounts := make([]any, 0, len(addresses))
+			for _, address := range addresses {
+				if _, ok := c.tokens[common.PublicKeyFromString(address)]; !ok {
+					accounts = append(accounts, nil)
+					continue
+				}
+				accounts = append(accounts, map[string]any{
+					"lamports":   tokenAccountRent,
+					"owner":      common.TokenProgramID.ToBase58(),
+					"data":       []string{"", "base64"},
+					"executable": false,
+					"rentEpoch":  0,
+				})
+			}
+			return withContext(accounts), nil
+		}
+
+		t.Errorf("unexpected call of %s", method)
+		return nil, errors.New("unexpected method")
+	}
+}
+
+func TestCheckBalances(t *testing.T) {
+	from := types.NewAccount().PublicKey
+	feePayer := types.NewAccount().PublicKey
+	existing := types.NewAccount().PublicKey
+	created := types.NewAccount().PublicKey
+	mint := types.NewAccount().PublicKey
+
+	tokenAccount := func(owner common.PublicKey) common.PublicKey {
+		address, _, err := common.FindAssociatedTokenAddress(owner, mint)
+		if err != nil {
+			t.Fatalf("FindAssociatedTokenAddress() error = %v", err)
+		}
+		return address
+	}
+
+	const fee = 5_000
+
+	tests := []struct {
+		name      string
+		lamports  map[common.PublicKey]uint64
+		tokens    map[common.PublicKey]uint64
+		feePayer  common.PublicKey
+		transfers []solana.BalanceTransfer
+		wantErr   *_types.Error
+	}{
+		{
+			name:      "enough lamports",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+		},
+		{
+			name:      "amount and fee above the balance",
+			lamports:  map[common.PublicKey]uint64{from: 1_000_000, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "transfers add up",
+			lamports:  map[common.PublicKey]uint64{from: 2_000_000, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}, {To: existing.ToBase58(), Amount: 1_000_000}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "sender emptied",
+			lamports:  map[common.PublicKey]uint64{from: 1_000_000 + fee, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+		},
+		{
+			name:      "sender left below rent exemption",
+			lamports:  map[common.PublicKey]uint64{from: 1_000_000 + fee + accountRent - 1, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+			wantErr:   _types.ErrBelowRentExemption,
+		},
+		{
+			name:      "sender left at rent exemption",
+			lamports:  map[common.PublicKey]uint64{from: 1_000_000 + fee + accountRent, existing: 1},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+		},
+		{
+			name:      "new recipient below rent exemption",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: created.ToBase58(), Amount: accountRent - 1}},
+			wantErr:   _types.ErrBelowRentExemption,
+		},
+		{
+			name:      "new recipient at rent exemption",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: created.ToBase58(), Amount: accountRent}},
+		},
+		{
+			name:      "fee payer cannot pay the fee",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000, feePayer: fee - 1, existing: 1},
+			feePayer:  feePayer,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Amount: 1_000_000}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "enough tokens",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000},
+			tokens:    map[common.PublicKey]uint64{tokenAccount(from): 100, tokenAccount(existing): 0},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Mint: mint.ToBase58(), Amount: 100}},
+		},
+		{
+			name:      "tokens above the balance",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000},
+			tokens:    map[common.PublicKey]uint64{tokenAccount(from): 99, tokenAccount(existing): 0},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Mint: mint.ToBase58(), Amount: 100}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "no token account",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000},
+			tokens:    map[common.PublicKey]uint64{tokenAccount(existing): 0},
+			feePayer:  from,
+			transfers: []solana.BalanceTransfer{{To: existing.ToBase58(), Mint: mint.ToBase58(), Amount: 100}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "fee payer cannot pay the recipient token account",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000, feePayer: fee + tokenAccountRent - 1},
+			tokens:    map[common.PublicKey]uint64{tokenAccount(from): 100},
+			feePayer:  feePayer,
+			transfers: []solana.BalanceTransfer{{To: created.ToBase58(), Mint: mint.ToBase58(), Amount: 100}},
+			wantErr:   _types.ErrInsufficientBalance,
+		},
+		{
+			name:      "fee payer pays the recipient token account",
+			lamports:  map[common.PublicKey]uint64{from: 10_000_000, feePayer: fee + tokenAccountRent},
+			tokens:    map[common.PublicKey]uint64{tokenAccount(from): 100},
+			feePayer:  feePayer,
+			transfers: []solana.BalanceTransfer{{To: created.ToBase58(), Mint: mint.ToBase58(), Amount: 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &balanceChain{lamports: tt.lamports, tokens: tt.tokens}
+			server := newRPCServerFunc(t, chain.handle(t))
+			defer server.Close()
+
+			err := solana.NewSolanaApi(api.NewSignerProvider(), server.URL, nil).
+				CheckBalances(context.Background(), from, tt.feePayer, fee, tt.transfers...)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("CheckBalances() error = %v", err)
+				}
+				return
+			}
+
+			var typed *_types.Error
+			if !errors.As(err, &typed) || typed.Code != tt.wantErr.Code {
+				t.Errorf("CheckBalances() error = %v, want %s", err, tt.wantErr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+const (
+	// maxTransactionSize is the largest serialized transaction accepted by the cluster.
+	maxTransactionSize = 1232
+	// maxTransactionAccounts is the number of accounts a transaction may lock.
+	maxTransactionAccounts = 64
+)
+
+type BatchTransferInput struct {
+	AppId       string
+	Network     string
+	FromAddress string
+	FeePayer    string
+	// address lookup tables the messages are compiled against, see CreateLookupTable
+	LookupTables []string
+	Transfers    []*BatchTransferItem
+}
+
+type BatchTransferItem struct {
+	ToAddress string
+	Amount    *big.Int
+	// mint of the SPL token, empty for SOL
+	ContractAddress string
+	TokenDecimals   int32
+}
+
+// BatchTransfer packs as many transfers as fit into every transaction and splits the batch into several
+// transactions otherwise, a transfer is never split and the order is kept. With lookup tables the messages
+// are compiled as v0 so that recipients stored in the tables take an index instead of a full key.
+// Balances are checked for the whole batch before anything is sent. When sending fails midway the
+// messages of the transactions sent so far are returned together with the error.
+func (a *SolanaApi) BatchTransfer(ctx context.Context, input *BatchTransferInput) ([]*_types.TransferMessage, error) {
+	c := client.NewClient(a.endpoint)
+
+	if len(input.Transfers) == 0 {
+		return nil, fmt.Errorf("no transfers in batch")
+	}
+
+	feePayerAddress := input.FeePayer
+	if feePayerAddress == "" {
+		feePayerAddress = input.FromAddress
+	}
+
+	feePayer, err := a.account(ctx, input.AppId, input.Network, feePayerAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := a.account(ctx, input.AppId, input.Network, input.FromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	lookupTables, err := a.getLookupTables(ctx, c, input.LookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.GetLatestBlockhash(ctx)
+	if err != nil {
+		log.Printf("failed to get latest blockhash, err: %v\n", err)
+		return nil, err
+	}
+
+	check := newBalanceCheck(from.PublicKey, feePayer.PublicKey)
+
+	transfers := make([][]types.Instruction, 0, len(input.Transfers))
+	for i, transfer := range input.Transfers {
+		instructions, err := transferInstructions(from.PublicKey, feePayer.PublicKey,
+			transfer.ToAddress, transfer.Amount, transfer.ContractAddress, transfer.TokenDecimals)
+		if err != nil {
+			return nil, fmt.Errorf("transfer %d: %w", i, err)
+		}
+		check.addTransfer(transfer.ToAddress, transfer.ContractAddress, transfer.Amount.Uint64())
+
+		transfers = append(transfers, instructions)
+	}
+
+	batches, err := packTransfers(feePayer.PublicKey, res.Blockhash, transfers, lookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instructions := range batches {
+		fee, err := a.signatureFee(ctx, c, feePayer.PublicKey, instructions)
+		if err != nil {
+			return nil, err
+		}
+		check.fee += fee
+	}
+
+	budgets := make([]*ComputeBudget, 0, len(batches))
+	for _, instructions := range batches {
+		budget, err := a.estimateComputeBudget(ctx, c, feePayer.PublicKey, res.Blockhash, instructions, lookupTables)
+		if err != nil {
+			log.Printf("failed to estimate compute budget, err: %v\n", err)
+			// batches the accounts cannot afford fail the simulation, report them as a balance error
+			if balanceErr := a.checkBalances(ctx, c, check); balanceErr != nil {
+				return nil, balanceErr
+			}
+			return nil, err
+		}
+
+		check.fee += budget.PriorityFee()
+		budgets = append(budgets, budget)
+	}
+
+	if err := a.checkBalances(ctx, c, check); err != nil {
+		return nil, err
+	}
+
+	signers := []types.Account{feePayer}
+	if from.PublicKey != feePayer.PublicKey {
+		signers = append(signers, from)
+	}
+
+	log.Printf("sending %d transfers in %d transactions\n", len(input.Transfers), len(batches))
+
+	messages := make([]*_types.TransferMessage, 0, len(batches))
+	for i, instructions := range batches {
+		// simulations take a while, sign every transaction with a fresh blockhash
+		res, err := c.GetLatestBlockhash(ctx)
+		if err != nil {
+			log.Printf("failed to get latest blockhash, err: %v\n", err)
+			return messages, err
+		}
+
+		tx, err := types.NewTransaction(types.NewTransactionParam{
+			Message: types.NewMessage(types.NewMessageParam{
+				FeePayer:                   feePayer.PublicKey,
+				RecentBlockhash:            res.Blockhash,
+				Instructions:               append(budgets[i].Instructions(), instructions...),
+				AddressLookupTableAccounts: lookupTables,
+			}),
+			Signers: signers,
+		})
+		if err != nil {
+			return messages, err
+		}
+
+		payload, err := tx.Serialize()
+		if err != nil {
+			return messages, err
+		}
+
+		txHash, err := c.SendTransaction(ctx, tx)
+		if err != nil {
+			return messages, err
+		}
+
+		log.Printf("tx sent: %s\n", txHash)
+
+		messages = append(messages, &_types.TransferMessage{
+			Hash:    []byte(txHash),
+			Payload: payload,
+		})
+	}
+
+	return messages, nil
+}
+
+// packTransfers groups the instructions of the transfers into as few transactions as the size and account
+// lock limits allow, keeping the order and the instructions of a transfer together.
+func packTransfers(feePayer common.PublicKey, blockhash string, transfers [][]types.Instruction, lookupTables []types.AddressLookupTableAccount) ([][]types.Instruction, error) {
+	var batches [][]types.Instruction
+	var current []types.Instruction
+	for i, instructions := range transfers {
+		if len(current) > 0 {
+			candidate := append(append([]types.Instruction(nil), current...), instructions...)
+
+			fits, err := fitsTransaction(feePayer, blockhash, candidate, lookupTables)
+			if err != nil {
+				return nil, err
+			}
+
+			if fits {
+				current = candidate
+				continue
+			}
+
+			batches = append(batches, current)
+		}
+
+		fits, err := fitsTransaction(feePayer, blockhash, instructions, lookupTables)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fits {
+			return nil, fmt.Errorf("transfer %d does not fit into a single transaction", i)
+		}
+
+		current = instructions
+	}
+
+	return append(batches, current), nil
+}
+
+// fitsTransaction reports whether the instructions, preceded by the compute budget instructions,
+// compile into a transaction within the size and account lock limits.
+func fitsTransaction(feePayer common.PublicKey, blockhash string, instructions []types.Instruction, lookupTables []types.AddressLookupTableAccount) (bool, error) {
+	budget := &ComputeBudget{UnitLimit: MaxComputeUnitLimit}
+
+	message := types.NewMessage(types.NewMessageParam{
+		FeePayer:                   feePayer,
+		RecentBlockhash:            blockhash,
+		Instructions:               append(budget.Instructions(), instructions...),
+		AddressLookupTableAccounts: lookupTables,
+	})
+
+	accounts := len(message.Accounts)
+	for _, table := range message.AddressLookupTables {
+		accounts += len(table.WritableIndexes) + len(table.ReadonlyIndexes)
+	}
+
+	if accounts > maxTransactionAccounts {
+		return false, nil
+	}
+
+	// signatures are left empty, they take the same space
+	tx, err := types.NewTransaction(types.NewTransactionParam{
+		Message: message,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := tx.Serialize()
+	if err != nil {
+		return false, err
+	}
+
+	return len(payload) <= maxTransactionSize, nil
+}
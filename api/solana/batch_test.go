@@ -0,0 +1,162 @@
+package solana_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/solana"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// transactionSize returns the serialized size of the instructions sent with the compute budget instructions.
+func transactionSize(t *testing.T, feePayer common.PublicKey, instructions []types.Instruction, lookupTables []types.AddressLookupTableAccount) int {
+	t.Helper()
+
+	budget := &solana.ComputeBudget{UnitLimit: solana.MaxComputeUnitLimit}
+	tx, err := types.NewTransaction(types.NewTransactionParam{
+		Message: types.NewMessage(types.NewMessageParam{
+			FeePayer:                   feePayer,
+			RecentBlockhash:            latestBlockhash,
+			Instructions:               append(budget.Instructions(), instructions...),
+			AddressLookupTableAccounts: lookupTables,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+
+	payload, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	return len(payload)
+}
+
+func TestPackTransfers(t *testing.T) {
+	feePayer := types.NewAccount().PublicKey
+	program := types.NewAccount().PublicKey
+
+	recipients := make([]common.PublicKey, 150)
+	for i := range recipients {
+		recipients[i] = types.NewAccount().PublicKey
+	}
+	table := []types.AddressLookupTableAccount{{Key: types.NewAccount().PublicKey, Addresses: recipients}}
+
+	lamports := func(n int) [][]types.Instruction {
+		transfers := make([][]types.Instruction, n)
+		for i := range transfers {
+			transfers[i] = []types.Instruction{system.Transfer(system.TransferParam{From: feePayer, To: recipients[i], Amount: 1})}
+		}
+		return transfers
+	}
+	// a transfer of two instructions that must not be split
+	pairs := func(n int) [][]types.Instruction {
+		transfers := make([][]types.Instruction, n)
+		for i := range transfers {
+			transfers[i] = []types.Instruction{
+				system.Transfer(system.TransferParam{From: feePayer, To: recipients[2*i], Amount: 1}),
+				system.Transfer(system.TransferParam{From: feePayer, To: recipients[2*i+1], Amount: 1}),
+			}
+		}
+		return transfers
+	}
+	// one writable account and no data each, lookup tables make them cheap enough for the account limit to apply first
+	touches := func(n int) [][]types.Instruction {
+		transfers := make([][]types.Instruction, n)
+		for i := range transfers {
+			transfers[i] = []types.Instruction{{
+				ProgramID: program,
+				Accounts:  []types.AccountMeta{{PubKey: recipients[i], IsWritable: true}},
+			}}
+		}
+		return transfers
+	}
+
+	tests := []struct {
+		name         string
+		transfers    [][]types.Instruction
+		lookupTables []types.AddressLookupTableAccount
+		// instructions of every full transaction when the account limit applies
+		wantPerBatch int
+	}{
+		{name: "single transfer", transfers: lamports(1)},
+		{name: "split at 1232 bytes", transfers: lamports(60)},
+		{name: "split at 1232 bytes with lookup tables", transfers: lamports(150), lookupTables: table},
+		{name: "transfers kept whole", transfers: pairs(40)},
+		// the fee payer, the compute budget program and the program leave room for 61 accounts
+		{name: "split at 64 accounts", transfers: touches(150), lookupTables: table, wantPerBatch: 61},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := solana.PackTransfers(feePayer, latestBlockhash, tt.transfers, tt.lookupTables)
+			if err != nil {
+				t.Fatalf("PackTransfers() error = %v", err)
+			}
+
+			// the batches hold every transfer once, in order, and each transaction starts at a transfer
+			var packed, want []types.Instruction
+			next := 0
+			for i, batch := range batches {
+				fits, err := solana.FitsTransaction(feePayer, latestBlockhash, batch, tt.lookupTables)
+				if err != nil {
+					t.Fatalf("FitsTransaction() error = %v", err)
+				}
+				if !fits {
+					t.Errorf("transaction %d of %d instructions exceeds the limits", i, len(batch))
+				}
+
+				for len(want) < len(packed)+len(batch) {
+					want = append(want, tt.transfers[next]...)
+					next++
+				}
+				packed = append(packed, batch...)
+				if len(want) != len(packed) {
+					t.Fatalf("transaction %d ends within transfer %d", i, next-1)
+				}
+
+				if i == len(batches)-1 {
+					break
+				}
+
+				// the next transfer would not fit, the transaction is full
+				full := append(slices.Clone(batch), tt.transfers[next]...)
+				fits, err = solana.FitsTransaction(feePayer, latestBlockhash, full, tt.lookupTables)
+				if err != nil {
+					t.Fatalf("FitsTransaction() error = %v", err)
+				}
+				if fits {
+					t.Errorf("transaction %d has room for transfer %d", i, next)
+				}
+
+				if tt.wantPerBatch > 0 {
+					if len(batch) != tt.wantPerBatch {
+						t.Errorf("transaction %d has %d instructions, want %d", i, len(batch), tt.wantPerBatch)
+					}
+				} else if size, over := transactionSize(t, feePayer, batch, tt.lookupTables), transactionSize(t, feePayer, full, tt.lookupTables); size > 1232 || over <= 1232 {
+					t.Errorf("transaction %d is %d bytes and %d bytes with the next transfer, want the limit of 1232 bytes between them", i, size, over)
+				}
+			}
+
+			if next != len(tt.transfers) {
+				t.Fatalf("PackTransfers() packed %d of %d transfers", next, len(tt.transfers))
+			}
+			for i := range packed {
+				if packed[i].Accounts[len(packed[i].Accounts)-1].PubKey != want[i].Accounts[len(want[i].Accounts)-1].PubKey {
+					t.Fatalf("instruction %d is out of order", i)
+				}
+			}
+			if len(tt.transfers) > 1 && len(batches) < 2 {
+				t.Errorf("PackTransfers() = %d transactions, want the transfers split", len(batches))
+			}
+		})
+	}
+
+	huge := []types.Instruction{{ProgramID: program, Data: make([]byte, 1232)}}
+	if _, err := solana.PackTransfers(feePayer, latestBlockhash, append(lamports(2), huge), nil); err == nil {
+		t.Errorf("PackTransfers() of a transfer larger than a transaction error = nil, want an error")
+	}
+}
@@ -0,0 +1,168 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/compute_budget"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+type ComputeBudget struct {
+	UnitLimit uint32
+	// price of a compute unit in micro-lamports
+	UnitPrice uint64
+}
+
+// Instructions returns the compute budget instructions, they must be placed
+// before any other instruction of the transaction.
+func (b *ComputeBudget) Instructions() []types.Instruction {
+	return []types.Instruction{
+		compute_budget.SetComputeUnitLimit(compute_budget.SetComputeUnitLimitParam{
+			Units: b.UnitLimit,
+		}),
+		compute_budget.SetComputeUnitPrice(compute_budget.SetComputeUnitPriceParam{
+			MicroLamports: b.UnitPrice,
+		}),
+	}
+}
+
+// PriorityFee returns the fee in lamports paid on top of the base signature fee.
+func (b *ComputeBudget) PriorityFee() uint64 {
+	return priorityFee(b.UnitLimit, b.UnitPrice)
+}
+
+func priorityFee(units uint32, price uint64) uint64 {
+	fee := new(big.Int).Mul(big.NewInt(int64(units)), new(big.Int).SetUint64(price))
+	fee.Add(fee, big.NewInt(microLamportsPerLamport-1))
+	return fee.Div(fee, big.NewInt(microLamportsPerLamport)).Uint64()
+}
+
+func (a *SolanaApi) estimateComputeBudget(ctx context.Context, c *client.Client, feePayer common.PublicKey, blockhash string, instructions []types.Instruction, lookupTables []types.AddressLookupTableAccount) (*ComputeBudget, error) {
+	consumed, err := a.simulateComputeUnits(ctx, c, feePayer, blockhash, instructions, lookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := a.recentPriorityFee(ctx, c, instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.newComputeBudget(consumed, price), nil
+}
+
+// newComputeBudget applies the configured margin and caps to the simulated units and the sampled price.
+func (a *SolanaApi) newComputeBudget(consumed uint64, price uint64) *ComputeBudget {
+	units := uint64(math.Ceil(float64(consumed) * (1 + a.opts.computeUnitMargin)))
+	if units > uint64(a.opts.maxComputeUnitLimit) {
+		units = uint64(a.opts.maxComputeUnitLimit)
+	}
+
+	if price < a.opts.minComputeUnitPrice {
+		price = a.opts.minComputeUnitPrice
+	}
+	if a.opts.maxComputeUnitPrice > 0 && price > a.opts.maxComputeUnitPrice {
+		price = a.opts.maxComputeUnitPrice
+	}
+
+	if a.opts.maxPriorityFee > 0 && units > 0 && priorityFee(uint32(units), price) > a.opts.maxPriorityFee {
+		price = a.opts.maxPriorityFee * microLamportsPerLamport / units
+	}
+
+	return &ComputeBudget{
+		UnitLimit: uint32(units),
+		UnitPrice: price,
+	}
+}
+
+// simulateComputeUnits runs the instructions with the highest unit limit and returns the consumed units,
+// messages compiled against lookup tables are simulated as v0 transactions.
+func (a *SolanaApi) simulateComputeUnits(ctx context.Context, c *client.Client, feePayer common.PublicKey, blockhash string, instructions []types.Instruction, lookupTables []types.AddressLookupTableAccount) (uint64, error) {
+	budget := &ComputeBudget{UnitLimit: MaxComputeUnitLimit}
+
+	tx, err := types.NewTransaction(types.NewTransactionParam{
+		Message: types.NewMessage(types.NewMessageParam{
+			FeePayer:                   feePayer,
+			RecentBlockhash:            blockhash,
+			Instructions:               append(budget.Instructions(), instructions...),
+			AddressLookupTableAccounts: lookupTables,
+		}),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.SimulateTransactionWithConfig(ctx, tx, client.SimulateTransactionConfig{
+		SigVerify:              false,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+
+	if res.Err != nil {
+		return 0, fmt.Errorf("transaction simulation failed: %v, logs: %v", res.Err, res.Logs)
+	}
+
+	if res.UnitConsumed == nil {
+		return 0, fmt.Errorf("transaction simulation returned no consumed units")
+	}
+
+	return *res.UnitConsumed, nil
+}
+
+// recentPriorityFee samples the prioritization fees paid recently for the writable accounts of the instructions.
+func (a *SolanaApi) recentPriorityFee(ctx context.Context, c *client.Client, instructions []types.Instruction) (uint64, error) {
+	res, err := c.GetRecentPrioritizationFees(ctx, writableAccounts(instructions))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+
+	fees := make([]uint64, 0, len(res))
+	for _, fee := range res {
+		fees = append(fees, fee.PrioritizationFee)
+	}
+
+	return percentile(fees, a.opts.priorityFeePercentile), nil
+}
+
+func writableAccounts(instructions []types.Instruction) []common.PublicKey {
+	seen := make(map[common.PublicKey]bool)
+
+	var accounts []common.PublicKey
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts {
+			if !account.IsWritable || seen[account.PubKey] {
+				continue
+			}
+			seen[account.PubKey] = true
+			accounts = append(accounts, account.PubKey)
+		}
+	}
+
+	return accounts
+}
+
+func percentile(values []uint64, p int) uint64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]uint64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[(len(sorted)-1)*p/100]
+}
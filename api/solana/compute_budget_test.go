@@ -0,0 +1,170 @@
+package solana_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/solana"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+func TestNewComputeBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		consumed  uint64
+		price     uint64
+		opts      []solana.Option
+		wantUnits uint32
+		wantPrice uint64
+	}{
+		{name: "default margin", consumed: 1_000, price: 5_000, wantUnits: 1_200, wantPrice: 5_000},
+		{name: "margin rounds up", consumed: 1_001, price: 5_000, wantUnits: 1_202, wantPrice: 5_000},
+		{name: "no margin", consumed: 1_000, price: 5_000, opts: []solana.Option{solana.WithComputeUnitMargin(0)}, wantUnits: 1_000, wantPrice: 5_000},
+		{name: "highest unit limit", consumed: 1_300_000, price: 5_000, wantUnits: solana.MaxComputeUnitLimit, wantPrice: 5_000},
+		{
+			name:      "configured unit limit",
+			consumed:  500_000,
+			price:     5_000,
+			opts:      []solana.Option{solana.WithMaxComputeUnitLimit(200_000)},
+			wantUnits: 200_000,
+			wantPrice: 5_000,
+		},
+		{
+			name:      "price below the minimum",
+			consumed:  1_000,
+			price:     10,
+			opts:      []solana.Option{solana.WithComputeUnitPrice(1_000, 0)},
+			wantUnits: 1_200,
+			wantPrice: 1_000,
+		},
+		{
+			name:      "price above the maximum",
+			consumed:  1_000,
+			price:     1_000_000,
+			opts:      []solana.Option{solana.WithComputeUnitPrice(0, 50_000)},
+			wantUnits: 1_200,
+			wantPrice: 50_000,
+		},
+		{
+			// 120k units at 1 lamport each would cost 120k lamports
+			name:      "priority fee above the maximum",
+			consumed:  100_000,
+			price:     1_000_000,
+			opts:      []solana.Option{solana.WithMaxPriorityFee(1_000)},
+			wantUnits: 120_000,
+			wantPrice: 8_333,
+		},
+		{
+			name:      "priority fee below the maximum",
+			consumed:  100_000,
+			price:     1_000,
+			opts:      []solana.Option{solana.WithMaxPriorityFee(1_000)},
+			wantUnits: 120_000,
+			wantPrice: 1_000,
+		},
+		{name: "nothing consumed", consumed: 0, price: 5_000, opts: []solana.Option{solana.WithMaxPriorityFee(1)}, wantUnits: 0, wantPrice: 5_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := solana.NewComputeBudget(tt.consumed, tt.price, tt.opts...)
+			if got.UnitLimit != tt.wantUnits || got.UnitPrice != tt.wantPrice {
+				t.Errorf("NewComputeBudget() = %d units at %d, want %d units at %d", got.UnitLimit, got.UnitPrice, tt.wantUnits, tt.wantPrice)
+			}
+		})
+	}
+
+	capped := solana.NewComputeBudget(100_000, 1_000_000, solana.WithMaxPriorityFee(1_000))
+	if fee := capped.PriorityFee(); fee > 1_000 {
+		t.Errorf("PriorityFee() = %d, want at most 1000", fee)
+	}
+}
+
+func TestComputeBudgetPriorityFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget solana.ComputeBudget
+		want   uint64
+	}{
+		{"no price", solana.ComputeBudget{UnitLimit: 200_000}, 0},
+		{"whole lamports", solana.ComputeBudget{UnitLimit: 200_000, UnitPrice: 5_000}, 1_000},
+		// fractions of a lamport are charged as a whole one
+		{"rounds up", solana.ComputeBudget{UnitLimit: 1, UnitPrice: 1}, 1},
+		{"highest limit and price", solana.ComputeBudget{UnitLimit: solana.MaxComputeUnitLimit, UnitPrice: 1 << 40}, 1_539_316_278_887},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.PriorityFee(); got != tt.want {
+				t.Errorf("PriorityFee() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []uint64
+		p      int
+		want   uint64
+	}{
+		{"no fees", nil, 75, 0},
+		{"single fee", []uint64{7}, 75, 7},
+		{"lowest", []uint64{30, 10, 20}, 0, 10},
+		{"highest", []uint64{30, 10, 20}, 100, 30},
+		{"below the range", []uint64{30, 10, 20}, -5, 10},
+		{"above the range", []uint64{30, 10, 20}, 150, 30},
+		{"75th of unsorted fees", []uint64{5, 1, 4, 2, 3}, 75, 4},
+		{"median rounds down", []uint64{40, 10, 30, 20}, 50, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := solana.Percentile(tt.values, tt.p); got != tt.want {
+				t.Errorf("Percentile() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecentPriorityFee(t *testing.T) {
+	fees := make([]map[string]any, 0, 20)
+	for i := 20; i > 0; i-- {
+		fees = append(fees, map[string]any{"slot": 300_000_000 + i, "prioritizationFee": i * 1_000})
+	}
+
+	server := newRPCServer(t, map[string]any{"getRecentPrioritizationFees": fees})
+	defer server.Close()
+
+	transfer := system.Transfer(system.TransferParam{
+		From:   types.NewAccount().PublicKey,
+		To:     types.NewAccount().PublicKey,
+		Amount: 1,
+	})
+
+	tests := []struct {
+		name string
+		opts []solana.Option
+		want uint64
+	}{
+		{name: "default percentile", want: 15_000},
+		{name: "median", opts: []solana.Option{solana.WithPriorityFeePercentile(50)}, want: 10_000},
+		{name: "highest", opts: []solana.Option{solana.WithPriorityFeePercentile(100)}, want: 20_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := solana.NewSolanaApi(api.NewSignerProvider(), server.URL, nil, tt.opts...).
+				RecentPriorityFee(context.Background(), []types.Instruction{transfer})
+			if err != nil {
+				t.Fatalf("RecentPriorityFee() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RecentPriorityFee() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
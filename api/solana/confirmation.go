@@ -0,0 +1,187 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mr-tron/base58"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+type ConfirmationStatus string
+
+const (
+	ConfirmationStatusPending   ConfirmationStatus = "pending"
+	ConfirmationStatusProcessed ConfirmationStatus = "processed"
+	ConfirmationStatusConfirmed ConfirmationStatus = "confirmed"
+	ConfirmationStatusFinalized ConfirmationStatus = "finalized"
+	// the transaction landed but its execution failed, the fee is charged anyway
+	ConfirmationStatusFailed ConfirmationStatus = "failed"
+	// the blockhash (or durable nonce) the transaction was signed with is no longer usable
+	// and the transaction did not land, it is safe to build and sign a new one
+	ConfirmationStatusExpired ConfirmationStatus = "expired"
+)
+
+type Confirmation struct {
+	Signature string
+	Status    ConfirmationStatus
+	Slot      uint64
+	// execution error reported for failed transactions
+	Err any
+}
+
+// TrackTransaction follows a signed transaction until it reaches the given commitment, fails on chain
+// or expires. While the transaction is not seen by the cluster the same signed payload is re-broadcast
+// periodically, it never re-signs, so a transaction reported as expired can be rebuilt without paying twice.
+// When ctx is done before a definitive status the last observed confirmation is returned with ctx error.
+func (a *SolanaApi) TrackTransaction(ctx context.Context, payload []byte, commitment rpc.Commitment) (*Confirmation, error) {
+	c := client.NewClient(a.endpoint)
+
+	tx, err := types.TransactionDeserialize(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := &Confirmation{
+		Signature: base58.Encode(tx.Signatures[0]),
+		Status:    ConfirmationStatusPending,
+	}
+
+	lastSent := time.Now()
+	for {
+		status, err := c.GetSignatureStatusWithConfig(ctx, confirmation.Signature, getSignatureStatusesConfig)
+		if err != nil {
+			log.Printf("failed to get signature status of %s, err: %v\n", confirmation.Signature, err)
+		} else if status != nil {
+			confirmation.Slot = status.Slot
+			if status.Err != nil {
+				confirmation.Status = ConfirmationStatusFailed
+				confirmation.Err = status.Err
+				return confirmation, nil
+			}
+
+			if status.ConfirmationStatus != nil {
+				confirmation.Status = ConfirmationStatus(*status.ConfirmationStatus)
+				if commitmentLevel(*status.ConfirmationStatus) >= commitmentLevel(commitment) {
+					return confirmation, nil
+				}
+			}
+		} else {
+			expired, err := a.isTransactionExpired(ctx, c, tx)
+			if err != nil {
+				log.Printf("failed to check expiration of %s, err: %v\n", confirmation.Signature, err)
+			} else if expired {
+				// the transaction may have landed between the two queries
+				status, err := c.GetSignatureStatusWithConfig(ctx, confirmation.Signature, getSignatureStatusesConfig)
+				if err != nil {
+					return confirmation, err
+				}
+
+				if status == nil {
+					confirmation.Status = ConfirmationStatusExpired
+					return confirmation, nil
+				}
+
+				continue
+			}
+
+			if time.Since(lastSent) >= a.opts.rebroadcastInterval {
+				if _, err := c.SendTransactionWithConfig(ctx, tx, client.SendTransactionConfig{
+					SkipPreflight: true,
+				}); err != nil {
+					log.Printf("failed to re-broadcast %s, err: %v\n", confirmation.Signature, err)
+				}
+				lastSent = time.Now()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return confirmation, ctx.Err()
+		case <-time.After(a.opts.confirmationPollInterval):
+		}
+	}
+}
+
+var getSignatureStatusesConfig = client.GetSignatureStatusesConfig{
+	SearchTransactionHistory: true,
+}
+
+// isTransactionExpired reports whether the transaction can no longer be included in a block.
+// Finalized state is checked so that no fork the node has not seen yet can still accept it.
+func (a *SolanaApi) isTransactionExpired(ctx context.Context, c *client.Client, tx types.Transaction) (bool, error) {
+	if nonceAccount, ok := durableNonceAccount(tx.Message); ok {
+		account, err := c.GetAccountInfoWithConfig(ctx, nonceAccount.ToBase58(), client.GetAccountInfoConfig{
+			Commitment: rpc.CommitmentFinalized,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		nonce, err := system.NonceAccountDeserialize(account.Data)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse nonce account %s: %w", nonceAccount.ToBase58(), err)
+		}
+
+		// once the nonce advanced the transaction signed with the old value is rejected
+		return nonce.Nonce.ToBase58() != tx.Message.RecentBlockHash, nil
+	}
+
+	// a recent blockhash is not known to the finalized bank yet, so it has to
+	// be rejected by both the processed and the finalized bank
+	for _, commitment := range []rpc.Commitment{rpc.CommitmentProcessed, rpc.CommitmentFinalized} {
+		valid, err := c.IsBlockhashValidWithConfig(ctx, tx.Message.RecentBlockHash, client.IsBlockhashValidConfig{
+			Commitment: commitment,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if valid {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// durableNonceAccount returns the nonce account when the first instruction of the message advances it.
+func durableNonceAccount(message types.Message) (common.PublicKey, bool) {
+	if len(message.Instructions) == 0 {
+		return common.PublicKey{}, false
+	}
+
+	instruction := message.Instructions[0]
+	if message.Accounts[instruction.ProgramIDIndex] != common.SystemProgramID || len(instruction.Accounts) == 0 {
+		return common.PublicKey{}, false
+	}
+
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(system.InstructionAdvanceNonceAccount))
+	if !bytes.Equal(instruction.Data, data) {
+		return common.PublicKey{}, false
+	}
+
+	return message.Accounts[instruction.Accounts[0]], true
+}
+
+func commitmentLevel(commitment rpc.Commitment) int {
+	switch commitment {
+	case rpc.CommitmentProcessed:
+		return 1
+	case rpc.CommitmentConfirmed:
+		return 2
+	case rpc.CommitmentFinalized:
+		return 3
+	}
+
+	return 0
+}
@@ -0,0 +1,55 @@
+package solana
+
+import (
+	"context"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// NewComputeBudget exposes the margin and caps applied to the simulated units and the sampled price.
+func NewComputeBudget(consumed, price uint64, opts ...Option) *ComputeBudget {
+	return NewSolanaApi(nil, "", nil, opts...).newComputeBudget(consumed, price)
+}
+
+// RecentPriorityFee exposes the unit price sampled from the recent prioritization fees.
+func (a *SolanaApi) RecentPriorityFee(ctx context.Context, instructions []types.Instruction) (uint64, error) {
+	return a.recentPriorityFee(ctx, client.NewClient(a.endpoint), instructions)
+}
+
+// RecentBlockhash exposes the blockhash, or durable nonce, transfers are signed with.
+func (a *SolanaApi) RecentBlockhash(ctx context.Context, input *_types.TransferInput, authority common.PublicKey) (string, []types.Instruction, error) {
+	return a.recentBlockhash(ctx, client.NewClient(a.endpoint), input, authority)
+}
+
+// BalanceTransfer is a transfer checked by CheckBalances, an empty mint stands for lamports.
+type BalanceTransfer struct {
+	To     string
+	Mint   string
+	Amount uint64
+}
+
+// CheckBalances exposes the balance check of transfers sent by from, with the fee paid by feePayer.
+func (a *SolanaApi) CheckBalances(ctx context.Context, from, feePayer common.PublicKey, fee uint64, transfers ...BalanceTransfer) error {
+	check := newBalanceCheck(from, feePayer)
+	check.fee = fee
+	for _, transfer := range transfers {
+		check.addTransfer(transfer.To, transfer.Mint, transfer.Amount)
+	}
+
+	return a.checkBalances(ctx, client.NewClient(a.endpoint), check)
+}
+
+// PackTransfers and FitsTransaction expose how batch transfers are split into transactions.
+var (
+	PackTransfers   = packTransfers
+	FitsTransaction = fitsTransaction
+)
+
+// CheckTransactionSize exposes the size check of transfers with a memo.
+var CheckTransactionSize = checkTransactionSize
+
+// Percentile exposes the percentile the unit price is sampled at.
+var Percentile = percentile
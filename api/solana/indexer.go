@@ -0,0 +1,333 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+)
+
+const (
+	defaultIndexerPollInterval = 5 * time.Second
+	// maxSignaturesPerPage is the page size limit of getSignaturesForAddress
+	maxSignaturesPerPage = 1000
+)
+
+// Checkpoint is the newest transaction of a watched account the indexer has processed.
+type Checkpoint struct {
+	Signature string
+	Slot      uint64
+}
+
+// CheckpointStore persists the checkpoint of every watched account so that indexing resumes
+// where it stopped. Load returns a nil checkpoint for accounts that were never indexed.
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, account string) (*Checkpoint, error)
+	SaveCheckpoint(ctx context.Context, account string, checkpoint *Checkpoint) error
+}
+
+// DepositHandler receives the deposits of a transaction, the checkpoint is only moved
+// past the transaction once the handler succeeds, so it has to be idempotent.
+type DepositHandler func(ctx context.Context, deposits []*TransferEvent) error
+
+type IndexerOption func(*DepositIndexer)
+
+// WithIndexerCommitment sets the commitment transactions must reach before they are indexed,
+// only confirmed and finalized are supported by the node.
+func WithIndexerCommitment(v rpc.Commitment) IndexerOption {
+	return func(i *DepositIndexer) {
+		i.commitment = v
+	}
+}
+
+func WithIndexerPollInterval(v time.Duration) IndexerOption {
+	return func(i *DepositIndexer) {
+		i.pollInterval = v
+	}
+}
+
+// WithIndexerMints makes the indexer watch the associated token accounts of these mints for every watched wallet.
+func WithIndexerMints(mints ...string) IndexerOption {
+	return func(i *DepositIndexer) {
+		i.mints = append(i.mints, mints...)
+	}
+}
+
+// DepositIndexer detects incoming SOL and SPL token transfers to watched wallets by walking the
+// signatures of every wallet and of its associated token accounts. SOL deposits are attributed to the
+// wallet, token deposits to the associated token account they credit, so that a transaction seen
+// under several accounts yields every deposit once.
+type DepositIndexer struct {
+	endpoint     string
+	store        CheckpointStore
+	commitment   rpc.Commitment
+	pollInterval time.Duration
+	mints        []string
+
+	mu sync.Mutex
+	// watched accounts by address
+	accounts map[string]*watchedAccount
+}
+
+type watchedAccount struct {
+	address string
+	// owner of the associated token account, empty for wallets
+	owner string
+	mint  string
+}
+
+func NewDepositIndexer(endpoint string, store CheckpointStore, o ...IndexerOption) *DepositIndexer {
+	i := &DepositIndexer{
+		endpoint:     endpoint,
+		store:        store,
+		commitment:   rpc.CommitmentFinalized,
+		pollInterval: defaultIndexerPollInterval,
+		accounts:     make(map[string]*watchedAccount),
+	}
+
+	for _, opt := range o {
+		opt(i)
+	}
+
+	return i
+}
+
+// Watch adds the wallet and its associated token accounts of the configured mints to the indexer.
+// Accounts without a checkpoint are checkpointed at their newest transaction, every transaction made
+// after Watch returns is indexed, earlier history is not.
+func (i *DepositIndexer) Watch(ctx context.Context, wallet string) error {
+	owner := common.PublicKeyFromString(wallet)
+
+	accounts := []*watchedAccount{{address: wallet}}
+	for _, mint := range i.mints {
+		address, err := associatedTokenAddress(owner, common.PublicKeyFromString(mint))
+		if err != nil {
+			return err
+		}
+
+		accounts = append(accounts, &watchedAccount{
+			address: address.ToBase58(),
+			owner:   wallet,
+			mint:    mint,
+		})
+	}
+
+	c := client.NewClient(i.endpoint)
+	for _, account := range accounts {
+		if err := i.startCheckpoint(ctx, c, account.address); err != nil {
+			return fmt.Errorf("failed to checkpoint %s: %w", account.address, err)
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, account := range accounts {
+		i.accounts[account.address] = account
+	}
+
+	return nil
+}
+
+// startCheckpoint saves the newest transaction of an account that was never indexed as its checkpoint,
+// an account without transactions gets an empty checkpoint and all of its transactions are indexed.
+func (i *DepositIndexer) startCheckpoint(ctx context.Context, c *client.Client, address string) error {
+	checkpoint, err := i.store.LoadCheckpoint(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint != nil {
+		return nil
+	}
+
+	signatures, err := c.GetSignaturesForAddressWithConfig(ctx, address, client.GetSignaturesForAddressConfig{
+		Limit:      1,
+		Commitment: i.commitment,
+	})
+	if err != nil {
+		return err
+	}
+
+	checkpoint = &Checkpoint{}
+	if len(signatures) > 0 {
+		checkpoint.Signature = signatures[0].Signature
+		checkpoint.Slot = signatures[0].Slot
+	}
+
+	return i.store.SaveCheckpoint(ctx, address, checkpoint)
+}
+
+// Run polls the watched accounts until ctx is done. Errors of a round are logged and retried in the next one.
+func (i *DepositIndexer) Run(ctx context.Context, handler DepositHandler) error {
+	for {
+		if err := i.Poll(ctx, handler); err != nil {
+			log.Printf("failed to index deposits, err: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(i.pollInterval):
+		}
+	}
+}
+
+// Poll indexes the transactions of every watched account made since its checkpoint, oldest first.
+// An account that fails does not hold back the others, the errors of all accounts are returned joined.
+func (i *DepositIndexer) Poll(ctx context.Context, handler DepositHandler) error {
+	c := client.NewClient(i.endpoint)
+
+	i.mu.Lock()
+	accounts := make([]*watchedAccount, 0, len(i.accounts))
+	for _, account := range i.accounts {
+		accounts = append(accounts, account)
+	}
+	i.mu.Unlock()
+
+	var errs []error
+	for _, account := range accounts {
+		if err := i.pollAccount(ctx, c, account, handler); err != nil {
+			errs = append(errs, fmt.Errorf("failed to index %s: %w", account.address, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (i *DepositIndexer) pollAccount(ctx context.Context, c *client.Client, account *watchedAccount, handler DepositHandler) error {
+	checkpoint, err := i.store.LoadCheckpoint(ctx, account.address)
+	if err != nil {
+		return err
+	}
+
+	// Watch saves the checkpoint, a store that lost it gets the whole history indexed
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{}
+	}
+
+	signatures, err := i.signaturesSince(ctx, c, account.address, checkpoint.Signature)
+	if err != nil {
+		return err
+	}
+
+	// signatures are returned newest first
+	for j := len(signatures) - 1; j >= 0; j-- {
+		signature := signatures[j]
+
+		if signature.Err == nil {
+			tx, err := c.GetTransactionWithConfig(ctx, signature.Signature, client.GetTransactionConfig{
+				Commitment: i.commitment,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get transaction %s: %w", signature.Signature, err)
+			}
+
+			if tx == nil {
+				return fmt.Errorf("transaction %s is not found", signature.Signature)
+			}
+
+			if deposits := account.deposits(ParseTransfers(tx)); len(deposits) > 0 {
+				if err := handler(ctx, deposits); err != nil {
+					return err
+				}
+			}
+		}
+
+		checkpoint = &Checkpoint{
+			Signature: signature.Signature,
+			Slot:      signature.Slot,
+		}
+		if err := i.store.SaveCheckpoint(ctx, account.address, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signaturesSince returns the signatures of the address newer than the given one, newest first.
+func (i *DepositIndexer) signaturesSince(ctx context.Context, c *client.Client, address, until string) (rpc.GetSignaturesForAddress, error) {
+	var signatures rpc.GetSignaturesForAddress
+
+	before := ""
+	for {
+		page, err := c.GetSignaturesForAddressWithConfig(ctx, address, client.GetSignaturesForAddressConfig{
+			Limit:      maxSignaturesPerPage,
+			Before:     before,
+			Until:      until,
+			Commitment: i.commitment,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		signatures = append(signatures, page...)
+		if len(page) < maxSignaturesPerPage {
+			return signatures, nil
+		}
+
+		before = page[len(page)-1].Signature
+	}
+}
+
+// deposits keeps the transfers crediting the account.
+func (account *watchedAccount) deposits(transfers []*TransferEvent) []*TransferEvent {
+	var deposits []*TransferEvent
+	for _, transfer := range transfers {
+		if account.owner == "" {
+			if transfer.Mint != "" || transfer.To != account.address || transfer.From == account.address {
+				continue
+			}
+		} else {
+			if transfer.ToTokenAccount != account.address || transfer.FromTokenAccount == account.address {
+				continue
+			}
+
+			// the owner is missing from the meta of some old transactions
+			transfer.To = account.owner
+			if transfer.Mint == "" {
+				transfer.Mint = account.mint
+			}
+		}
+
+		deposits = append(deposits, transfer)
+	}
+
+	return deposits
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory, indexing restarts from the newest transactions
+// after the process restarts.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]*Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		checkpoints: make(map[string]*Checkpoint),
+	}
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, account string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoints[account], nil
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, account string, checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[account] = checkpoint
+
+	return nil
+}
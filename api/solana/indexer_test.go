@@ -0,0 +1,241 @@
+package solana_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mr-tron/base58"
+	"github.com/openweb3-io/blockchain/api/solana"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// fakeNode answers getSignaturesForAddress and getTransaction from the transfers added to it.
+type fakeNode struct {
+	mu sync.Mutex
+	// signatures of every address, newest first
+	signatures map[string][]string
+	txs        map[string]string
+	slot       uint64
+}
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{
+		signatures: make(map[string][]string),
+		txs:        make(map[string]string),
+	}
+}
+
+// transfer adds a signed SOL transfer and returns its signature.
+func (n *fakeNode) transfer(t *testing.T, from types.Account, to common.PublicKey, amount uint64) string {
+	t.Helper()
+
+	tx, err := types.NewTransaction(types.NewTransactionParam{
+		Message: types.NewMessage(types.NewMessageParam{
+			FeePayer:        from.PublicKey,
+			RecentBlockhash: types.NewAccount().PublicKey.ToBase58(),
+			Instructions: []types.Instruction{
+				system.Transfer(system.TransferParam{From: from.PublicKey, To: to, Amount: amount}),
+			},
+		}),
+		Signers: []types.Account{from},
+	})
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+
+	payload, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+
+	signature := base58.Encode(tx.Signatures[0])
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.slot++
+	n.txs[signature] = base64.StdEncoding.EncodeToString(payload)
+	for _, address := range []string{from.PublicKey.ToBase58(), to.ToBase58()} {
+		n.signatures[address] = append([]string{signature}, n.signatures[address]...)
+	}
+
+	return signature
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var key string
+	_ = json.Unmarshal(req.Params[0], &key)
+
+	var result any
+	switch req.Method {
+	case "getSignaturesForAddress":
+		var cfg struct {
+			Limit  int    `json:"limit"`
+			Before string `json:"before"`
+			Until  string `json:"until"`
+		}
+		if len(req.Params) > 1 {
+			_ = json.Unmarshal(req.Params[1], &cfg)
+		}
+
+		page := []map[string]any{}
+		started := cfg.Before == ""
+		for _, signature := range n.signatures[key] {
+			if !started {
+				started = signature == cfg.Before
+				continue
+			}
+			if signature == cfg.Until || (cfg.Limit > 0 && len(page) == cfg.Limit) {
+				break
+			}
+			page = append(page, map[string]any{"signature": signature, "slot": n.slot})
+		}
+		result = page
+	case "getTransaction":
+		result = map[string]any{
+			"slot":        n.slot,
+			"blockTime":   1_700_000_000,
+			"transaction": []string{n.txs[key], "base64"},
+			"meta": map[string]any{
+				"err":               nil,
+				"fee":               5000,
+				"preBalances":       []int64{},
+				"postBalances":      []int64{},
+				"innerInstructions": []any{},
+			},
+		}
+	default:
+		http.Error(w, "unexpected method "+req.Method, http.StatusBadRequest)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": result})
+}
+
+type deposits struct {
+	mu         sync.Mutex
+	signatures map[string][]string
+	// wallets whose deposits the handler refuses
+	failing map[string]bool
+}
+
+func (d *deposits) handle(ctx context.Context, events []*solana.TransferEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, event := range events {
+		if d.failing[event.To] {
+			return errors.New("handler failed")
+		}
+		d.signatures[event.To] = append(d.signatures[event.To], event.Signature)
+	}
+
+	return nil
+}
+
+func TestDepositIndexer(t *testing.T) {
+	node := newFakeNode()
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	ctx := context.Background()
+	sender := types.NewAccount()
+	wallet := types.NewAccount().PublicKey
+	other := types.NewAccount().PublicKey
+
+	node.transfer(t, sender, wallet, 1)
+
+	store := solana.NewMemoryCheckpointStore()
+	indexer := solana.NewDepositIndexer(server.URL, store)
+	if err := indexer.Watch(ctx, wallet.ToBase58()); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := indexer.Watch(ctx, other.ToBase58()); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// made between Watch and the first Poll
+	first := node.transfer(t, sender, wallet, 2)
+	second := node.transfer(t, sender, wallet, 3)
+	toOther := node.transfer(t, sender, other, 4)
+
+	got := &deposits{signatures: make(map[string][]string)}
+	if err := indexer.Poll(ctx, got.handle); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	if want := []string{first, second}; !slices.Equal(got.signatures[wallet.ToBase58()], want) {
+		t.Errorf("deposits to wallet = %v, want %v", got.signatures[wallet.ToBase58()], want)
+	}
+	if want := []string{toOther}; !slices.Equal(got.signatures[other.ToBase58()], want) {
+		t.Errorf("deposits to other = %v, want %v", got.signatures[other.ToBase58()], want)
+	}
+
+	checkpoint, _ := store.LoadCheckpoint(ctx, wallet.ToBase58())
+	if checkpoint == nil || checkpoint.Signature != second {
+		t.Errorf("checkpoint = %+v, want %s", checkpoint, second)
+	}
+
+	// a new indexer over the same store resumes after the checkpoints
+	third := node.transfer(t, sender, wallet, 5)
+	secondToOther := node.transfer(t, sender, other, 6)
+
+	resumed := solana.NewDepositIndexer(server.URL, store)
+	for _, address := range []string{wallet.ToBase58(), other.ToBase58()} {
+		if err := resumed.Watch(ctx, address); err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+	}
+
+	// a failing account does not hold back the other one
+	got = &deposits{
+		signatures: make(map[string][]string),
+		failing:    map[string]bool{other.ToBase58(): true},
+	}
+	if err := resumed.Poll(ctx, got.handle); err == nil {
+		t.Errorf("Poll() error = nil, want the handler error")
+	}
+
+	if want := []string{third}; !slices.Equal(got.signatures[wallet.ToBase58()], want) {
+		t.Errorf("deposits to wallet after resume = %v, want %v", got.signatures[wallet.ToBase58()], want)
+	}
+
+	checkpoint, _ = store.LoadCheckpoint(ctx, other.ToBase58())
+	if checkpoint == nil || checkpoint.Signature != toOther {
+		t.Errorf("checkpoint of the failing account = %+v, want %s", checkpoint, toOther)
+	}
+
+	got.failing = nil
+	if err := resumed.Poll(ctx, got.handle); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	if want := []string{secondToOther}; !slices.Equal(got.signatures[other.ToBase58()], want) {
+		t.Errorf("deposits to other after retry = %v, want %v", got.signatures[other.ToBase58()], want)
+	}
+	if want := []string{third}; !slices.Equal(got.signatures[wallet.ToBase58()], want) {
+		t.Errorf("deposits to wallet after retry = %v, want %v", got.signatures[wallet.ToBase58()], want)
+	}
+}
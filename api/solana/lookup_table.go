@@ -0,0 +1,171 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/address_lookup_table"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// maxExtendAddresses keeps an extend transaction well below the transaction size limit.
+const maxExtendAddresses = 20
+
+type CreateLookupTableInput struct {
+	AppId   string
+	Network string
+	// Authority owns the lookup table and pays its rent, usually the hot wallet
+	Authority string
+}
+
+type ExtendLookupTableInput struct {
+	AppId       string
+	Network     string
+	Authority   string
+	LookupTable string
+	// addresses already stored in the table are skipped
+	Addresses []string
+}
+
+// CreateLookupTable creates an empty address lookup table owned by the authority and returns its address.
+func (a *SolanaApi) CreateLookupTable(ctx context.Context, input *CreateLookupTableInput) (string, *_types.TransferMessage, error) {
+	c := client.NewClient(a.endpoint)
+
+	authority, err := a.account(ctx, input.AppId, input.Network, input.Authority)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the derivation slot has to be a recent one known to the slot hashes sysvar
+	slot, err := c.GetSlotWithConfig(ctx, client.GetSlotConfig{
+		Commitment: rpc.CommitmentFinalized,
+	})
+	if err != nil {
+		log.Printf("failed to get slot, err: %v\n", err)
+		return "", nil, err
+	}
+
+	lookupTable, bump := address_lookup_table.DeriveLookupTableAddress(authority.PublicKey, slot)
+
+	txHash, err := a.sendInstructions(ctx, c, []types.Account{authority}, []types.Instruction{
+		address_lookup_table.CreateLookupTable(address_lookup_table.CreateLookupTableParams{
+			LookupTable: lookupTable,
+			Authority:   authority.PublicKey,
+			Payer:       authority.PublicKey,
+			RecentSlot:  slot,
+			BumpSeed:    bump,
+		}),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	log.Printf("lookup table %s created, tx: %s\n", lookupTable.ToBase58(), txHash)
+
+	return lookupTable.ToBase58(), &_types.TransferMessage{
+		Hash: []byte(txHash),
+	}, nil
+}
+
+// ExtendLookupTable appends the addresses to the lookup table, one transaction is sent per
+// maxExtendAddresses new addresses. Extended addresses become usable one slot later.
+func (a *SolanaApi) ExtendLookupTable(ctx context.Context, input *ExtendLookupTableInput) ([]*_types.TransferMessage, error) {
+	c := client.NewClient(a.endpoint)
+
+	authority, err := a.account(ctx, input.AppId, input.Network, input.Authority)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := a.getLookupTables(ctx, c, []string{input.LookupTable})
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[common.PublicKey]bool)
+	for _, address := range tables[0].Addresses {
+		stored[address] = true
+	}
+
+	var addresses []common.PublicKey
+	for _, address := range input.Addresses {
+		key := common.PublicKeyFromString(address)
+		if stored[key] {
+			continue
+		}
+		stored[key] = true
+		addresses = append(addresses, key)
+	}
+
+	if uint(len(tables[0].Addresses)+len(addresses)) > address_lookup_table.LOOKUP_TABLE_MAX_ADDRESSES {
+		return nil, fmt.Errorf("lookup table %s holds %d addresses, cannot add %d more",
+			input.LookupTable, len(tables[0].Addresses), len(addresses))
+	}
+
+	var messages []*_types.TransferMessage
+	for start := 0; start < len(addresses); start += maxExtendAddresses {
+		end := min(start+maxExtendAddresses, len(addresses))
+
+		txHash, err := a.sendInstructions(ctx, c, []types.Account{authority}, []types.Instruction{
+			address_lookup_table.ExtendLookupTable(address_lookup_table.ExtendLookupTableParams{
+				LookupTable: tables[0].Key,
+				Authority:   authority.PublicKey,
+				Payer:       &authority.PublicKey,
+				Addresses:   addresses[start:end],
+			}),
+		})
+		if err != nil {
+			return messages, err
+		}
+
+		log.Printf("lookup table %s extended with %d addresses, tx: %s\n", input.LookupTable, end-start, txHash)
+
+		messages = append(messages, &_types.TransferMessage{
+			Hash: []byte(txHash),
+		})
+	}
+
+	return messages, nil
+}
+
+// getLookupTables loads the lookup tables messages are compiled against, deactivated tables are rejected.
+func (a *SolanaApi) getLookupTables(ctx context.Context, c *client.Client, addresses []string) ([]types.AddressLookupTableAccount, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	accounts, err := c.GetMultipleAccounts(ctx, addresses)
+	if err != nil {
+		log.Printf("failed to get lookup tables, err: %v\n", err)
+		return nil, err
+	}
+
+	tables := make([]types.AddressLookupTableAccount, 0, len(accounts))
+	for i, account := range accounts {
+		if account.Lamports == 0 {
+			return nil, fmt.Errorf("lookup table %s is not found", addresses[i])
+		}
+
+		table, err := address_lookup_table.DeserializeLookupTable(account.Data, account.Owner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse lookup table %s: %w", addresses[i], err)
+		}
+
+		if table.DeactivationSlot != math.MaxUint64 {
+			return nil, fmt.Errorf("lookup table %s is deactivated", addresses[i])
+		}
+
+		tables = append(tables, types.AddressLookupTableAccount{
+			Key:       common.PublicKeyFromString(addresses[i]),
+			Addresses: table.Addresses,
+		})
+	}
+
+	return tables, nil
+}
@@ -0,0 +1,58 @@
+package solana
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/memo"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// MaxMemoSize is the largest memo in bytes that fits a plain SOL transfer, it is only a quick check. Token
+// transfers, durable nonces and compute budget instructions leave less room, checkTransactionSize has the final say.
+const MaxMemoSize = 566
+
+// ValidateMemo checks the memo against the rules of the memo program, it has to be valid UTF-8
+// and small enough to fit into the transaction.
+func ValidateMemo(text string) error {
+	if len(text) > MaxMemoSize {
+		return _types.WrapErr(_types.ErrInvalidMemo, fmt.Errorf("memo is %d bytes, at most %d bytes are allowed", len(text), MaxMemoSize))
+	}
+
+	if !utf8.ValidString(text) {
+		return _types.WrapErr(_types.ErrInvalidMemo, fmt.Errorf("memo is not valid UTF-8"))
+	}
+
+	return nil
+}
+
+// checkTransactionSize makes sure the instructions, once the compute budget instructions are added, fit into
+// a single transaction. A transaction that only overflows because of its memo is rejected as an invalid memo.
+func checkTransactionSize(feePayer common.PublicKey, blockhash string, instructions []types.Instruction, memo string) error {
+	fits, err := fitsTransaction(feePayer, blockhash, instructions, nil)
+	if err != nil {
+		return err
+	}
+
+	if fits {
+		return nil
+	}
+
+	if memo != "" {
+		return _types.WrapErr(_types.ErrInvalidMemo,
+			fmt.Errorf("memo of %d bytes does not fit into the transaction, it exceeds %d bytes", len(memo), maxTransactionSize))
+	}
+
+	return fmt.Errorf("transaction exceeds %d bytes or %d accounts", maxTransactionSize, maxTransactionAccounts)
+}
+
+// memoInstruction returns a memo program instruction signed by the signer so that the memo
+// cannot be attached to the transfer by anyone else.
+func memoInstruction(text string, signer common.PublicKey) types.Instruction {
+	return memo.BuildMemo(memo.BuildMemoParam{
+		SignerPubkeys: []common.PublicKey{signer},
+		Memo:          []byte(text),
+	})
+}
@@ -0,0 +1,112 @@
+package solana_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/solana"
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/memo"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+func isInvalidMemo(err error) bool {
+	var typed *_types.Error
+	return errors.As(err, &typed) && typed.Code == _types.ErrInvalidMemo.Code
+}
+
+func TestValidateMemo(t *testing.T) {
+	tests := []struct {
+		name    string
+		memo    string
+		wantErr bool
+	}{
+		{name: "empty", memo: ""},
+		{name: "text", memo: "invoice 1024"},
+		{name: "largest", memo: strings.Repeat("a", solana.MaxMemoSize)},
+		// 283 two-byte characters are the largest memo in bytes, not in characters
+		{name: "largest multibyte", memo: strings.Repeat("é", solana.MaxMemoSize/2)},
+		{name: "one byte too large", memo: strings.Repeat("a", solana.MaxMemoSize+1), wantErr: true},
+		{name: "multibyte too large", memo: strings.Repeat("é", solana.MaxMemoSize/2+1), wantErr: true},
+		{name: "invalid UTF-8", memo: "invoice \xff", wantErr: true},
+		{name: "truncated character", memo: "\xc3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := solana.ValidateMemo(tt.memo)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ValidateMemo() error = %v", err)
+				}
+				return
+			}
+
+			if !isInvalidMemo(err) {
+				t.Errorf("ValidateMemo() error = %v, want %s", err, _types.ErrInvalidMemo.Message)
+			}
+		})
+	}
+}
+
+func TestCheckTransactionSize(t *testing.T) {
+	from := types.NewAccount().PublicKey
+	to := types.NewAccount().PublicKey
+
+	transfer := system.Transfer(system.TransferParam{From: from, To: to, Amount: 1})
+	withMemo := func(text string) []types.Instruction {
+		return []types.Instruction{transfer, memo.BuildMemo(memo.BuildMemoParam{
+			SignerPubkeys: []common.PublicKey{from},
+			Memo:          []byte(text),
+		})}
+	}
+
+	// a plain transfer with a memo and the compute budget instructions takes 304 bytes besides the memo: 65 of
+	// signatures, 3 of header, 161 of five account keys, 32 of blockhash and 43 of instructions
+	const largest = 1232 - 304
+
+	tests := []struct {
+		name         string
+		instructions []types.Instruction
+		memo         string
+		wantErr      bool
+		wantMemoErr  bool
+	}{
+		{name: "no memo", instructions: []types.Instruction{transfer}},
+		{name: "short memo", instructions: withMemo("invoice 1024"), memo: "invoice 1024"},
+		// every memo ValidateMemo accepts fits a plain transfer
+		{name: "memo at the quick check", instructions: withMemo(strings.Repeat("a", solana.MaxMemoSize)), memo: strings.Repeat("a", solana.MaxMemoSize)},
+		{name: "largest memo", instructions: withMemo(strings.Repeat("a", largest)), memo: strings.Repeat("a", largest)},
+		{
+			name:         "memo one byte too large",
+			instructions: withMemo(strings.Repeat("a", largest+1)),
+			memo:         strings.Repeat("a", largest+1),
+			wantErr:      true,
+			wantMemoErr:  true,
+		},
+		{
+			name:         "too large without a memo",
+			instructions: []types.Instruction{transfer, {ProgramID: types.NewAccount().PublicKey, Data: make([]byte, 1232)}},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := solana.CheckTransactionSize(from, latestBlockhash, tt.instructions, tt.memo)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("CheckTransactionSize() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || isInvalidMemo(err) != tt.wantMemoErr {
+				t.Errorf("CheckTransactionSize() error = %v, want an invalid memo error %v", err, tt.wantMemoErr)
+			}
+		})
+	}
+}
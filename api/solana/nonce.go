@@ -0,0 +1,115 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+type CreateNonceAccountInput struct {
+	AppId   string
+	Network string
+	// Authority funds the nonce account, derives its address and is allowed to advance it
+	Authority string
+	// Seed the nonce account address is derived with, one authority may own many nonce accounts
+	Seed string
+}
+
+// NonceAccountAddress returns the address of the nonce account derived from the authority and seed.
+func NonceAccountAddress(authority, seed string) common.PublicKey {
+	return common.CreateWithSeed(common.PublicKeyFromString(authority), seed, common.SystemProgramID)
+}
+
+// CreateNonceAccount creates and initializes a durable nonce account in a single transaction,
+// the returned address can be used as TransferInput.NonceAccount once the transaction is confirmed.
+func (a *SolanaApi) CreateNonceAccount(ctx context.Context, input *CreateNonceAccountInput) (string, *_types.TransferMessage, error) {
+	client := client.NewClient(a.endpoint)
+
+	authority, err := a.account(ctx, input.AppId, input.Network, input.Authority)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonceAccount := NonceAccountAddress(input.Authority, input.Seed)
+
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, system.NonceAccountSize)
+	if err != nil {
+		log.Printf("failed to get rent exemption, err: %v\n", err)
+		return "", nil, err
+	}
+
+	txHash, err := a.sendInstructions(ctx, client, []types.Account{authority}, []types.Instruction{
+		system.CreateAccountWithSeed(system.CreateAccountWithSeedParam{
+			From:     authority.PublicKey,
+			New:      nonceAccount,
+			Base:     authority.PublicKey,
+			Owner:    common.SystemProgramID,
+			Seed:     input.Seed,
+			Lamports: rent,
+			Space:    system.NonceAccountSize,
+		}),
+		system.InitializeNonceAccount(system.InitializeNonceAccountParam{
+			Nonce: nonceAccount,
+			Auth:  authority.PublicKey,
+		}),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	log.Printf("nonce account %s created, tx: %s\n", nonceAccount.ToBase58(), txHash)
+
+	return nonceAccount.ToBase58(), &_types.TransferMessage{
+		Hash: []byte(txHash),
+	}, nil
+}
+
+// GetNonce returns the durable nonce currently stored in the nonce account.
+func (a *SolanaApi) GetNonce(ctx context.Context, nonceAccount string) (string, error) {
+	client := client.NewClient(a.endpoint)
+
+	account, err := client.GetNonceAccount(ctx, nonceAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to get nonce account %s: %w", nonceAccount, err)
+	}
+
+	return account.Nonce.ToBase58(), nil
+}
+
+// recentBlockhash returns the value signed as the recent blockhash of the transaction. In durable
+// nonce mode it is the stored nonce and the returned advance instruction must be the first one.
+func (a *SolanaApi) recentBlockhash(ctx context.Context, client *client.Client, input *_types.TransferInput, authority common.PublicKey) (string, []types.Instruction, error) {
+	if input.NonceAccount == "" {
+		res, err := client.GetLatestBlockhash(ctx)
+		if err != nil {
+			log.Printf("failed to get latest blockhash, err: %v\n", err)
+			return "", nil, err
+		}
+
+		return res.Blockhash, nil, nil
+	}
+
+	account, err := client.GetNonceAccount(ctx, input.NonceAccount)
+	if err != nil {
+		log.Printf("failed to get nonce account, err: %v\n", err)
+		return "", nil, fmt.Errorf("failed to get nonce account %s: %w", input.NonceAccount, err)
+	}
+
+	if account.AuthorizedPubkey != authority {
+		return "", nil, fmt.Errorf("nonce account %s is authorized to %s, not %s",
+			input.NonceAccount, account.AuthorizedPubkey.ToBase58(), authority.ToBase58())
+	}
+
+	return account.Nonce.ToBase58(), []types.Instruction{
+		system.AdvanceNonceAccount(system.AdvanceNonceAccountParam{
+			Nonce: common.PublicKeyFromString(input.NonceAccount),
+			Auth:  authority,
+		}),
+	}, nil
+}
@@ -0,0 +1,177 @@
+package solana_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/solana"
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+const latestBlockhash = "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N"
+
+// nonceAccountInfo returns the getAccountInfo result of an initialized nonce account owned by owner.
+func nonceAccountInfo(owner string, authority, nonce common.PublicKey) map[string]any {
+	data := make([]byte, system.NonceAccountSize)
+	binary.LittleEndian.PutUint32(data[0:], 1) // current version
+	binary.LittleEndian.PutUint32(data[4:], 1) // initialized
+	copy(data[8:], authority.Bytes())
+	copy(data[40:], nonce.Bytes())
+	binary.LittleEndian.PutUint64(data[72:], 5_000) // lamports per signature
+
+	return map[string]any{
+		"context": map[string]any{"slot": 300_000_000},
+		"value": map[string]any{
+			"lamports":   1_447_680,
+			"owner":      owner,
+			"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+			"executable": false,
+			"rentEpoch":  0,
+		},
+	}
+}
+
+func TestNonceAccountAddress(t *testing.T) {
+	authority := types.NewAccount().PublicKey.ToBase58()
+
+	first := solana.NonceAccountAddress(authority, "nonce-1")
+	if got := solana.NonceAccountAddress(authority, "nonce-1"); got != first {
+		t.Errorf("NonceAccountAddress() = %s, want the same address %s for the same seed", got, first)
+	}
+	if got := solana.NonceAccountAddress(authority, "nonce-2"); got == first {
+		t.Errorf("NonceAccountAddress() of another seed = %s, want another address", got)
+	}
+	if want := common.CreateWithSeed(common.PublicKeyFromString(authority), "nonce-1", common.SystemProgramID); first != want {
+		t.Errorf("NonceAccountAddress() = %s, want %s", first, want)
+	}
+}
+
+func TestGetNonce(t *testing.T) {
+	authority := types.NewAccount().PublicKey
+	nonce := types.NewAccount().PublicKey
+	nonceAccount := types.NewAccount().PublicKey.ToBase58()
+
+	tests := []struct {
+		name    string
+		info    map[string]any
+		want    string
+		wantErr bool
+	}{
+		{name: "initialized", info: nonceAccountInfo(common.SystemProgramID.ToBase58(), authority, nonce), want: nonce.ToBase58()},
+		{name: "not owned by the system program", info: nonceAccountInfo(common.TokenProgramID.ToBase58(), authority, nonce), wantErr: true},
+		{
+			name: "too short",
+			info: map[string]any{
+				"context": map[string]any{"slot": 300_000_000},
+				"value": map[string]any{
+					"lamports":   1_447_680,
+					"owner":      common.SystemProgramID.ToBase58(),
+					"data":       []string{base64.StdEncoding.EncodeToString(make([]byte, 40)), "base64"},
+					"executable": false,
+					"rentEpoch":  0,
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRPCServer(t, map[string]any{"getAccountInfo": tt.info})
+			defer server.Close()
+
+			got, err := solana.NewSolanaApi(api.NewSignerProvider(), server.URL, nil).GetNonce(context.Background(), nonceAccount)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetNonce() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetNonce() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("GetNonce() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecentBlockhash(t *testing.T) {
+	authority := types.NewAccount().PublicKey
+	nonce := types.NewAccount().PublicKey
+	nonceAccount := types.NewAccount().PublicKey
+
+	server := newRPCServer(t, map[string]any{
+		"getAccountInfo": nonceAccountInfo(common.SystemProgramID.ToBase58(), authority, nonce),
+		"getLatestBlockhash": map[string]any{
+			"context": map[string]any{"slot": 300_000_000},
+			"value":   map[string]any{"blockhash": latestBlockhash, "lastValidBlockHeight": 280_000_150},
+		},
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		nonceAccount string
+		authority    common.PublicKey
+		want         string
+		wantAdvance  bool
+		wantErr      string
+	}{
+		{name: "latest blockhash", authority: authority, want: latestBlockhash},
+		{name: "durable nonce", nonceAccount: nonceAccount.ToBase58(), authority: authority, want: nonce.ToBase58(), wantAdvance: true},
+		{
+			name:         "nonce of another authority",
+			nonceAccount: nonceAccount.ToBase58(),
+			authority:    types.NewAccount().PublicKey,
+			wantErr:      "is authorized to " + authority.ToBase58(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &_types.TransferInput{NonceAccount: tt.nonceAccount}
+
+			got, instructions, err := solana.NewSolanaApi(api.NewSignerProvider(), server.URL, nil).
+				RecentBlockhash(context.Background(), input, tt.authority)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RecentBlockhash() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RecentBlockhash() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("RecentBlockhash() = %s, want %s", got, tt.want)
+			}
+
+			if !tt.wantAdvance {
+				if len(instructions) != 0 {
+					t.Errorf("RecentBlockhash() returned %d instructions, want none", len(instructions))
+				}
+				return
+			}
+
+			// the advance instruction is signed by the authority and advances the nonce account
+			want := system.AdvanceNonceAccount(system.AdvanceNonceAccountParam{Nonce: nonceAccount, Auth: authority})
+			if len(instructions) != 1 || string(instructions[0].Data) != string(want.Data) || instructions[0].ProgramID != common.SystemProgramID {
+				t.Fatalf("RecentBlockhash() instructions = %+v, want an advance nonce instruction", instructions)
+			}
+			if instructions[0].Accounts[0].PubKey != nonceAccount || instructions[0].Accounts[2].PubKey != authority || !instructions[0].Accounts[2].IsSigner {
+				t.Errorf("advance nonce accounts = %+v, want %s advanced by %s", instructions[0].Accounts, nonceAccount, authority)
+			}
+		})
+	}
+}
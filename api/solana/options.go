@@ -0,0 +1,93 @@
+package solana
+
+import "time"
+
+const (
+	// MaxComputeUnitLimit is the largest compute unit limit a transaction may request.
+	MaxComputeUnitLimit uint32 = 1_400_000
+
+	defaultComputeUnitMargin     = 0.2
+	defaultPriorityFeePercentile = 75
+	microLamportsPerLamport      = 1_000_000
+
+	defaultConfirmationPollInterval = 2 * time.Second
+	defaultRebroadcastInterval      = 4 * time.Second
+)
+
+type Options struct {
+	// extra share of the simulated compute units added to the requested limit
+	computeUnitMargin float64
+	// caps applied to the compute budget instructions
+	maxComputeUnitLimit uint32
+	minComputeUnitPrice uint64
+	maxComputeUnitPrice uint64
+	// upper bound of the priority fee in lamports, zero disables it
+	maxPriorityFee uint64
+	// percentile of the recent prioritization fees used as the unit price
+	priorityFeePercentile int
+
+	// how often the signature status is polled while tracking a transaction
+	confirmationPollInterval time.Duration
+	// how often a pending transaction is sent again while tracking it
+	rebroadcastInterval time.Duration
+}
+
+type Option func(*Options)
+
+// WithComputeUnitMargin sets the share of simulated compute units added on top
+// of the consumed amount, e.g. 0.2 requests 120% of what the simulation used.
+func WithComputeUnitMargin(v float64) Option {
+	return func(o *Options) {
+		o.computeUnitMargin = v
+	}
+}
+
+func WithMaxComputeUnitLimit(v uint32) Option {
+	return func(o *Options) {
+		o.maxComputeUnitLimit = v
+	}
+}
+
+// WithComputeUnitPrice bounds the compute unit price in micro-lamports.
+func WithComputeUnitPrice(min, max uint64) Option {
+	return func(o *Options) {
+		o.minComputeUnitPrice = min
+		o.maxComputeUnitPrice = max
+	}
+}
+
+// WithMaxPriorityFee caps the total priority fee paid per transaction in lamports.
+func WithMaxPriorityFee(v uint64) Option {
+	return func(o *Options) {
+		o.maxPriorityFee = v
+	}
+}
+
+func WithPriorityFeePercentile(v int) Option {
+	return func(o *Options) {
+		o.priorityFeePercentile = v
+	}
+}
+
+func WithConfirmationPollInterval(v time.Duration) Option {
+	return func(o *Options) {
+		o.confirmationPollInterval = v
+	}
+}
+
+func WithRebroadcastInterval(v time.Duration) Option {
+	return func(o *Options) {
+		o.rebroadcastInterval = v
+	}
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		computeUnitMargin:     defaultComputeUnitMargin,
+		maxComputeUnitLimit:   MaxComputeUnitLimit,
+		priorityFeePercentile: defaultPriorityFeePercentile,
+
+		confirmationPollInterval: defaultConfirmationPollInterval,
+		rebroadcastInterval:      defaultRebroadcastInterval,
+	}
+}
@@ -0,0 +1,259 @@
+package solana
+
+import (
+	"encoding/binary"
+	"math/big"
+	"strings"
+
+	"github.com/mr-tron/base58"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+const (
+	// SolDecimals is the number of decimals of lamports in a SOL.
+	SolDecimals = 9
+
+	tokenInstructionTransfer        = 3
+	tokenInstructionTransferChecked = 12
+)
+
+// memo program v1, still used by some wallets and exchanges
+var legacyMemoProgramID = common.PublicKeyFromString("Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo")
+
+// TransferEvent is a SOL or SPL token transfer found in a transaction.
+type TransferEvent struct {
+	Signature string
+	Slot      uint64
+	// unix time of the block, zero when the node does not know it
+	BlockTime int64
+	// index of the outer instruction and of the inner instruction within it, -1 for outer instructions
+	InstructionIndex int
+	InnerIndex       int
+	// wallets of the sender and the recipient, for SPL transfers the owners of the token accounts
+	// when the transaction meta reports them
+	From string
+	To   string
+	// token accounts of SPL transfers
+	FromTokenAccount string
+	ToTokenAccount   string
+	// mint of the SPL token, empty for SOL
+	Mint     string
+	Decimals uint8
+	Amount   *big.Int
+	// memos attached to the transaction, joined by "; "
+	Memo string
+}
+
+// ParseTransfers extracts the SOL and SPL token transfers of a transaction, including the ones made by
+// inner instructions, in execution order. Failed transactions transfer nothing and return no events.
+func ParseTransfers(tx *client.Transaction) []*TransferEvent {
+	if tx == nil || tx.Meta == nil || tx.Meta.Err != nil {
+		return nil
+	}
+
+	p := &transferParser{
+		tx:     tx,
+		owners: make(map[int]string),
+		mints:  make(map[int]rpc.TransactionMetaTokenBalance),
+	}
+	for _, balances := range [][]rpc.TransactionMetaTokenBalance{tx.Meta.PreTokenBalances, tx.Meta.PostTokenBalances} {
+		for _, balance := range balances {
+			p.mints[int(balance.AccountIndex)] = balance
+			if balance.Owner != "" {
+				p.owners[int(balance.AccountIndex)] = balance.Owner
+			}
+		}
+	}
+
+	inner := make(map[int][]types.CompiledInstruction)
+	for _, instructions := range tx.Meta.InnerInstructions {
+		inner[int(instructions.Index)] = instructions.Instructions
+	}
+
+	var events []*TransferEvent
+	var memos []string
+	for i, instruction := range tx.Transaction.Message.Instructions {
+		if memo, ok := p.memo(instruction); ok {
+			memos = append(memos, memo)
+		}
+		if event := p.transfer(instruction); event != nil {
+			event.InstructionIndex, event.InnerIndex = i, -1
+			events = append(events, event)
+		}
+
+		for j, instruction := range inner[i] {
+			if memo, ok := p.memo(instruction); ok {
+				memos = append(memos, memo)
+			}
+			if event := p.transfer(instruction); event != nil {
+				event.InstructionIndex, event.InnerIndex = i, j
+				events = append(events, event)
+			}
+		}
+	}
+
+	var blockTime int64
+	if tx.BlockTime != nil {
+		blockTime = *tx.BlockTime
+	}
+
+	signature := signatureOf(tx.Transaction)
+	for _, event := range events {
+		event.Signature = signature
+		event.Slot = tx.Slot
+		event.BlockTime = blockTime
+		event.Memo = strings.Join(memos, "; ")
+	}
+
+	return events
+}
+
+type transferParser struct {
+	tx *client.Transaction
+	// owners and token balances of the token accounts by account index
+	owners map[int]string
+	mints  map[int]rpc.TransactionMetaTokenBalance
+}
+
+func (p *transferParser) account(instruction types.CompiledInstruction, i int) (common.PublicKey, bool) {
+	if i >= len(instruction.Accounts) || instruction.Accounts[i] >= len(p.tx.AccountKeys) {
+		return common.PublicKey{}, false
+	}
+
+	return p.tx.AccountKeys[instruction.Accounts[i]], true
+}
+
+func (p *transferParser) programID(instruction types.CompiledInstruction) common.PublicKey {
+	if instruction.ProgramIDIndex >= len(p.tx.AccountKeys) {
+		return common.PublicKey{}
+	}
+
+	return p.tx.AccountKeys[instruction.ProgramIDIndex]
+}
+
+func (p *transferParser) memo(instruction types.CompiledInstruction) (string, bool) {
+	switch p.programID(instruction) {
+	case common.MemoProgramID, legacyMemoProgramID:
+		return string(instruction.Data), true
+	}
+
+	return "", false
+}
+
+func (p *transferParser) transfer(instruction types.CompiledInstruction) *TransferEvent {
+	switch p.programID(instruction) {
+	case common.SystemProgramID:
+		return p.systemTransfer(instruction)
+	case common.TokenProgramID, common.Token2022ProgramID:
+		return p.tokenTransfer(instruction)
+	}
+
+	return nil
+}
+
+func (p *transferParser) systemTransfer(instruction types.CompiledInstruction) *TransferEvent {
+	if len(instruction.Data) < 12 {
+		return nil
+	}
+
+	// accounts: transfer [from, to], transfer with seed [from, base, to]
+	var toIndex int
+	switch system.Instruction(binary.LittleEndian.Uint32(instruction.Data)) {
+	case system.InstructionTransfer:
+		toIndex = 1
+	case system.InstructionTransferWithSeed:
+		toIndex = 2
+	default:
+		return nil
+	}
+
+	from, ok := p.account(instruction, 0)
+	if !ok {
+		return nil
+	}
+
+	to, ok := p.account(instruction, toIndex)
+	if !ok {
+		return nil
+	}
+
+	return &TransferEvent{
+		From:     from.ToBase58(),
+		To:       to.ToBase58(),
+		Decimals: SolDecimals,
+		Amount:   new(big.Int).SetUint64(binary.LittleEndian.Uint64(instruction.Data[4:12])),
+	}
+}
+
+func (p *transferParser) tokenTransfer(instruction types.CompiledInstruction) *TransferEvent {
+	if len(instruction.Data) < 9 {
+		return nil
+	}
+
+	// accounts: transfer [source, destination, authority], transfer checked [source, mint, destination, authority]
+	var destinationIndex, authorityIndex int
+	switch instruction.Data[0] {
+	case tokenInstructionTransfer:
+		destinationIndex, authorityIndex = 1, 2
+	case tokenInstructionTransferChecked:
+		destinationIndex, authorityIndex = 2, 3
+	default:
+		return nil
+	}
+
+	source, ok := p.account(instruction, 0)
+	if !ok {
+		return nil
+	}
+
+	destination, ok := p.account(instruction, destinationIndex)
+	if !ok {
+		return nil
+	}
+
+	event := &TransferEvent{
+		FromTokenAccount: source.ToBase58(),
+		ToTokenAccount:   destination.ToBase58(),
+		Amount:           new(big.Int).SetUint64(binary.LittleEndian.Uint64(instruction.Data[1:9])),
+	}
+
+	if authority, ok := p.account(instruction, authorityIndex); ok {
+		event.From = authority.ToBase58()
+	}
+	if owner, ok := p.owners[instruction.Accounts[0]]; ok {
+		event.From = owner
+	}
+	if owner, ok := p.owners[instruction.Accounts[destinationIndex]]; ok {
+		event.To = owner
+	}
+
+	if balance, ok := p.mints[instruction.Accounts[destinationIndex]]; ok {
+		event.Mint = balance.Mint
+		event.Decimals = balance.UITokenAmount.Decimals
+	} else if balance, ok := p.mints[instruction.Accounts[0]]; ok {
+		event.Mint = balance.Mint
+		event.Decimals = balance.UITokenAmount.Decimals
+	}
+
+	if instruction.Data[0] == tokenInstructionTransferChecked && len(instruction.Data) >= 10 {
+		if mint, ok := p.account(instruction, 1); ok {
+			event.Mint = mint.ToBase58()
+		}
+		event.Decimals = instruction.Data[9]
+	}
+
+	return event
+}
+
+// signatureOf returns the base58 signature identifying the transaction.
+func signatureOf(tx types.Transaction) string {
+	if len(tx.Signatures) == 0 {
+		return ""
+	}
+
+	return base58.Encode(tx.Signatures[0])
+}
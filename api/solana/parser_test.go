@@ -0,0 +1,95 @@
+package solana_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/solana"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+func TestParseTransfers(t *testing.T) {
+	sender := types.NewAccount().PublicKey
+	recipient := types.NewAccount().PublicKey
+	source := types.NewAccount().PublicKey
+	destination := types.NewAccount().PublicKey
+	mint := types.NewAccount().PublicKey
+	router := types.NewAccount().PublicKey
+
+	accountKeys := []common.PublicKey{
+		sender, recipient, common.SystemProgramID, common.MemoProgramID,
+		common.TokenProgramID, source, destination, mint, router,
+	}
+
+	systemTransfer := make([]byte, 12)
+	binary.LittleEndian.PutUint32(systemTransfer, 2)
+	binary.LittleEndian.PutUint64(systemTransfer[4:], 1_000_000)
+
+	tokenTransfer := make([]byte, 10)
+	tokenTransfer[0] = 12
+	binary.LittleEndian.PutUint64(tokenTransfer[1:], 500)
+	tokenTransfer[9] = 6
+
+	blockTime := int64(1_700_000_000)
+	tx := &client.Transaction{
+		Slot:      42,
+		BlockTime: &blockTime,
+		Transaction: types.Transaction{
+			Signatures: []types.Signature{make([]byte, 64)},
+			Message: types.Message{
+				Accounts: accountKeys,
+				Instructions: []types.CompiledInstruction{
+					{ProgramIDIndex: 2, Accounts: []int{0, 1}, Data: systemTransfer},
+					{ProgramIDIndex: 3, Data: []byte("deposit-42")},
+					{ProgramIDIndex: 8, Accounts: []int{5, 6, 0}},
+				},
+			},
+		},
+		Meta: &client.TransactionMeta{
+			InnerInstructions: []client.InnerInstruction{
+				{
+					Index: 2,
+					Instructions: []types.CompiledInstruction{
+						{ProgramIDIndex: 4, Accounts: []int{5, 7, 6, 0}, Data: tokenTransfer},
+					},
+				},
+			},
+			PostTokenBalances: []rpc.TransactionMetaTokenBalance{
+				{AccountIndex: 6, Mint: mint.ToBase58(), Owner: recipient.ToBase58()},
+			},
+		},
+		AccountKeys: accountKeys,
+	}
+
+	events := solana.ParseTransfers(tx)
+	if len(events) != 2 {
+		t.Fatalf("ParseTransfers() returned %d events, want 2", len(events))
+	}
+
+	sol := events[0]
+	if sol.From != sender.ToBase58() || sol.To != recipient.ToBase58() || sol.Mint != "" ||
+		sol.Amount.Uint64() != 1_000_000 || sol.InstructionIndex != 0 || sol.InnerIndex != -1 {
+		t.Errorf("ParseTransfers() SOL transfer = %+v", sol)
+	}
+
+	spl := events[1]
+	if spl.From != sender.ToBase58() || spl.To != recipient.ToBase58() || spl.ToTokenAccount != destination.ToBase58() ||
+		spl.Mint != mint.ToBase58() || spl.Decimals != 6 || spl.Amount.Uint64() != 500 ||
+		spl.InstructionIndex != 2 || spl.InnerIndex != 0 {
+		t.Errorf("ParseTransfers() SPL transfer = %+v", spl)
+	}
+
+	for _, event := range events {
+		if event.Memo != "deposit-42" || event.Slot != 42 || event.BlockTime != blockTime {
+			t.Errorf("ParseTransfers() event = %+v, want memo, slot and block time of the transaction", event)
+		}
+	}
+
+	tx.Meta.Err = map[string]any{"InstructionError": []any{0, "Custom"}}
+	if events := solana.ParseTransfers(tx); len(events) != 0 {
+		t.Errorf("ParseTransfers() of a failed transaction returned %d events, want 0", len(events))
+	}
+}
@@ -2,14 +2,15 @@ package solana
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"math/big"
 
+	"github.com/mr-tron/base58"
 	"github.com/openweb3-io/blockchain/api"
 	_types "github.com/openweb3-io/blockchain/api/types"
 	"github.com/openweb3-io/solana-go-sdk/client"
 	"github.com/openweb3-io/solana-go-sdk/common"
-	"github.com/openweb3-io/solana-go-sdk/program/system"
 	"github.com/openweb3-io/solana-go-sdk/types"
 )
 
@@ -17,24 +18,105 @@ type SolanaApi struct {
 	signerProvider *api.SignerProvider
 	endpoint       string
 	chainId        *big.Int
+	opts           *Options
 }
 
-func NewSolanaApi(signerProvider *api.SignerProvider, endpoint string, chainId *big.Int) *SolanaApi {
-	return &SolanaApi{signerProvider, endpoint, chainId}
+func NewSolanaApi(signerProvider *api.SignerProvider, endpoint string, chainId *big.Int, o ...Option) *SolanaApi {
+	opts := defaultOptions()
+
+	for _, opt := range o {
+		opt(opts)
+	}
+
+	return &SolanaApi{signerProvider, endpoint, chainId, opts}
 }
 
+// EstimateGas returns the base signature fee plus the priority fee of the transfer in lamports.
 func (a *SolanaApi) EstimateGas(ctx context.Context, input *_types.TransferInput) (_types.TokenSymbol, *big.Int, error) {
-	// TODO implement
-	return _types.TOKEN_TYPE_NONE, nil, nil
+	client := client.NewClient(a.endpoint)
+
+	feePayer := common.PublicKeyFromString(feePayerAddress(input))
+
+	instructions, err := a.buildTransferInstructions(input)
+	if err != nil {
+		return _types.TOKEN_TYPE_NONE, nil, err
+	}
+
+	blockhash, nonceInstructions, err := a.recentBlockhash(ctx, client, input, feePayer)
+	if err != nil {
+		return _types.TOKEN_TYPE_NONE, nil, err
+	}
+	instructions = append(nonceInstructions, instructions...)
+
+	if err := checkTransactionSize(feePayer, blockhash, instructions, input.Memo); err != nil {
+		return _types.TOKEN_TYPE_NONE, nil, err
+	}
+
+	budget, err := a.estimateComputeBudget(ctx, client, feePayer, blockhash, instructions, nil)
+	if err != nil {
+		log.Printf("failed to estimate compute budget, err: %v\n", err)
+		return _types.TOKEN_TYPE_NONE, nil, err
+	}
+
+	fee, err := a.signatureFee(ctx, client, feePayer, instructions)
+	if err != nil {
+		return _types.TOKEN_TYPE_NONE, nil, err
+	}
+
+	return _types.TOKEN_TYPE_SOL, new(big.Int).SetUint64(fee + budget.PriorityFee()), nil
 }
 
+// PrepareTransaction builds and signs the transfer without sending it. When input.NonceAccount
+// is set the transaction is bound to the durable nonce and stays valid until the nonce advances,
+// otherwise it expires together with the latest blockhash. When input.SponsoredFee is set and
+// input.FeePayer is another account only the sender signs and the returned message has no hash yet,
+// the fee payer signature is added by Sponsor.CoSign before the transaction is broadcast.
 func (a *SolanaApi) PrepareTransaction(ctx context.Context, input *_types.TransferInput) (*_types.TransferMessage, error) {
-	// TODO implement
-	return nil, nil
+	client := client.NewClient(a.endpoint)
+
+	tx, err := a.buildTransaction(ctx, client, input, input.SponsoredFee)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &_types.TransferMessage{
+		Payload: payload,
+	}
+
+	// the transaction is identified by the fee payer signature, unknown until the sponsor signs
+	if !isSignatureEmpty(tx.Signatures[0]) {
+		message.Hash = []byte(base58.Encode(tx.Signatures[0]))
+	}
+
+	return message, nil
 }
 
 func (a *SolanaApi) BroadcastTransaction(ctx context.Context, input *_types.TransferMessage) error {
-	// TODO implement
+	client := client.NewClient(a.endpoint)
+
+	tx, err := types.TransactionDeserialize(input.Payload)
+	if err != nil {
+		return err
+	}
+
+	for i, signature := range tx.Signatures {
+		if isSignatureEmpty(signature) {
+			return fmt.Errorf("transaction is missing the signature of %s", tx.Message.Accounts[i].ToBase58())
+		}
+	}
+
+	txHash, err := client.SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("tx sent: %s\n", txHash)
+
 	return nil
 }
 
@@ -46,87 +128,189 @@ func (a *SolanaApi) GetWalletData(ctx context.Context, address string) (*_types.
 func (a *SolanaApi) Transfer(ctx context.Context, input *_types.TransferInput) (*_types.TransferMessage, error) {
 	client := client.NewClient(a.endpoint)
 
-	var feePayerAddress string
-	if len(input.FeePayer) != 0 {
-		feePayerAddress = input.FeePayer
-	} else {
-		feePayerAddress = input.FromAddress
+	tx, err := a.buildTransaction(ctx, client, input, false)
+	if err != nil {
+		return nil, err
 	}
 
-	feePayerSigner, err := a.signerProvider.Provide(ctx, input.AppId, input.Network, feePayerAddress)
+	payload, err := tx.Serialize()
 	if err != nil {
 		return nil, err
 	}
 
-	feePayer, err := types.AccountFromSigner(ctx, feePayerSigner)
+	txHash, err := client.SendTransaction(ctx, tx)
 	if err != nil {
-		log.Printf("feePayer address err: %v", err)
 		return nil, err
 	}
 
-	toPublicKey := common.PublicKeyFromString(input.ToAddress)
+	log.Printf("tx sent: %s\n", txHash)
+
+	// the signed payload is returned so the transaction can be tracked and re-broadcast by TrackTransaction
+	return &_types.TransferMessage{
+		Hash:    []byte(txHash),
+		Payload: payload,
+	}, nil
+}
+
+// buildTransaction builds and signs the transfer. A sponsored transaction is only signed by the sender,
+// the signature slot of a distinct fee payer is left empty for the sponsor to fill, see Sponsor.
+func (a *SolanaApi) buildTransaction(ctx context.Context, client *client.Client, input *_types.TransferInput, sponsored bool) (types.Transaction, error) {
+	from, err := a.account(ctx, input.AppId, input.Network, input.FromAddress)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	signers := []types.Account{from}
 
-	fromSigner, err := a.signerProvider.Provide(ctx, input.AppId, input.Network, input.FromAddress)
+	feePayer := common.PublicKeyFromString(feePayerAddress(input))
+	if feePayer != from.PublicKey && !sponsored {
+		feePayerAccount, err := a.account(ctx, input.AppId, input.Network, feePayerAddress(input))
+		if err != nil {
+			return types.Transaction{}, err
+		}
+
+		signers = append(signers, feePayerAccount)
+	}
+
+	instructions, err := a.buildTransferInstructions(input)
 	if err != nil {
-		return nil, err
+		return types.Transaction{}, err
 	}
 
-	from, err := types.AccountFromSigner(ctx, fromSigner)
+	blockhash, nonceInstructions, err := a.recentBlockhash(ctx, client, input, feePayer)
 	if err != nil {
-		log.Printf("from address err: %v", err)
-		return nil, err
+		return types.Transaction{}, err
 	}
+	instructions = append(nonceInstructions, instructions...)
 
-	res, err := client.GetLatestBlockhash(ctx)
+	if err := checkTransactionSize(feePayer, blockhash, instructions, input.Memo); err != nil {
+		return types.Transaction{}, err
+	}
+
+	fee, err := a.signatureFee(ctx, client, feePayer, instructions)
 	if err != nil {
-		log.Printf("failed to get latest blockhash, err: %v\n", err)
-		return nil, err
+		return types.Transaction{}, err
 	}
 
-	balance, err := client.GetBalance(
-		ctx,
-		input.FromAddress,
-	)
+	check := newBalanceCheck(from.PublicKey, feePayer)
+	check.addTransfer(input.ToAddress, input.ContractAddress, input.Amount.Uint64())
+	check.fee = fee
+
+	budget, err := a.estimateComputeBudget(ctx, client, feePayer, blockhash, instructions, nil)
 	if err != nil {
-		log.Printf("error get balance\n")
-		return nil, err
+		log.Printf("failed to estimate compute budget, err: %v\n", err)
+		// a transfer the accounts cannot afford fails the simulation, report it as a balance error
+		if balanceErr := a.checkBalances(ctx, client, check); balanceErr != nil {
+			return types.Transaction{}, balanceErr
+		}
+		return types.Transaction{}, err
 	}
 
-	// compare transfer amount
-	if input.Amount.Uint64() > balance {
-		log.Printf("insufficient amount, balance: %v, amount: %v\n", balance, input.Amount.String())
-		return nil, err
+	check.fee += budget.PriorityFee()
+	if err := a.checkBalances(ctx, client, check); err != nil {
+		return types.Transaction{}, err
 	}
 
+	// advance nonce must stay the first instruction of a durable transaction
+	instructions = append(append(nonceInstructions, budget.Instructions()...), instructions[len(nonceInstructions):]...)
+
 	// create a message
 	message := types.NewMessage(types.NewMessageParam{
-		FeePayer:        feePayer.PublicKey,
-		RecentBlockhash: res.Blockhash, // recent blockhash
-		Instructions: []types.Instruction{
-			system.Transfer(system.TransferParam{
-				From:   from.PublicKey,        // from
-				To:     toPublicKey,           // to
-				Amount: input.Amount.Uint64(), // 1 SOL
-			}),
-		},
+		FeePayer:        feePayer,
+		RecentBlockhash: blockhash, // recent blockhash or durable nonce
+		Instructions:    instructions,
 	})
 
-	tx, err := types.NewTransaction(types.NewTransactionParam{
+	return types.NewTransaction(types.NewTransactionParam{
 		Message: message,
-		Signers: []types.Account{feePayer, from},
+		Signers: signers,
 	})
+}
+
+// buildTransferInstructions returns the transfer instructions followed by a memo signed by the sender when input.Memo is set.
+func (a *SolanaApi) buildTransferInstructions(input *_types.TransferInput) ([]types.Instruction, error) {
+	from := common.PublicKeyFromString(input.FromAddress)
+
+	// reject memos the memo program would fail on before anything is queried
+	if input.Memo != "" {
+		if err := ValidateMemo(input.Memo); err != nil {
+			return nil, err
+		}
+	}
+
+	instructions, err := transferInstructions(
+		from,
+		common.PublicKeyFromString(feePayerAddress(input)),
+		input.ToAddress,
+		input.Amount,
+		input.ContractAddress,
+		input.TokenDecimals,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	txHash, err := client.SendTransaction(ctx, tx)
+	if input.Memo != "" {
+		instructions = append(instructions, memoInstruction(input.Memo, from))
+	}
+
+	return instructions, nil
+}
+
+// account returns the account signing for address through the signer provider.
+func (a *SolanaApi) account(ctx context.Context, appId, network, address string) (types.Account, error) {
+	signer, err := a.signerProvider.Provide(ctx, appId, network, address)
 	if err != nil {
-		return nil, err
+		return types.Account{}, err
 	}
 
-	log.Printf("tx sent: %s\n", txHash)
+	account, err := types.AccountFromSigner(ctx, signer)
+	if err != nil {
+		log.Printf("%s address err: %v", address, err)
+		return types.Account{}, err
+	}
 
-	return &_types.TransferMessage{
-		Hash: []byte(txHash),
-	}, nil
+	return account, nil
+}
+
+// sendInstructions signs the instructions with a recent blockhash and sends them,
+// the first signer pays the fee.
+func (a *SolanaApi) sendInstructions(ctx context.Context, c *client.Client, signers []types.Account, instructions []types.Instruction) (string, error) {
+	res, err := c.GetLatestBlockhash(ctx)
+	if err != nil {
+		log.Printf("failed to get latest blockhash, err: %v\n", err)
+		return "", err
+	}
+
+	tx, err := types.NewTransaction(types.NewTransactionParam{
+		Message: types.NewMessage(types.NewMessageParam{
+			FeePayer:        signers[0].PublicKey,
+			RecentBlockhash: res.Blockhash,
+			Instructions:    instructions,
+		}),
+		Signers: signers,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return c.SendTransaction(ctx, tx)
+}
+
+func isSignatureEmpty(signature types.Signature) bool {
+	for _, b := range signature {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func feePayerAddress(input *_types.TransferInput) string {
+	if len(input.FeePayer) != 0 {
+		return input.FeePayer
+	}
+
+	return input.FromAddress
 }
@@ -0,0 +1,343 @@
+package solana
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"log"
+
+	"github.com/mr-tron/base58"
+	"github.com/openweb3-io/blockchain/api"
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/associated_token_account"
+	"github.com/openweb3-io/solana-go-sdk/program/compute_budget"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/program/token"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+const (
+	// defaultComputeUnitLimit is the limit of an instruction without a compute budget instruction.
+	defaultComputeUnitLimit = 200_000
+
+	defaultMaxSponsoredFee = 10_000_000
+	// rent of a single token account
+	defaultMaxSponsoredRent = 2_039_280
+)
+
+type SponsorOption func(*Sponsor)
+
+// WithSponsoredPrograms replaces the programs sponsored transactions may invoke.
+func WithSponsoredPrograms(programs ...string) SponsorOption {
+	return func(s *Sponsor) {
+		s.programs = make(map[common.PublicKey]bool)
+		for _, program := range programs {
+			s.programs[common.PublicKeyFromString(program)] = true
+		}
+	}
+}
+
+// WithMaxSponsoredFee caps the lamports a single transaction may cost the fee payer, including
+// signature and priority fees and the rent of token accounts it funds.
+func WithMaxSponsoredFee(v uint64) SponsorOption {
+	return func(s *Sponsor) {
+		s.maxFee = v
+	}
+}
+
+// WithMaxSponsoredRent caps the lamports a single transaction may cost the fee payer in rent of the token
+// accounts it creates, one token account by default.
+func WithMaxSponsoredRent(v uint64) SponsorOption {
+	return func(s *Sponsor) {
+		s.maxRent = v
+	}
+}
+
+// Sponsor adds the fee payer signature to transactions prepared by SolanaApi.PrepareTransaction with
+// TransferInput.FeePayer set to the sponsor and TransferInput.SponsoredFee set. It only signs transactions
+// that invoke allowed programs, do not move anything out of the fee payer, only have it fund the token
+// accounts receiving their transfers and stay within the sponsored fee and rent.
+type Sponsor struct {
+	signerProvider *api.SignerProvider
+	endpoint       string
+	appId          string
+	network        string
+	feePayer       common.PublicKey
+	programs       map[common.PublicKey]bool
+	maxFee         uint64
+	maxRent        uint64
+}
+
+func NewSponsor(signerProvider *api.SignerProvider, endpoint, appId, network, feePayer string, o ...SponsorOption) *Sponsor {
+	s := &Sponsor{
+		signerProvider: signerProvider,
+		endpoint:       endpoint,
+		appId:          appId,
+		network:        network,
+		feePayer:       common.PublicKeyFromString(feePayer),
+		programs: map[common.PublicKey]bool{
+			common.SystemProgramID:                    true,
+			common.TokenProgramID:                     true,
+			common.SPLAssociatedTokenAccountProgramID: true,
+			common.ComputeBudgetProgramID:             true,
+			common.MemoProgramID:                      true,
+		},
+		maxFee:  defaultMaxSponsoredFee,
+		maxRent: defaultMaxSponsoredRent,
+	}
+
+	for _, opt := range o {
+		opt(s)
+	}
+
+	return s
+}
+
+// CoSign validates the partially signed transaction and adds the fee payer signature, the returned
+// message carries the transaction hash and can be passed to SolanaApi.BroadcastTransaction.
+func (s *Sponsor) CoSign(ctx context.Context, input *_types.TransferMessage) (*_types.TransferMessage, error) {
+	tx, err := types.TransactionDeserialize(input.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := tx.Message.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(ctx, tx, data); err != nil {
+		log.Printf("refused to sponsor transaction, err: %v\n", err)
+		return nil, err
+	}
+
+	signer, err := s.signerProvider.Provide(ctx, s.appId, s.network, s.feePayer.ToBase58())
+	if err != nil {
+		return nil, err
+	}
+
+	feePayer, err := types.AccountFromSigner(ctx, signer)
+	if err != nil {
+		log.Printf("fee payer address err: %v", err)
+		return nil, err
+	}
+
+	if feePayer.PublicKey != s.feePayer {
+		return nil, fmt.Errorf("signer of fee payer %s has public key %s", s.feePayer.ToBase58(), feePayer.PublicKey.ToBase58())
+	}
+
+	tx.Signatures[0] = feePayer.Sign(data)
+
+	payload, err := tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return &_types.TransferMessage{
+		Hash:    []byte(base58.Encode(tx.Signatures[0])),
+		Payload: payload,
+	}, nil
+}
+
+func (s *Sponsor) validate(ctx context.Context, tx types.Transaction, data []byte) error {
+	message := tx.Message
+
+	if len(message.Accounts) == 0 || message.Accounts[0] != s.feePayer {
+		return fmt.Errorf("fee payer of the transaction is not %s", s.feePayer.ToBase58())
+	}
+
+	if message.Header.NumRequireSignatures < 2 {
+		return fmt.Errorf("transaction is not signed by anyone but the fee payer")
+	}
+
+	// looked up accounts cannot be checked without resolving the tables
+	if len(message.AddressLookupTables) > 0 {
+		return fmt.Errorf("sponsored transactions cannot use address lookup tables")
+	}
+
+	// the sender signs first, an unsigned transaction could be altered by whoever relays it
+	for i := 1; i < int(message.Header.NumRequireSignatures); i++ {
+		if !ed25519.Verify(message.Accounts[i].Bytes(), data, tx.Signatures[i]) {
+			return fmt.Errorf("signature of %s is missing or invalid", message.Accounts[i].ToBase58())
+		}
+	}
+
+	// the fee payer only funds the token accounts receiving the transfers of the message
+	recipients := transferRecipients(message)
+	created := make(map[common.PublicKey]bool)
+
+	var units, unitPrice, instructions uint64
+	for i, instruction := range message.Instructions {
+		if !validIndexes(message, instruction) {
+			return fmt.Errorf("instruction %d references accounts outside of the message", i)
+		}
+
+		program := message.Accounts[instruction.ProgramIDIndex]
+		if !s.programs[program] {
+			return fmt.Errorf("instruction %d invokes program %s which is not sponsored", i, program.ToBase58())
+		}
+
+		if program == common.ComputeBudgetProgramID {
+			if len(instruction.Data) == 0 {
+				return fmt.Errorf("instruction %d is not a valid compute budget instruction", i)
+			}
+
+			switch compute_budget.Instruction(instruction.Data[0]) {
+			case compute_budget.InstructionSetComputeUnitLimit:
+				if len(instruction.Data) < 5 {
+					return fmt.Errorf("instruction %d is not a valid compute unit limit", i)
+				}
+				units = uint64(binary.LittleEndian.Uint32(instruction.Data[1:5]))
+			case compute_budget.InstructionSetComputeUnitPrice:
+				if len(instruction.Data) < 9 {
+					return fmt.Errorf("instruction %d is not a valid compute unit price", i)
+				}
+				unitPrice = binary.LittleEndian.Uint64(instruction.Data[1:9])
+			}
+			continue
+		}
+		instructions++
+
+		account, err := s.validateFeePayerUse(message, instruction, recipients)
+		if err != nil {
+			return fmt.Errorf("instruction %d: %w", i, err)
+		}
+
+		if account != nil {
+			created[*account] = true
+		}
+	}
+
+	// closing a funded token account in the same transaction would hand its rent to the closing authority
+	for i, instruction := range message.Instructions {
+		if message.Accounts[instruction.ProgramIDIndex] == common.TokenProgramID && len(instruction.Data) > 0 &&
+			token.Instruction(instruction.Data[0]) == token.InstructionCloseAccount &&
+			len(instruction.Accounts) > 0 && created[message.Accounts[instruction.Accounts[0]]] {
+			return fmt.Errorf("instruction %d closes a token account funded by the fee payer", i)
+		}
+	}
+
+	if units == 0 {
+		units = instructions * defaultComputeUnitLimit
+	}
+	units = min(units, uint64(MaxComputeUnitLimit))
+
+	c := client.NewClient(s.endpoint)
+
+	baseFee, err := messageFee(ctx, c, message)
+	if err != nil {
+		return err
+	}
+
+	fee := baseFee + priorityFee(uint32(units), unitPrice)
+	if len(created) > 0 {
+		rent, err := c.GetMinimumBalanceForRentExemption(ctx, token.TokenAccountSize)
+		if err != nil {
+			log.Printf("failed to get rent exemption, err: %v\n", err)
+			return err
+		}
+
+		rent *= uint64(len(created))
+		if s.maxRent > 0 && rent > s.maxRent {
+			return fmt.Errorf("transaction costs the fee payer %d lamports of rent, at most %d lamports are sponsored", rent, s.maxRent)
+		}
+		fee += rent
+	}
+
+	if s.maxFee > 0 && fee > s.maxFee {
+		return fmt.Errorf("transaction costs the fee payer %d lamports, at most %d lamports are sponsored", fee, s.maxFee)
+	}
+
+	return nil
+}
+
+// validateFeePayerUse makes sure the instruction cannot move lamports or tokens out of the fee payer. The fee
+// payer may only fund the associated token account receiving a transfer and advance a durable nonce it is the
+// authority of, the token account it funds is returned.
+func (s *Sponsor) validateFeePayerUse(message types.Message, instruction types.CompiledInstruction, recipients map[common.PublicKey]common.PublicKey) (*common.PublicKey, error) {
+	program := message.Accounts[instruction.ProgramIDIndex]
+	accounts := instruction.Accounts
+
+	var funded *common.PublicKey
+	switch program {
+	case common.SPLAssociatedTokenAccountProgramID:
+		// an empty instruction is the legacy create
+		op := associated_token_account.InstructionCreate
+		if len(instruction.Data) > 0 {
+			op = associated_token_account.Instruction(instruction.Data[0])
+		}
+
+		if op == associated_token_account.InstructionRecoverNested {
+			return nil, fmt.Errorf("recovering nested token accounts is not sponsored")
+		}
+
+		// accounts: [funder, associated token account, owner, mint, system program, token program]
+		if len(accounts) > 0 && message.Accounts[accounts[0]] == s.feePayer {
+			if len(accounts) < 4 {
+				return nil, fmt.Errorf("token account creation is missing accounts")
+			}
+
+			account, mint := message.Accounts[accounts[1]], message.Accounts[accounts[3]]
+			if recipient, ok := recipients[account]; !ok || recipient != mint {
+				return nil, fmt.Errorf("token account %s does not receive a transfer of the transaction", account.ToBase58())
+			}
+			funded = &account
+		}
+
+		if len(accounts) > 0 {
+			accounts = accounts[1:]
+		}
+	case common.SystemProgramID:
+		// accounts: [nonce account, recent blockhashes sysvar, authority]
+		if len(instruction.Data) >= 4 && len(accounts) >= 3 &&
+			system.Instruction(binary.LittleEndian.Uint32(instruction.Data)) == system.InstructionAdvanceNonceAccount {
+			accounts = accounts[:2]
+		}
+	}
+
+	for _, account := range accounts {
+		if message.Accounts[account] == s.feePayer {
+			return nil, fmt.Errorf("fee payer is passed to program %s", program.ToBase58())
+		}
+	}
+
+	return funded, nil
+}
+
+// transferRecipients returns the mint of the destination token account of every TransferChecked instruction.
+func transferRecipients(message types.Message) map[common.PublicKey]common.PublicKey {
+	recipients := make(map[common.PublicKey]common.PublicKey)
+
+	for _, instruction := range message.Instructions {
+		if !validIndexes(message, instruction) || message.Accounts[instruction.ProgramIDIndex] != common.TokenProgramID {
+			continue
+		}
+
+		// accounts: [source, mint, destination, authority]
+		if len(instruction.Data) == 0 || token.Instruction(instruction.Data[0]) != token.InstructionTransferChecked ||
+			len(instruction.Accounts) < 3 {
+			continue
+		}
+
+		recipients[message.Accounts[instruction.Accounts[2]]] = message.Accounts[instruction.Accounts[1]]
+	}
+
+	return recipients
+}
+
+func validIndexes(message types.Message, instruction types.CompiledInstruction) bool {
+	if instruction.ProgramIDIndex < 0 || instruction.ProgramIDIndex >= len(message.Accounts) {
+		return false
+	}
+
+	for _, account := range instruction.Accounts {
+		if account < 0 || account >= len(message.Accounts) {
+			return false
+		}
+	}
+
+	return true
+}
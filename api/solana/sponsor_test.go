@@ -0,0 +1,109 @@
+package solana_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/solana"
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/associated_token_account"
+	"github.com/openweb3-io/solana-go-sdk/program/memo"
+	"github.com/openweb3-io/solana-go-sdk/program/token"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+func TestSponsorCoSignRejects(t *testing.T) {
+	feePayer := types.NewAccount().PublicKey
+	user := types.NewAccount()
+	recipient := types.NewAccount().PublicKey
+	mint := types.NewAccount().PublicKey
+
+	source, _, _ := common.FindAssociatedTokenAddress(user.PublicKey, mint)
+	destination, _, _ := common.FindAssociatedTokenAddress(recipient, mint)
+
+	create := func(owner, account common.PublicKey) types.Instruction {
+		return associated_token_account.CreateIdempotent(associated_token_account.CreateIdempotentParam{
+			Funder:                 feePayer,
+			Owner:                  owner,
+			Mint:                   mint,
+			AssociatedTokenAccount: account,
+		})
+	}
+	transfer := token.TransferChecked(token.TransferCheckedParam{
+		From:     source,
+		To:       destination,
+		Mint:     mint,
+		Auth:     user.PublicKey,
+		Signers:  []common.PublicKey{},
+		Amount:   1,
+		Decimals: 6,
+	})
+
+	tests := []struct {
+		name         string
+		instructions []types.Instruction
+		want         string
+	}{
+		{
+			name: "token account without a transfer",
+			instructions: []types.Instruction{
+				create(user.PublicKey, source),
+				memo.BuildMemo(memo.BuildMemoParam{SignerPubkeys: []common.PublicKey{user.PublicKey}, Memo: []byte("rent")}),
+			},
+			want: "does not receive a transfer",
+		},
+		{
+			name: "token account of another owner than the recipient",
+			instructions: []types.Instruction{
+				create(user.PublicKey, source),
+				transfer,
+			},
+			want: "does not receive a transfer",
+		},
+		{
+			name: "funded token account closed",
+			instructions: []types.Instruction{
+				create(recipient, destination),
+				transfer,
+				token.CloseAccount(token.CloseAccountParam{
+					Account: destination,
+					Auth:    user.PublicKey,
+					Signers: []common.PublicKey{},
+					To:      user.PublicKey,
+				}),
+			},
+			want: "closes a token account funded by the fee payer",
+		},
+	}
+
+	sponsor := solana.NewSponsor(api.NewSignerProvider(), "http://127.0.0.1:0", "app", "solana", feePayer.ToBase58())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := types.NewTransaction(types.NewTransactionParam{
+				Message: types.NewMessage(types.NewMessageParam{
+					FeePayer:        feePayer,
+					RecentBlockhash: types.NewAccount().PublicKey.ToBase58(),
+					Instructions:    tt.instructions,
+				}),
+				Signers: []types.Account{user},
+			})
+			if err != nil {
+				t.Fatalf("NewTransaction() error = %v", err)
+			}
+
+			payload, err := tx.Serialize()
+			if err != nil {
+				t.Fatalf("Serialize() error = %v", err)
+			}
+
+			_, err = sponsor.CoSign(context.Background(), &_types.TransferMessage{Payload: payload})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CoSign() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package solana
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+
+	_types "github.com/openweb3-io/blockchain/api/types"
+	"github.com/openweb3-io/solana-go-sdk/client"
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/stake"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/rpc"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// offsets in the stake account data, see the StakeStateV2 layout of the stake program
+const (
+	stakeStateOffset             = 0
+	stakeRentReserveOffset       = 4
+	stakeStakerOffset            = 12
+	stakeWithdrawerOffset        = 44
+	stakeVoterOffset             = 124
+	stakeDelegatedOffset         = 156
+	stakeActivationEpochOffset   = 164
+	stakeDeactivationEpochOffset = 172
+
+	stakeStateInitialized = 1
+	stakeStateDelegated   = 2
+)
+
+type StakeActivation string
+
+const (
+	StakeActivationInactive     StakeActivation = "inactive"
+	StakeActivationActivating   StakeActivation = "activating"
+	StakeActivationActive       StakeActivation = "active"
+	StakeActivationDeactivating StakeActivation = "deactivating"
+)
+
+type StakeInput struct {
+	AppId   string
+	Network string
+	// Authority is the staker and withdrawer of the stake accounts and pays the fees
+	Authority string
+}
+
+type CreateStakeAccountInput struct {
+	AppId     string
+	Network   string
+	Authority string
+	// Seed the stake account address is derived with, see StakeAccountAddress
+	Seed string
+	// lamports moved into the stake account, they must cover the rent-exempt reserve and the minimum delegation
+	Amount *big.Int
+	// vote account the stake is delegated to right away, empty to only create the account
+	VoteAccount string
+}
+
+type StakeAccount struct {
+	Address     string
+	Lamports    uint64
+	Staker      string
+	Withdrawer  string
+	VoteAccount string
+	// delegated lamports, rewards are compounded into it
+	Stake       uint64
+	RentReserve uint64
+	Activation  StakeActivation
+	// reward paid in the last completed epoch, nil when it is not known yet
+	LastReward      *uint64
+	LastRewardEpoch uint64
+}
+
+// StakeAccountAddress returns the address of the stake account derived from the authority and seed.
+func StakeAccountAddress(authority, seed string) common.PublicKey {
+	return common.CreateWithSeed(common.PublicKeyFromString(authority), seed, common.StakeProgramID)
+}
+
+// CreateStakeAccount creates and initializes a stake account with the authority as staker and
+// withdrawer, and delegates it when input.VoteAccount is set.
+func (a *SolanaApi) CreateStakeAccount(ctx context.Context, input *CreateStakeAccountInput) (string, *_types.TransferMessage, error) {
+	c := client.NewClient(a.endpoint)
+
+	if input.Amount == nil || input.Amount.Sign() <= 0 || !input.Amount.IsUint64() {
+		return "", nil, fmt.Errorf("invalid amount %v", input.Amount)
+	}
+
+	authority, err := a.account(ctx, input.AppId, input.Network, input.Authority)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rent, err := c.GetMinimumBalanceForRentExemption(ctx, stake.AccountSize)
+	if err != nil {
+		log.Printf("failed to get rent exemption, err: %v\n", err)
+		return "", nil, err
+	}
+
+	if input.Amount.Uint64() <= rent {
+		return "", nil, _types.WrapErr(_types.ErrBelowRentExemption,
+			fmt.Errorf("stake of %d lamports does not exceed the rent-exempt reserve of %d lamports", input.Amount.Uint64(), rent))
+	}
+
+	stakeAccount := StakeAccountAddress(input.Authority, input.Seed)
+
+	instructions := []types.Instruction{
+		system.CreateAccountWithSeed(system.CreateAccountWithSeedParam{
+			From:     authority.PublicKey,
+			New:      stakeAccount,
+			Base:     authority.PublicKey,
+			Owner:    common.StakeProgramID,
+			Seed:     input.Seed,
+			Lamports: input.Amount.Uint64(),
+			Space:    stake.AccountSize,
+		}),
+		stake.Initialize(stake.InitializeParam{
+			Stake: stakeAccount,
+			Auth: stake.Authorized{
+				Staker:     authority.PublicKey,
+				Withdrawer: authority.PublicKey,
+			},
+		}),
+	}
+
+	if input.VoteAccount != "" {
+		instructions = append(instructions, stake.DelegateStake(stake.DelegateStakeParam{
+			Stake: stakeAccount,
+			Auth:  authority.PublicKey,
+			Vote:  common.PublicKeyFromString(input.VoteAccount),
+		}))
+	}
+
+	txHash, err := a.sendInstructions(ctx, c, []types.Account{authority}, instructions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	log.Printf("stake account %s created, tx: %s\n", stakeAccount.ToBase58(), txHash)
+
+	return stakeAccount.ToBase58(), &_types.TransferMessage{
+		Hash: []byte(txHash),
+	}, nil
+}
+
+// DelegateStake delegates the stake account to the vote account, a deactivated account can be delegated again.
+func (a *SolanaApi) DelegateStake(ctx context.Context, input *StakeInput, stakeAccount, voteAccount string) (*_types.TransferMessage, error) {
+	return a.sendStakeInstructions(ctx, input, func(authority common.PublicKey) ([]types.Instruction, error) {
+		return []types.Instruction{
+			stake.DelegateStake(stake.DelegateStakeParam{
+				Stake: common.PublicKeyFromString(stakeAccount),
+				Auth:  authority,
+				Vote:  common.PublicKeyFromString(voteAccount),
+			}),
+		}, nil
+	})
+}
+
+// DeactivateStake starts the cooldown of the stake account, the lamports can be withdrawn once it is inactive.
+func (a *SolanaApi) DeactivateStake(ctx context.Context, input *StakeInput, stakeAccount string) (*_types.TransferMessage, error) {
+	return a.sendStakeInstructions(ctx, input, func(authority common.PublicKey) ([]types.Instruction, error) {
+		return []types.Instruction{
+			stake.Deactivate(stake.DeactivateParam{
+				Stake: common.PublicKeyFromString(stakeAccount),
+				Auth:  authority,
+			}),
+		}, nil
+	})
+}
+
+// WithdrawStake moves lamports of an inactive stake account, or its undelegated excess, to the recipient.
+// Withdrawing the whole balance closes the stake account.
+func (a *SolanaApi) WithdrawStake(ctx context.Context, input *StakeInput, stakeAccount, to string, amount *big.Int) (*_types.TransferMessage, error) {
+	return a.sendStakeInstructions(ctx, input, func(authority common.PublicKey) ([]types.Instruction, error) {
+		if amount == nil || amount.Sign() <= 0 || !amount.IsUint64() {
+			return nil, fmt.Errorf("invalid amount %v", amount)
+		}
+
+		return []types.Instruction{
+			stake.Withdraw(stake.WithdrawParam{
+				Stake:    common.PublicKeyFromString(stakeAccount),
+				Auth:     authority,
+				To:       common.PublicKeyFromString(to),
+				Lamports: amount.Uint64(),
+			}),
+		}, nil
+	})
+}
+
+// SplitStake moves amount lamports of the stake account into a new stake account derived from the
+// authority and seed, which keeps the delegation state. The authority prefunds the rent-exempt
+// reserve of the new account.
+func (a *SolanaApi) SplitStake(ctx context.Context, input *StakeInput, stakeAccount, seed string, amount *big.Int) (string, *_types.TransferMessage, error) {
+	splitAccount := StakeAccountAddress(input.Authority, seed)
+
+	message, err := a.sendStakeInstructions(ctx, input, func(authority common.PublicKey) ([]types.Instruction, error) {
+		if amount == nil || amount.Sign() <= 0 || !amount.IsUint64() {
+			return nil, fmt.Errorf("invalid amount %v", amount)
+		}
+
+		rent, err := client.NewClient(a.endpoint).GetMinimumBalanceForRentExemption(ctx, stake.AccountSize)
+		if err != nil {
+			log.Printf("failed to get rent exemption, err: %v\n", err)
+			return nil, err
+		}
+
+		return []types.Instruction{
+			system.AllocateWithSeed(system.AllocateWithSeedParam{
+				Account: splitAccount,
+				Base:    authority,
+				Owner:   common.StakeProgramID,
+				Seed:    seed,
+				Space:   stake.AccountSize,
+			}),
+			system.Transfer(system.TransferParam{
+				From:   authority,
+				To:     splitAccount,
+				Amount: rent,
+			}),
+			stake.Split(stake.SplitParam{
+				Stake:      common.PublicKeyFromString(stakeAccount),
+				Auth:       authority,
+				SplitStake: splitAccount,
+				Lamports:   amount.Uint64(),
+			}),
+		}, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return splitAccount.ToBase58(), message, nil
+}
+
+// MergeStake merges the source stake account into the destination, both need the same authorities,
+// lockup and activation state. The source account is closed.
+func (a *SolanaApi) MergeStake(ctx context.Context, input *StakeInput, destination, source string) (*_types.TransferMessage, error) {
+	return a.sendStakeInstructions(ctx, input, func(authority common.PublicKey) ([]types.Instruction, error) {
+		return []types.Instruction{
+			stake.Merge(stake.MergeParam{
+				From: common.PublicKeyFromString(source),
+				Auth: authority,
+				To:   common.PublicKeyFromString(destination),
+			}),
+		}, nil
+	})
+}
+
+func (a *SolanaApi) sendStakeInstructions(ctx context.Context, input *StakeInput, build func(authority common.PublicKey) ([]types.Instruction, error)) (*_types.TransferMessage, error) {
+	c := client.NewClient(a.endpoint)
+
+	authority, err := a.account(ctx, input.AppId, input.Network, input.Authority)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := build(authority.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash, err := a.sendInstructions(ctx, c, []types.Account{authority}, instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("stake tx sent: %s\n", txHash)
+
+	return &_types.TransferMessage{
+		Hash: []byte(txHash),
+	}, nil
+}
+
+// GetStakeAccounts lists the stake accounts whose withdrawer is the authority together with their
+// activation state in the current epoch and the reward paid in the last epoch. The activation state
+// ignores the cluster wide warmup and cooldown limits, which only delay large changes of stake.
+func (a *SolanaApi) GetStakeAccounts(ctx context.Context, authority string) ([]*StakeAccount, error) {
+	c := client.NewClient(a.endpoint)
+
+	res, err := c.RpcClient.GetProgramAccountsWithConfig(ctx, common.StakeProgramID.ToBase58(), rpc.GetProgramAccountsConfig{
+		Encoding: rpc.AccountEncodingBase64,
+		Filters: []rpc.GetProgramAccountsConfigFilter{
+			{DataSize: stake.AccountSize},
+			{MemCmp: &rpc.GetProgramAccountsConfigFilterMemCmp{Offset: stakeWithdrawerOffset, Bytes: authority}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stake accounts: %w", err)
+	}
+	if err := res.GetError(); err != nil {
+		return nil, fmt.Errorf("failed to get stake accounts: %w", err)
+	}
+
+	epoch, err := c.GetEpochInfo(ctx)
+	if err != nil {
+		log.Printf("failed to get epoch info, err: %v\n", err)
+		return nil, err
+	}
+
+	accounts := make([]*StakeAccount, 0, len(res.Result))
+	addresses := make([]string, 0, len(res.Result))
+	for _, account := range res.Result {
+		data, err := accountData(account.Account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode stake account %s: %w", account.Pubkey, err)
+		}
+
+		stakeAccount, err := parseStakeAccount(data, epoch.Epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stake account %s: %w", account.Pubkey, err)
+		}
+		stakeAccount.Address = account.Pubkey
+		stakeAccount.Lamports = account.Account.Lamports
+
+		accounts = append(accounts, stakeAccount)
+		addresses = append(addresses, account.Pubkey)
+	}
+
+	if len(addresses) == 0 || epoch.Epoch == 0 {
+		return accounts, nil
+	}
+
+	rewards, err := c.RpcClient.GetInflationRewardWithConfig(ctx, addresses, rpc.GetInflationRewardConfig{
+		Epoch: epoch.Epoch - 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inflation rewards: %w", err)
+	}
+	if err := rewards.GetError(); err != nil {
+		return nil, fmt.Errorf("failed to get inflation rewards: %w", err)
+	}
+
+	for i, reward := range rewards.Result {
+		if reward == nil || i >= len(accounts) {
+			continue
+		}
+
+		amount := reward.Amount
+		accounts[i].LastReward = &amount
+		accounts[i].LastRewardEpoch = reward.Epoch
+	}
+
+	return accounts, nil
+}
+
+func accountData(account rpc.AccountInfo) ([]byte, error) {
+	data, ok := account.Data.([]any)
+	if !ok || len(data) != 2 || data[1] != string(rpc.AccountEncodingBase64) {
+		return nil, fmt.Errorf("account data is not base64 encoded")
+	}
+
+	encoded, ok := data[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("account data is not base64 encoded")
+	}
+
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func parseStakeAccount(data []byte, currentEpoch uint64) (*StakeAccount, error) {
+	if len(data) < int(stake.AccountSize) {
+		return nil, fmt.Errorf("stake account data is %d bytes, expected %d", len(data), stake.AccountSize)
+	}
+
+	account := &StakeAccount{
+		Activation: StakeActivationInactive,
+	}
+
+	state := binary.LittleEndian.Uint32(data[stakeStateOffset:])
+	if state != stakeStateInitialized && state != stakeStateDelegated {
+		return account, nil
+	}
+
+	account.RentReserve = binary.LittleEndian.Uint64(data[stakeRentReserveOffset:])
+	account.Staker = common.PublicKeyFromBytes(data[stakeStakerOffset : stakeStakerOffset+32]).ToBase58()
+	account.Withdrawer = common.PublicKeyFromBytes(data[stakeWithdrawerOffset : stakeWithdrawerOffset+32]).ToBase58()
+
+	if state != stakeStateDelegated {
+		return account, nil
+	}
+
+	account.VoteAccount = common.PublicKeyFromBytes(data[stakeVoterOffset : stakeVoterOffset+32]).ToBase58()
+	account.Stake = binary.LittleEndian.Uint64(data[stakeDelegatedOffset:])
+
+	activationEpoch := binary.LittleEndian.Uint64(data[stakeActivationEpochOffset:])
+	deactivationEpoch := binary.LittleEndian.Uint64(data[stakeDeactivationEpochOffset:])
+
+	switch {
+	case deactivationEpoch == math.MaxUint64 && activationEpoch >= currentEpoch:
+		account.Activation = StakeActivationActivating
+	case deactivationEpoch == math.MaxUint64:
+		account.Activation = StakeActivationActive
+	case deactivationEpoch >= currentEpoch && activationEpoch != deactivationEpoch:
+		account.Activation = StakeActivationDeactivating
+	}
+
+	return account, nil
+}
@@ -0,0 +1,124 @@
+package solana_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/solana"
+)
+
+// stake accounts in the StakeStateV2 layout as getProgramAccounts returns them, staked with 9WzDX…AWWM
+// as staker and withdrawer and delegated to the CertusDeB…hvLu vote account
+const (
+	// delegated 1000 SOL at epoch 500
+	activeStakeAccount = "AgAAAIDVIgAAAAAAfowIh2C/3h3dzzLBfyCbgkLuUqrxMfrNiNDqLG0LBvJ+jAiHYL/eHd3PMsF/IJuCQu5SqvEx+s2I0OosbQsG8gAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAK0jdm2qTzCVek6Qzfgmj2H4Hw8A6DnJrW76AJ9E7J+mABCl1OgAAAD0AQAAAAAAAP//////////AAAAAAAA0D9A4gEAAAAAAAAAAAA="
+	// delegated at epoch 500, deactivated at epoch 601
+	deactivatingStakeAccount = "AgAAAIDVIgAAAAAAfowIh2C/3h3dzzLBfyCbgkLuUqrxMfrNiNDqLG0LBvJ+jAiHYL/eHd3PMsF/IJuCQu5SqvEx+s2I0OosbQsG8gAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAK0jdm2qTzCVek6Qzfgmj2H4Hw8A6DnJrW76AJ9E7J+mABCl1OgAAAD0AQAAAAAAAFkCAAAAAAAAAAAAAAAA0D9A4gEAAAAAAAAAAAA="
+	// initialized, never delegated
+	initializedStakeAccount = "AQAAAIDVIgAAAAAAfowIh2C/3h3dzzLBfyCbgkLuUqrxMfrNiNDqLG0LBvJ+jAiHYL/eHd3PMsF/IJuCQu5SqvEx+s2I0OosbQsG8gAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	// delegated at epoch 600
+	activatingStakeAccount = "AgAAAIDVIgAAAAAAfowIh2C/3h3dzzLBfyCbgkLuUqrxMfrNiNDqLG0LBvJ+jAiHYL/eHd3PMsF/IJuCQu5SqvEx+s2I0OosbQsG8gAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAK0jdm2qTzCVek6Qzfgmj2H4Hw8A6DnJrW76AJ9E7J+mABCl1OgAAABYAgAAAAAAAP//////////AAAAAAAA0D9A4gEAAAAAAAAAAAA="
+
+	stakeAuthority = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+	voteAccount    = "CertusDeBmqN8ZawdkxK5kFGMwBXdudvWHYwtNgNhvLu"
+)
+
+// newRPCServer answers every call of a method with the same result.
+func newRPCServer(t *testing.T, results map[string]any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, ok := results[req.Method]
+		if !ok {
+			t.Errorf("unexpected call of %s", req.Method)
+			http.Error(w, "unexpected method", http.StatusBadRequest)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+}
+
+func TestGetStakeAccounts(t *testing.T) {
+	accounts := []struct {
+		address string
+		data    string
+	}{
+		{"StakeActive11111111111111111111111111111111", activeStakeAccount},
+		{"StakeDeactivating111111111111111111111111111", deactivatingStakeAccount},
+		{"StakeCreated11111111111111111111111111111111", initializedStakeAccount},
+		{"StakeActivating11111111111111111111111111111", activatingStakeAccount},
+	}
+
+	programAccounts := make([]map[string]any, 0, len(accounts))
+	for _, account := range accounts {
+		programAccounts = append(programAccounts, map[string]any{
+			"pubkey": account.address,
+			"account": map[string]any{
+				"lamports":   1_000_002_282_880,
+				"owner":      "Stake11111111111111111111111111111111111111",
+				"data":       []string{account.data, "base64"},
+				"executable": false,
+				"rentEpoch":  0,
+			},
+		})
+	}
+
+	server := newRPCServer(t, map[string]any{
+		"getProgramAccounts": programAccounts,
+		"getEpochInfo":       map[string]any{"absoluteSlot": 259_200_000, "blockHeight": 237_000_000, "epoch": 600, "slotIndex": 0, "slotsInEpoch": 432_000},
+		"getInflationReward": []any{
+			map[string]any{"epoch": 599, "effectiveSlot": 258_768_000, "amount": 180_000_000, "postBalance": 1_000_182_282_880},
+			map[string]any{"epoch": 599, "effectiveSlot": 258_768_000, "amount": 180_000_000, "postBalance": 1_000_182_282_880},
+			nil,
+			nil,
+		},
+	})
+	defer server.Close()
+
+	got, err := solana.NewSolanaApi(api.NewSignerProvider(), server.URL, nil).GetStakeAccounts(context.Background(), stakeAuthority)
+	if err != nil {
+		t.Fatalf("GetStakeAccounts() error = %v", err)
+	}
+
+	if len(got) != len(accounts) {
+		t.Fatalf("GetStakeAccounts() returned %d accounts, want %d", len(got), len(accounts))
+	}
+
+	tests := []struct {
+		activation  solana.StakeActivation
+		voteAccount string
+		stake       uint64
+		reward      bool
+	}{
+		{solana.StakeActivationActive, voteAccount, 1_000_000_000_000, true},
+		{solana.StakeActivationDeactivating, voteAccount, 1_000_000_000_000, true},
+		{solana.StakeActivationInactive, "", 0, false},
+		{solana.StakeActivationActivating, voteAccount, 1_000_000_000_000, false},
+	}
+
+	for i, tt := range tests {
+		account := got[i]
+		if account.Address != accounts[i].address || account.Staker != stakeAuthority || account.Withdrawer != stakeAuthority ||
+			account.RentReserve != 2_282_880 {
+			t.Errorf("account %d = %+v, want %s with authority %s and rent reserve 2282880", i, account, accounts[i].address, stakeAuthority)
+		}
+		if account.Activation != tt.activation || account.VoteAccount != tt.voteAccount || account.Stake != tt.stake {
+			t.Errorf("account %d = %+v, want %s stake of %d delegated to %q", i, account, tt.activation, tt.stake, tt.voteAccount)
+		}
+		if (account.LastReward != nil) != tt.reward {
+			t.Errorf("account %d last reward = %v, want reward %v", i, account.LastReward, tt.reward)
+		}
+	}
+}
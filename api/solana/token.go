@@ -0,0 +1,76 @@
+package solana
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/openweb3-io/solana-go-sdk/common"
+	"github.com/openweb3-io/solana-go-sdk/program/associated_token_account"
+	"github.com/openweb3-io/solana-go-sdk/program/system"
+	"github.com/openweb3-io/solana-go-sdk/program/token"
+	"github.com/openweb3-io/solana-go-sdk/types"
+)
+
+// transferInstructions returns the instructions moving amount from the sender to the recipient.
+// Without a mint lamports are transferred, otherwise tokens are moved between the associated token
+// accounts of both wallets and the recipient token account is created by the fee payer when missing.
+func transferInstructions(from, feePayer common.PublicKey, to string, amount *big.Int, mint string, decimals int32) ([]types.Instruction, error) {
+	if amount == nil || amount.Sign() < 0 || !amount.IsUint64() {
+		return nil, fmt.Errorf("invalid amount %v", amount)
+	}
+
+	recipient := common.PublicKeyFromString(to)
+
+	if mint == "" {
+		return []types.Instruction{
+			system.Transfer(system.TransferParam{
+				From:   from,
+				To:     recipient,
+				Amount: amount.Uint64(),
+			}),
+		}, nil
+	}
+
+	if decimals < 0 || decimals > 255 {
+		return nil, fmt.Errorf("invalid token decimals %d", decimals)
+	}
+
+	mintAddress := common.PublicKeyFromString(mint)
+
+	source, err := associatedTokenAddress(from, mintAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := associatedTokenAddress(recipient, mintAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.Instruction{
+		associated_token_account.CreateIdempotent(associated_token_account.CreateIdempotentParam{
+			Funder:                 feePayer,
+			Owner:                  recipient,
+			Mint:                   mintAddress,
+			AssociatedTokenAccount: destination,
+		}),
+		token.TransferChecked(token.TransferCheckedParam{
+			From:     source,
+			To:       destination,
+			Mint:     mintAddress,
+			Auth:     from,
+			Signers:  []common.PublicKey{},
+			Amount:   amount.Uint64(),
+			Decimals: uint8(decimals),
+		}),
+	}, nil
+}
+
+func associatedTokenAddress(owner, mint common.PublicKey) (common.PublicKey, error) {
+	address, _, err := common.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return common.PublicKey{}, fmt.Errorf("failed to derive token account of %s for mint %s: %w", owner.ToBase58(), mint.ToBase58(), err)
+	}
+
+	return address, nil
+}
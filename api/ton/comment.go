@@ -0,0 +1,29 @@
+package ton
+
+import (
+	"context"
+
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"go.uber.org/zap"
+)
+
+// DecryptComment returns the encrypted comment of a TON transfer to one of our wallets in plain text, it is
+// decrypted with the signer of the wallet. It returns an empty string when the transfer has no encrypted comment.
+func (a *TonApiV2) DecryptComment(ctx context.Context, appId, network string, tx *wrap.TransactionWrapper) (string, error) {
+	if !tx.InMsg.Set || tx.InMsg.Value.OpCode.Value != wrap.OpCodeEncryptedComment {
+		return "", nil
+	}
+
+	accountAddress, err := tx.GetAccountAddress()
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := a.signerProvider.Provide(ctx, appId, network, accountAddress)
+	if err != nil {
+		a.logger.Error("get signer failed", zap.Error(err))
+		return "", err
+	}
+
+	return tx.GetEncryptedComment(ctx, a.lclient, signer)
+}
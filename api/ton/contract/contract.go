@@ -1,24 +1,46 @@
 package contract
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/openweb3-io/blockchain/api/ton/contract/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"go.uber.org/zap"
 )
 
+// time jettons whose metadata cannot be loaded stay unknown before loading it again
+const unknownJettonTTL = 10 * time.Minute
+
 var (
+	mu             sync.RWMutex
 	knownContracts = make(map[string]types.IContract)
+	unknownJettons = make(map[string]unknownJetton)
 )
 
+type unknownJetton struct {
+	jetton  *Jetton
+	expires time.Time
+}
+
 func init() {
 	Register(&TONUSDT{})
 }
 
 func Register(contract types.IContract) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	knownContracts[contract.GetContractAddress()] = contract
 }
 
 func GetByAddress(address string) (types.IContract, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
 	contract, ok := knownContracts[address]
 	if !ok {
 		return nil, fmt.Errorf("unknown contract address: %s", address)
@@ -26,6 +48,69 @@ func GetByAddress(address string) (types.IContract, error) {
 	return contract, nil
 }
 
-func FindAndRegisterByAddress(address string) {
-	// TODO: query contract information from chain and register to Contracts
+// FindAndRegisterByAddress returns the known contract of the jetton master, or discovers it by calling
+// get_jetton_data and reading its TEP-64 metadata. Jettons whose metadata cannot be loaded are returned
+// as unknown without being registered, loading is retried once unknownJettonTTL passed.
+func FindAndRegisterByAddress(ctx context.Context, api jetton.TonApi, masterAddress string) (types.IContract, error) {
+	addr, err := address.ParseAddr(masterAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contract address %s: %w", masterAddress, err)
+	}
+
+	// contracts are registered by their bounceable mainnet address
+	key := addr.Bounce(true).Testnet(false).String()
+	if contract, err := GetByAddress(key); err == nil {
+		return contract, nil
+	}
+
+	if j := getUnknownJetton(key); j != nil {
+		return j, nil
+	}
+
+	data, err := jetton.NewJettonMasterClient(api, addr).GetJettonData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a jetton master: %w", masterAddress, err)
+	}
+
+	j := &Jetton{
+		Address:  key,
+		Decimals: defaultJettonDecimals,
+	}
+
+	if err := j.loadMetadata(ctx, data.Content); err != nil {
+		zap.S().Warn("load jetton metadata failed", zap.String("address", key), zap.Error(err))
+		j.Unknown = true
+		setUnknownJetton(j)
+		return j, nil
+	}
+
+	Register(j)
+
+	return j, nil
+}
+
+func getUnknownJetton(address string) *Jetton {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	unknown, ok := unknownJettons[address]
+	if !ok || time.Now().After(unknown.expires) {
+		return nil
+	}
+
+	return unknown.jetton
+}
+
+func setUnknownJetton(j *Jetton) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	for address, unknown := range unknownJettons {
+		if now.After(unknown.expires) {
+			delete(unknownJettons, address)
+		}
+	}
+
+	unknownJettons[j.Address] = unknownJetton{jetton: j, expires: now.Add(unknownJettonTTL)}
 }
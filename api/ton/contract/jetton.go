@@ -0,0 +1,248 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"go.uber.org/zap"
+)
+
+const (
+	// decimals of jettons whose metadata does not set them, as defined by TEP-64
+	defaultJettonDecimals = 9
+	maxMetadataSize       = 1 << 20
+	ipfsGateway           = "https://ipfs.io/ipfs/"
+)
+
+var (
+	metadataClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			// a proxy would connect on our behalf, out of the reach of the dialer
+			Proxy:               nil,
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s is not https", req.URL)
+			}
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+	// CGNAT addresses are not private by net.IP but are not reachable from the internet either
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+	metadataFetcher MetadataFetcher = FetchMetadata
+)
+
+// MetadataFetcher returns the off-chain metadata of a jetton at the https url.
+type MetadataFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// SetMetadataFetcher replaces the fetcher of the off-chain metadata of jettons, FetchMetadata by default.
+func SetMetadataFetcher(f MetadataFetcher) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	metadataFetcher = f
+}
+
+func getMetadataFetcher() MetadataFetcher {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return metadataFetcher
+}
+
+// FetchMetadata gets the metadata over https from public addresses only, the url comes from the jetton
+// master and anyone can deploy one pointing to internal services.
+func FetchMetadata(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+// dialPublicOnly refuses connections to loopback, private and other non public addresses, it runs after
+// the name is resolved so a public name resolving to an internal address is refused too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+
+	return nil
+}
+
+// Jetton is a jetton master discovered on chain.
+type Jetton struct {
+	Address  string
+	Name     string
+	Symbol   string
+	Decimals int32
+	// the metadata of the jetton could not be loaded, its name and symbol are empty
+	Unknown bool
+}
+
+func (j *Jetton) GetContractAddress() string {
+	return j.Address
+}
+
+func (j *Jetton) GetContractName() string {
+	return j.Name
+}
+
+func (j *Jetton) GetTokenName() string {
+	return j.Symbol
+}
+
+func (j *Jetton) GetDecimals() int32 {
+	return j.Decimals
+}
+
+// jettonMetadata is the off-chain metadata of a jetton, decimals are a string by the standard
+// but some jettons use a number.
+type jettonMetadata struct {
+	Name     string      `json:"name"`
+	Symbol   string      `json:"symbol"`
+	Decimals json.Number `json:"decimals"`
+}
+
+// loadMetadata reads the name, symbol and decimals of the jetton from its content. The attributes of
+// semi-chain content stored on chain take precedence, the off-chain metadata only fills the missing ones
+// and is not required once the chain gives a symbol.
+func (j *Jetton) loadMetadata(ctx context.Context, content nft.ContentAny) error {
+	switch c := content.(type) {
+	case *nft.ContentOnchain:
+		return j.setOnchainMetadata(c)
+	case *nft.ContentOffchain:
+		metadata, err := loadOffchainMetadata(ctx, c.URI)
+		if err != nil {
+			return err
+		}
+		return j.setOffchainMetadata(metadata, false)
+	case *nft.ContentSemichain:
+		if err := j.setOnchainMetadata(&c.ContentOnchain); err != nil {
+			return err
+		}
+
+		metadata, err := loadOffchainMetadata(ctx, c.URI)
+		if err != nil {
+			if j.Symbol == "" {
+				return err
+			}
+
+			zap.S().Warn("load off-chain jetton metadata failed", zap.String("uri", c.URI), zap.Error(err))
+			return nil
+		}
+		return j.setOffchainMetadata(metadata, c.GetAttribute("decimals") != "")
+	}
+
+	return fmt.Errorf("unsupported content %T", content)
+}
+
+func (j *Jetton) setOnchainMetadata(content *nft.ContentOnchain) error {
+	if name := content.GetAttribute("name"); name != "" {
+		j.Name = name
+	}
+
+	if symbol := content.GetAttribute("symbol"); symbol != "" {
+		j.Symbol = symbol
+	}
+
+	if decimals := content.GetAttribute("decimals"); decimals != "" {
+		if err := j.setDecimals(decimals); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setOffchainMetadata fills the attributes the jetton does not have yet.
+func (j *Jetton) setOffchainMetadata(metadata *jettonMetadata, hasDecimals bool) error {
+	if j.Name == "" {
+		j.Name = metadata.Name
+	}
+
+	if j.Symbol == "" {
+		j.Symbol = metadata.Symbol
+	}
+
+	if !hasDecimals && metadata.Decimals != "" {
+		return j.setDecimals(metadata.Decimals.String())
+	}
+
+	return nil
+}
+
+// loadOffchainMetadata fetches the metadata at the https or ipfs uri, ipfs is read through a gateway.
+func loadOffchainMetadata(ctx context.Context, uri string) (*jettonMetadata, error) {
+	switch {
+	case uri == "":
+		return nil, fmt.Errorf("metadata uri is empty")
+	case strings.HasPrefix(uri, "ipfs://"):
+		uri = ipfsGateway + strings.TrimPrefix(uri, "ipfs://")
+	case !strings.HasPrefix(uri, "https://"):
+		return nil, fmt.Errorf("metadata uri %s is neither https nor ipfs", uri)
+	}
+
+	body, err := getMetadataFetcher()(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata from %s: %w", uri, err)
+	}
+
+	var metadata jettonMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata from %s: %w", uri, err)
+	}
+
+	return &metadata, nil
+}
+
+func (j *Jetton) setDecimals(v string) error {
+	decimals, err := strconv.ParseUint(v, 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid decimals %q: %w", v, err)
+	}
+
+	j.Decimals = int32(decimals)
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/contract"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// jettonMaster answers get_jetton_data with the content.
+type jettonMaster struct {
+	jetton.TonApi
+	content *cell.Cell
+}
+
+func (m *jettonMaster) CurrentMasterchainInfo(ctx context.Context) (*ton.BlockIDExt, error) {
+	return &ton.BlockIDExt{Workchain: address.MasterchainID, SeqNo: 1}, nil
+}
+
+func (m *jettonMaster) WaitForBlock(seqno uint32) ton.APIClientWrapped {
+	return masterClient{master: m}
+}
+
+type masterClient struct {
+	ton.APIClientWrapped
+	master *jettonMaster
+}
+
+func (c masterClient) RunGetMethod(ctx context.Context, block *ton.BlockIDExt, addr *address.Address, method string, params ...any) (*ton.ExecutionResult, error) {
+	if method != "get_jetton_data" {
+		return nil, errors.New("unexpected method " + method)
+	}
+
+	admin := cell.BeginCell().MustStoreAddr(nil).EndCell().BeginParse()
+	return ton.NewExecutionResult([]any{big.NewInt(1), big.NewInt(-1), admin, c.master.content, cell.BeginCell().EndCell()}), nil
+}
+
+func onchainContent(t *testing.T, uri string, attributes map[string]string) *cell.Cell {
+	t.Helper()
+
+	content := &nft.ContentOnchain{}
+	for name, value := range attributes {
+		if err := content.SetAttribute(name, value); err != nil {
+			t.Fatalf("SetAttribute() error = %v", err)
+		}
+	}
+
+	var c *cell.Cell
+	var err error
+	if uri != "" {
+		c, err = (&nft.ContentSemichain{ContentOffchain: nft.ContentOffchain{URI: uri}, ContentOnchain: *content}).ContentCell()
+	} else {
+		c, err = content.ContentCell()
+	}
+	if err != nil {
+		t.Fatalf("ContentCell() error = %v", err)
+	}
+
+	return c
+}
+
+func offchainContent(t *testing.T, uri string) *cell.Cell {
+	t.Helper()
+
+	c, err := (&nft.ContentOffchain{URI: uri}).ContentCell()
+	if err != nil {
+		t.Fatalf("ContentCell() error = %v", err)
+	}
+
+	return c
+}
+
+func TestFindAndRegisterByAddress(t *testing.T) {
+	fetched := make(map[string]int)
+	contract.SetMetadataFetcher(func(ctx context.Context, url string) ([]byte, error) {
+		fetched[url]++
+		if strings.Contains(url, "down") {
+			return nil, errors.New("connection refused")
+		}
+		return []byte(`{"name":"Off-chain","symbol":"OFF","decimals":"6"}`), nil
+	})
+	defer contract.SetMetadataFetcher(contract.FetchMetadata)
+
+	tests := []struct {
+		name     string
+		content  *cell.Cell
+		want     contract.Jetton
+		fetching string
+	}{
+		{
+			name:    "on-chain",
+			content: onchainContent(t, "", map[string]string{"name": "On-chain", "symbol": "ON", "decimals": "3"}),
+			want:    contract.Jetton{Name: "On-chain", Symbol: "ON", Decimals: 3},
+		},
+		{
+			name:     "off-chain",
+			content:  offchainContent(t, "https://example.com/off.json"),
+			want:     contract.Jetton{Name: "Off-chain", Symbol: "OFF", Decimals: 6},
+			fetching: "https://example.com/off.json",
+		},
+		{
+			name:     "off-chain on ipfs",
+			content:  offchainContent(t, "ipfs://bafkreie"),
+			want:     contract.Jetton{Name: "Off-chain", Symbol: "OFF", Decimals: 6},
+			fetching: "https://ipfs.io/ipfs/bafkreie",
+		},
+		{
+			name:    "off-chain over http",
+			content: offchainContent(t, "http://169.254.169.254/latest/meta-data"),
+			want:    contract.Jetton{Decimals: 9, Unknown: true},
+		},
+		{
+			name:     "semi-chain",
+			content:  onchainContent(t, "https://example.com/semi.json", map[string]string{"symbol": "SEMI", "decimals": "2"}),
+			want:     contract.Jetton{Name: "Off-chain", Symbol: "SEMI", Decimals: 2},
+			fetching: "https://example.com/semi.json",
+		},
+		{
+			name:     "semi-chain with its metadata down",
+			content:  onchainContent(t, "https://down.example.com/semi.json", map[string]string{"name": "Semi", "symbol": "SEMI"}),
+			want:     contract.Jetton{Name: "Semi", Symbol: "SEMI", Decimals: 9},
+			fetching: "https://down.example.com/semi.json",
+		},
+		{
+			name:     "off-chain down",
+			content:  offchainContent(t, "https://down.example.com/off.json"),
+			want:     contract.Jetton{Decimals: 9, Unknown: true},
+			fetching: "https://down.example.com/off.json",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, 32)
+			data[0], data[31] = 0xa0, byte(i)
+			master := address.NewAddress(0, 0, data).String()
+			api := &jettonMaster{content: tt.content}
+
+			for range 2 {
+				got, err := contract.FindAndRegisterByAddress(context.Background(), api, master)
+				if err != nil {
+					t.Fatalf("FindAndRegisterByAddress() error = %v", err)
+				}
+
+				tt.want.Address = master
+				if j, ok := got.(*contract.Jetton); !ok || *j != tt.want {
+					t.Errorf("FindAndRegisterByAddress() = %+v, want %+v", got, tt.want)
+				}
+			}
+
+			// known and unknown jettons are both cached
+			if tt.fetching != "" && fetched[tt.fetching] != 1 {
+				t.Errorf("fetched %s %d times, want once", tt.fetching, fetched[tt.fetching])
+			}
+		})
+	}
+}
+
+func TestFetchMetadata(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"symbol":"LOCAL"}`))
+	}))
+	defer server.Close()
+
+	_, err := contract.FetchMetadata(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "is not a public address") {
+		t.Errorf("FetchMetadata(%s) error = %v, want the loopback address refused", server.URL, err)
+	}
+}
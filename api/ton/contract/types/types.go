@@ -4,4 +4,5 @@ type IContract interface {
 	GetContractName() string
 	GetContractAddress() string
 	GetTokenName() string
+	GetDecimals() int32
 }
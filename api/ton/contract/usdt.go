@@ -4,6 +4,7 @@ const (
 	USDT_CONTRACT_ADDRESS = "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs"
 	USDT_CONTRACT_NAME    = "USDT-TON"
 	USDT_TOKEN_NAME       = "USDT"
+	USDT_DECIMALS         = 6
 )
 
 type TONUSDT struct {
@@ -20,3 +21,7 @@ func (e *TONUSDT) GetContractName() string {
 func (e *TONUSDT) GetTokenName() string {
 	return USDT_TOKEN_NAME
 }
+
+func (e *TONUSDT) GetDecimals() int32 {
+	return USDT_DECIMALS
+}
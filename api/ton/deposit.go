@@ -0,0 +1,579 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDepositPollInterval = 2 * time.Second
+	// transactions listed per request when scanning a shard block
+	blockTxPageSize = 100
+)
+
+// Deposit is TON or jettons credited to a watched wallet.
+type Deposit struct {
+	// watched wallet credited with the deposit
+	Address string
+	// empty for TON deposits
+	JettonMaster string
+	// jetton wallet of the watched wallet that received the jettons
+	JettonWallet string
+	// sender wallet, the owner of the sending jetton wallet for jetton deposits
+	From string
+	// nanotons or jetton units
+	Amount  string
+	Comment string
+	// transaction crediting the deposit, the jetton wallet transaction for jetton deposits
+	Lt   uint64
+	Hash []byte
+	// masterchain block the transaction was committed in
+	MasterSeqno uint32
+	Time        time.Time
+}
+
+// DepositCheckpoint is the last deposit handled, deposits of a masterchain block are handled in (lt, hash)
+// order so the watcher resumes right after it. Lt and Hash are empty when the block had no deposits.
+type DepositCheckpoint struct {
+	MasterSeqno uint32
+	Lt          uint64
+	Hash        []byte
+}
+
+// handled reports whether the deposit was handled before the checkpoint was saved.
+func (c *DepositCheckpoint) handled(d *Deposit) bool {
+	if d.MasterSeqno != c.MasterSeqno {
+		return d.MasterSeqno < c.MasterSeqno
+	}
+
+	if d.Lt != c.Lt {
+		return d.Lt < c.Lt
+	}
+
+	return bytes.Compare(d.Hash, c.Hash) <= 0
+}
+
+// DepositCheckpointStore persists the checkpoint of a deposit watcher so that it resumes where it stopped
+// after a restart. Load returns a nil checkpoint when the watcher never ran, it starts from the last block then.
+type DepositCheckpointStore interface {
+	LoadDepositCheckpoint(ctx context.Context) (*DepositCheckpoint, error)
+	SaveDepositCheckpoint(ctx context.Context, checkpoint DepositCheckpoint) error
+}
+
+// DepositHandler is called with every deposit, the watcher stops and returns the error when it fails. The
+// checkpoint is saved after the handler returns, a deposit is handled again when the process stops in between,
+// so handlers should be idempotent on (Lt, Hash).
+type DepositHandler func(ctx context.Context, deposit *Deposit) error
+
+type watchedAccount struct {
+	// raw address of the watched wallet, the owner for jetton wallets
+	owner string
+	// watched wallet as passed to Watch, deposits report it
+	address      string
+	jettonMaster string
+	jettonWallet string
+}
+
+type DepositWatcherOption func(*DepositWatcher)
+
+// WithDepositPollInterval sets how often the watcher checks for a new masterchain block, 2 seconds by default.
+func WithDepositPollInterval(v time.Duration) DepositWatcherOption {
+	return func(w *DepositWatcher) {
+		w.pollInterval = v
+	}
+}
+
+// WithDepositDecryption makes the watcher decrypt encrypted comments with the signers of the watched wallets,
+// they are left empty otherwise.
+func WithDepositDecryption(appId, network string) DepositWatcherOption {
+	return func(w *DepositWatcher) {
+		w.appId, w.network = appId, network
+		w.decrypt = true
+	}
+}
+
+// DepositWatcher follows the masterchain and scans the shard blocks for deposits to the watched wallets, in
+// TON or in jettons of the watched jetton masters.
+type DepositWatcher struct {
+	api          *TonApiV2
+	store        DepositCheckpointStore
+	pollInterval time.Duration
+	decrypt      bool
+	appId        string
+	network      string
+
+	mu sync.RWMutex
+	// watched wallets and their jetton wallets by raw address
+	accounts map[string]watchedAccount
+	// watched wallets by raw address, a wallet is watched once whatever the format of its address
+	owners  map[string]*address.Address
+	masters map[string]*address.Address
+}
+
+func (a *TonApiV2) NewDepositWatcher(store DepositCheckpointStore, opts ...DepositWatcherOption) *DepositWatcher {
+	w := &DepositWatcher{
+		api:          a,
+		store:        store,
+		pollInterval: defaultDepositPollInterval,
+		accounts:     make(map[string]watchedAccount),
+		owners:       make(map[string]*address.Address),
+		masters:      make(map[string]*address.Address),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Watch adds wallets to watch, their jetton wallets of the watched jetton masters are derived and watched too.
+func (w *DepositWatcher) Watch(ctx context.Context, addresses ...string) error {
+	w.mu.RLock()
+	masters := make([]*address.Address, 0, len(w.masters))
+	for _, master := range w.masters {
+		masters = append(masters, master)
+	}
+	w.mu.RUnlock()
+
+	for _, addrStr := range addresses {
+		owner, err := address.ParseAddr(addrStr)
+		if err != nil {
+			return types.WrapErr(types.ErrInvalidAddress, err)
+		}
+
+		wallet := watchedAccount{owner: rawAddress(owner), address: addrStr}
+		accounts := map[string]watchedAccount{wallet.owner: wallet}
+
+		for _, master := range masters {
+			key, account, err := w.jettonWallet(ctx, master, owner, wallet)
+			if err != nil {
+				return err
+			}
+			accounts[key] = account
+		}
+
+		w.mu.Lock()
+		w.owners[wallet.owner] = owner
+		for key, account := range accounts {
+			w.accounts[key] = account
+		}
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+// WatchJetton adds a jetton master to watch deposits of, the jetton wallets of the watched wallets are derived.
+func (w *DepositWatcher) WatchJetton(ctx context.Context, jettonMaster string) error {
+	master, err := address.ParseAddr(jettonMaster)
+	if err != nil {
+		return types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	w.mu.RLock()
+	owners := make(map[*address.Address]watchedAccount, len(w.owners))
+	for key, owner := range w.owners {
+		owners[owner] = w.accounts[key]
+	}
+	w.mu.RUnlock()
+
+	accounts := make(map[string]watchedAccount, len(owners))
+	for owner, wallet := range owners {
+		key, account, err := w.jettonWallet(ctx, master, owner, wallet)
+		if err != nil {
+			return err
+		}
+		accounts[key] = account
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.masters[jettonMaster] = master
+	for key, account := range accounts {
+		w.accounts[key] = account
+	}
+
+	return nil
+}
+
+// Unwatch stops watching wallets and their jetton wallets.
+func (w *DepositWatcher) Unwatch(addresses ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, addrStr := range addresses {
+		if owner, err := address.ParseAddr(addrStr); err == nil {
+			delete(w.owners, rawAddress(owner))
+		}
+	}
+
+	for key, account := range w.accounts {
+		if _, ok := w.owners[account.owner]; !ok {
+			delete(w.accounts, key)
+		}
+	}
+}
+
+func (w *DepositWatcher) jettonWallet(ctx context.Context, master, owner *address.Address, wallet watchedAccount) (string, watchedAccount, error) {
+	tokenWallet, err := jetton.NewJettonMasterClient(w.api.lclient, master).GetJettonWallet(ctx, owner)
+	if err != nil {
+		w.api.logger.Error("GetJettonWallet failed", zap.Error(err), zap.String("address", wallet.address))
+		return "", watchedAccount{}, err
+	}
+
+	return rawAddress(tokenWallet.Address()), watchedAccount{
+		owner:        wallet.owner,
+		address:      wallet.address,
+		jettonMaster: master.String(),
+		jettonWallet: tokenWallet.Address().String(),
+	}, nil
+}
+
+func (w *DepositWatcher) watched(workchain int32, account []byte) (watchedAccount, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	a, ok := w.accounts[fmt.Sprintf("%d:%x", workchain, account)]
+	return a, ok
+}
+
+// Run follows the masterchain from the checkpoint and calls the handler with the deposits of every block,
+// until the context is done or the handler fails.
+func (w *DepositWatcher) Run(ctx context.Context, handle DepositHandler) error {
+	// route all requests to the same node
+	ctx = w.api.lclient.Client().StickyContext(ctx)
+
+	checkpoint, err := w.store.LoadDepositCheckpoint(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load deposit checkpoint: %w", err)
+	}
+
+	if checkpoint == nil {
+		master, err := w.api.lclient.CurrentMasterchainInfo(ctx)
+		if err != nil {
+			w.api.logger.Error("get masterchain info failed", zap.Error(err))
+			return err
+		}
+		checkpoint = &DepositCheckpoint{MasterSeqno: master.SeqNo}
+	}
+
+	// the shards of the previous block are scanned already, the checkpoint block is scanned again
+	prev, err := w.waitMasterBlock(ctx, checkpoint.MasterSeqno-1)
+	if err != nil {
+		return err
+	}
+
+	shardLastSeqno, err := w.shardSeqnos(ctx, prev)
+	if err != nil {
+		return err
+	}
+
+	for seqno := checkpoint.MasterSeqno; ; seqno++ {
+		master, err := w.waitMasterBlock(ctx, seqno)
+		if err != nil {
+			return err
+		}
+
+		deposits, err := w.scanMasterBlock(ctx, master, shardLastSeqno)
+		if err != nil {
+			w.api.logger.Error("scan master block failed", zap.Error(err), zap.Uint32("seqno", seqno))
+			return err
+		}
+
+		if checkpoint.MasterSeqno != seqno {
+			checkpoint = &DepositCheckpoint{MasterSeqno: seqno}
+		}
+
+		for _, d := range deposits {
+			if checkpoint.handled(d) {
+				continue
+			}
+
+			if err := handle(ctx, d); err != nil {
+				return err
+			}
+
+			checkpoint = &DepositCheckpoint{MasterSeqno: seqno, Lt: d.Lt, Hash: d.Hash}
+			if err := w.store.SaveDepositCheckpoint(ctx, *checkpoint); err != nil {
+				return fmt.Errorf("failed to save deposit checkpoint: %w", err)
+			}
+		}
+
+		if checkpoint.Hash == nil {
+			if err := w.store.SaveDepositCheckpoint(ctx, *checkpoint); err != nil {
+				return fmt.Errorf("failed to save deposit checkpoint: %w", err)
+			}
+		}
+	}
+}
+
+// waitMasterBlock polls the masterchain until the block with the seqno is committed.
+func (w *DepositWatcher) waitMasterBlock(ctx context.Context, seqno uint32) (*ton.BlockIDExt, error) {
+	for {
+		master, err := w.api.lclient.CurrentMasterchainInfo(ctx)
+		if err != nil {
+			w.api.logger.Error("get masterchain info failed", zap.Error(err))
+			return nil, err
+		}
+
+		if master.SeqNo >= seqno {
+			block, err := w.api.lclient.LookupBlock(ctx, master.Workchain, master.Shard, seqno)
+			if err != nil {
+				w.api.logger.Error("lookup master block failed", zap.Error(err), zap.Uint32("seqno", seqno))
+				return nil, err
+			}
+			return block, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+func shardID(shard *ton.BlockIDExt) string {
+	return fmt.Sprintf("%d|%d", shard.Workchain, shard.Shard)
+}
+
+// shardSeqnos returns the seqnos of the shard blocks committed in the master block by shard.
+func (w *DepositWatcher) shardSeqnos(ctx context.Context, master *ton.BlockIDExt) (map[string]uint32, error) {
+	shards, err := w.api.lclient.GetBlockShardsInfo(ctx, master)
+	if err != nil {
+		w.api.logger.Error("get block shards failed", zap.Error(err), zap.Uint32("seqno", master.SeqNo))
+		return nil, err
+	}
+
+	seqnos := make(map[string]uint32, len(shards))
+	for _, shard := range shards {
+		seqnos[shardID(shard)] = shard.SeqNo
+	}
+
+	return seqnos, nil
+}
+
+// scanMasterBlock returns the deposits of the master block and of the shard blocks it commits, sorted by
+// (lt, hash), and moves the last seen shard seqnos to the block.
+func (w *DepositWatcher) scanMasterBlock(ctx context.Context, master *ton.BlockIDExt, shardLastSeqno map[string]uint32) ([]*Deposit, error) {
+	client := w.api.lclient.WaitForBlock(master.SeqNo)
+
+	shards, err := client.GetBlockShardsInfo(ctx, master)
+	if err != nil {
+		return nil, fmt.Errorf("get block shards: %w", err)
+	}
+
+	// shard blocks are committed in batches, the ones between two master blocks are found through their parents
+	blocks := []*ton.BlockIDExt{master}
+	for _, shard := range shards {
+		notSeen, err := notSeenShards(ctx, client, shard, shardLastSeqno)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, notSeen...)
+	}
+
+	var deposits []*Deposit
+	for _, block := range blocks {
+		found, err := w.scanBlock(ctx, client, block, master.SeqNo)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, found...)
+	}
+
+	for _, shard := range shards {
+		shardLastSeqno[shardID(shard)] = shard.SeqNo
+	}
+
+	sort.Slice(deposits, func(i, j int) bool {
+		if deposits[i].Lt != deposits[j].Lt {
+			return deposits[i].Lt < deposits[j].Lt
+		}
+		return bytes.Compare(deposits[i].Hash, deposits[j].Hash) < 0
+	})
+
+	return deposits, nil
+}
+
+func notSeenShards(ctx context.Context, client ton.APIClientWrapped, shard *ton.BlockIDExt, shardLastSeqno map[string]uint32) ([]*ton.BlockIDExt, error) {
+	if seqno, ok := shardLastSeqno[shardID(shard)]; ok && seqno == shard.SeqNo {
+		return nil, nil
+	}
+
+	b, err := client.GetBlockData(ctx, shard)
+	if err != nil {
+		return nil, fmt.Errorf("get block data: %w", err)
+	}
+
+	parents, err := b.BlockInfo.GetParentBlocks()
+	if err != nil {
+		return nil, fmt.Errorf("get parent blocks of %d:%x:%d: %w", shard.Workchain, uint64(shard.Shard), shard.SeqNo, err)
+	}
+
+	var blocks []*ton.BlockIDExt
+	for _, parent := range parents {
+		notSeen, err := notSeenShards(ctx, client, parent, shardLastSeqno)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, notSeen...)
+	}
+
+	return append(blocks, shard), nil
+}
+
+// scanBlock returns the deposits of the block, only the transactions of watched accounts are fetched.
+func (w *DepositWatcher) scanBlock(ctx context.Context, client ton.APIClientWrapped, block *ton.BlockIDExt, masterSeqno uint32) ([]*Deposit, error) {
+	var deposits []*Deposit
+	var after *ton.TransactionID3
+
+	for more := true; more; {
+		var ids []ton.TransactionShortInfo
+		var err error
+
+		ids, more, err = client.GetBlockTransactionsV2(ctx, block, blockTxPageSize, after)
+		if err != nil {
+			return nil, fmt.Errorf("get block transactions: %w", err)
+		}
+
+		if more {
+			after = ids[len(ids)-1].ID3()
+		}
+
+		for _, id := range ids {
+			account, ok := w.watched(block.Workchain, id.Account)
+			if !ok {
+				continue
+			}
+
+			tx, err := client.GetTransaction(ctx, block, address.NewAddress(0, byte(block.Workchain), id.Account), id.LT)
+			if err != nil {
+				return nil, fmt.Errorf("get transaction: %w", err)
+			}
+
+			d, err := w.deposit(ctx, account, tx)
+			if err != nil {
+				return nil, err
+			}
+
+			if d != nil {
+				d.MasterSeqno = masterSeqno
+				deposits = append(deposits, d)
+			}
+		}
+	}
+
+	return deposits, nil
+}
+
+// deposit returns the deposit credited by the transaction of a watched account, nil when it credits none.
+// Wallets are credited by TON transfers with or without a comment, jetton wallets by the internal transfers
+// of other jetton wallets, their notifications to the owner can be sent by anyone and are not trusted.
+func (w *DepositWatcher) deposit(ctx context.Context, account watchedAccount, tx *tlb.Transaction) (*Deposit, error) {
+	if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return nil, nil
+	}
+
+	lite := &wrap.LiteTransactionWrapper{Transaction: tx}
+	status, err := lite.GetStatus()
+	if err != nil || status.Outcome != wrap.TransactionOutcomeSuccess {
+		return nil, nil
+	}
+
+	body, err := wrap.ParseMessageBody(tx.IO.In.AsInternal().Body)
+	if err != nil {
+		w.api.logger.Warn("parse deposit body failed", zap.Error(err), zap.String("address", account.address))
+		return nil, nil
+	}
+
+	switch body.OpName {
+	// empty bodies and comments only, other ops are calls of the wallet or bounces
+	case "", wrap.DecodedOpNameTextComment, wrap.DecodedOpNameEncryptedTextComment:
+		if account.jettonWallet != "" {
+			return nil, nil
+		}
+	case wrap.DecodedOpNameJettonInternalTransfer:
+		if account.jettonWallet == "" {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	addresses, err := lite.GetTxAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Deposit{
+		Address:      account.address,
+		JettonMaster: account.jettonMaster,
+		JettonWallet: account.jettonWallet,
+		From:         addresses.From,
+		Amount:       lite.GetAmount(),
+		Comment:      lite.GetComment(),
+		Lt:           tx.LT,
+		Hash:         tx.Hash,
+		Time:         time.Unix(int64(tx.Now), 0),
+	}
+
+	if body.OpName == wrap.DecodedOpNameEncryptedTextComment && w.decrypt {
+		signer, err := w.api.signerProvider.Provide(ctx, w.appId, w.network, account.address)
+		if err != nil {
+			w.api.logger.Error("get signer failed", zap.Error(err))
+			return nil, err
+		}
+
+		if d.Comment, err = lite.GetEncryptedComment(ctx, w.api.lclient, signer); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// MemoryDepositCheckpointStore keeps the checkpoint in memory, a watcher using it starts from the last block
+// when the process restarts.
+type MemoryDepositCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *DepositCheckpoint
+}
+
+func NewMemoryDepositCheckpointStore() *MemoryDepositCheckpointStore {
+	return &MemoryDepositCheckpointStore{}
+}
+
+func (s *MemoryDepositCheckpointStore) LoadDepositCheckpoint(ctx context.Context) (*DepositCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.checkpoint == nil {
+		return nil, nil
+	}
+
+	checkpoint := *s.checkpoint
+	return &checkpoint, nil
+}
+
+func (s *MemoryDepositCheckpointStore) SaveDepositCheckpoint(ctx context.Context, checkpoint DepositCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoint = &checkpoint
+	return nil
+}
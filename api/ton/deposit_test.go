@@ -0,0 +1,346 @@
+package ton_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	_ton "github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+// shard of every block of the basechain in the fake, the chain never splits
+const fakeShard = -1 << 63
+
+// blockChain commits the transactions of fakeChain in blocks, master block n commits basechain block n.
+type blockChain struct {
+	*fakeChain
+
+	// transactions of the basechain blocks by seqno
+	blocks     map[uint32][]*tlb.Transaction
+	lastMaster uint32
+	// jetton wallets of the owners by raw address
+	jettonWallets map[string]*address.Address
+}
+
+func newBlockChain(lastMaster uint32) *blockChain {
+	return &blockChain{
+		fakeChain:     newFakeChain(),
+		blocks:        make(map[uint32][]*tlb.Transaction),
+		lastMaster:    lastMaster,
+		jettonWallets: make(map[string]*address.Address),
+	}
+}
+
+func masterBlock(seqno uint32) *_ton.BlockIDExt {
+	return &_ton.BlockIDExt{Workchain: address.MasterchainID, Shard: fakeShard, SeqNo: seqno}
+}
+
+func (c *blockChain) commit(seqno uint32, txs ...*tlb.Transaction) {
+	c.blocks[seqno] = append(c.blocks[seqno], txs...)
+}
+
+func (c *blockChain) CurrentMasterchainInfo(ctx context.Context) (*_ton.BlockIDExt, error) {
+	return masterBlock(c.lastMaster), nil
+}
+
+func (c *blockChain) WaitForBlock(seqno uint32) _ton.APIClientWrapped {
+	return c
+}
+
+func (c *blockChain) LookupBlock(ctx context.Context, workchain int32, shard int64, seqno uint32) (*_ton.BlockIDExt, error) {
+	return masterBlock(seqno), nil
+}
+
+func (c *blockChain) GetBlockShardsInfo(ctx context.Context, master *_ton.BlockIDExt) ([]*_ton.BlockIDExt, error) {
+	return []*_ton.BlockIDExt{{Workchain: 0, Shard: fakeShard, SeqNo: master.SeqNo}}, nil
+}
+
+func (c *blockChain) GetBlockData(ctx context.Context, block *_ton.BlockIDExt) (*tlb.Block, error) {
+	b := &tlb.Block{}
+	b.BlockInfo.Shard = tlb.ShardIdent{WorkchainID: block.Workchain}
+	b.BlockInfo.SeqNo = block.SeqNo
+	b.BlockInfo.PrevRef.Prev1.SeqNo = block.SeqNo - 1
+	return b, nil
+}
+
+func (c *blockChain) GetBlockTransactionsV2(ctx context.Context, block *_ton.BlockIDExt, count uint32, after ...*_ton.TransactionID3) ([]_ton.TransactionShortInfo, bool, error) {
+	var ids []_ton.TransactionShortInfo
+	if block.Workchain == 0 {
+		for _, tx := range c.blocks[block.SeqNo] {
+			ids = append(ids, _ton.TransactionShortInfo{Account: tx.AccountAddr, LT: tx.LT, Hash: tx.Hash})
+		}
+	}
+
+	return ids, false, nil
+}
+
+func (c *blockChain) GetTransaction(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address, lt uint64) (*tlb.Transaction, error) {
+	for _, tx := range c.blocks[block.SeqNo] {
+		if tx.LT == lt && addr.Equals(address.NewAddress(0, 0, tx.AccountAddr)) {
+			return tx, nil
+		}
+	}
+
+	return nil, _ton.ErrNoTransactionsWereFound
+}
+
+func (c *blockChain) RunGetMethod(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address, method string, params ...any) (*_ton.ExecutionResult, error) {
+	owner, err := params[0].(*cell.Slice).LoadAddr()
+	if method != "get_wallet_address" || err != nil {
+		return nil, errors.New("unexpected get method " + method)
+	}
+
+	jettonWallet := c.jettonWallets[string(owner.Data())]
+	return _ton.NewExecutionResult([]any{cell.BeginCell().MustStoreAddr(jettonWallet).EndCell().BeginParse()}), nil
+}
+
+// uninitialized makes the transaction the one of an account without code, aborted before computing.
+func uninitialized(tx *tlb.Transaction) *tlb.Transaction {
+	description := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	description.Aborted = true
+	description.ComputePhase = tlb.ComputePhase{Phase: tlb.ComputePhaseSkipped{Reason: tlb.ComputeSkipReason{Type: tlb.ComputeSkipReasonNoState}}}
+	description.ActionPhase = nil
+	tx.Description.Description = description
+	return tx
+}
+
+// collector handles deposits until it sees the last one or fails on the failing one.
+type collector struct {
+	hashes  []string
+	failing *tlb.Transaction
+	last    *tlb.Transaction
+	cancel  context.CancelFunc
+}
+
+func (c *collector) handle(ctx context.Context, d *ton.Deposit) error {
+	if c.failing != nil && string(d.Hash) == string(c.failing.Hash) {
+		return errors.New("handler failed")
+	}
+
+	c.hashes = append(c.hashes, string(d.Hash))
+	if c.last != nil && string(d.Hash) == string(c.last.Hash) {
+		c.cancel()
+	}
+	return nil
+}
+
+func hashes(txs ...*tlb.Transaction) []string {
+	list := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		list = append(list, string(tx.Hash))
+	}
+	return list
+}
+
+func TestDepositWatcherClassification(t *testing.T) {
+	var (
+		owner        = newTestAddress(10)
+		ownerJetton  = newTestAddress(11)
+		sender       = newTestAddress(12)
+		senderJetton = newTestAddress(13)
+		master       = newTestAddress(14)
+		unwatched    = newTestAddress(15)
+	)
+
+	chain := newBlockChain(10)
+	chain.jettonWallets[string(owner.Data())] = ownerJetton
+
+	send := func(msg *tlb.InternalMessage) *tlb.Message {
+		msg.SrcAddr = sender
+		msg.CreatedLT = chain.lt + 1
+		return &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: msg}
+	}
+
+	transfer := chain.add(t, owner, send(message(t, owner, "1", "deposit 1")))
+
+	bouncedMsg := message(t, owner, "1", nil)
+	bouncedMsg.Bounced, bouncedMsg.Bounce = true, false
+	bounced := chain.add(t, owner, send(bouncedMsg))
+
+	// non-bounceable TON stays with an uninitialized wallet, a bounceable transfer goes back to the sender
+	nonBounceableMsg := message(t, owner, "2", nil)
+	nonBounceableMsg.Bounce = false
+	nonBounceable := uninitialized(chain.add(t, owner, send(nonBounceableMsg)))
+
+	bounceBack := message(t, sender, "2.9", nil)
+	bounceBack.Bounced, bounceBack.Bounce = true, false
+	bounceable := uninitialized(chain.add(t, owner, send(message(t, owner, "3", nil)), bounceBack))
+
+	// anyone can send a transfer notification to the wallet
+	forged := chain.add(t, owner, send(message(t, owner, "0.1", jetton.TransferNotification{
+		QueryID: 1,
+		Amount:  tlb.MustFromNano(big.NewInt(1_000_000), 0),
+		Sender:  sender,
+	})))
+
+	// TON attached to a call of the wallet is not a deposit
+	call := chain.add(t, owner, send(message(t, owner, "5", cell.BeginCell().MustStoreUInt(0x12345678, 32).MustStoreUInt(0, 64).EndCell())))
+
+	internal := message(t, ownerJetton, "0.05", internalTransfer{
+		QueryID:         2,
+		Amount:          tlb.MustFromNano(big.NewInt(700), 0),
+		From:            sender,
+		ResponseAddress: sender,
+	})
+	internal.SrcAddr = senderJetton
+	internal.CreatedLT = chain.lt + 1
+	jettonDeposit := chain.add(t, ownerJetton, &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: internal})
+
+	tonToJettonWallet := chain.add(t, ownerJetton, send(message(t, ownerJetton, "1", nil)))
+	toUnwatched := chain.add(t, unwatched, send(message(t, unwatched, "1", nil)))
+
+	chain.commit(10, transfer, bounced, nonBounceable, bounceable, forged, call, jettonDeposit, tonToJettonWallet, toUnwatched)
+
+	tonApi := ton.NewTonApiV2(api.NewSignerProvider(), nil, chain, zap.NewNop())
+	watcher := tonApi.NewDepositWatcher(ton.NewMemoryDepositCheckpointStore())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the same wallets in other formats are watched once and unwatched by any of them
+	if err := watcher.Watch(ctx, owner.String(), address.NewAddress(0, 0, owner.Data()).Bounce(false).String(), unwatched.String()); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if err := watcher.WatchJetton(ctx, master.String()); err != nil {
+		t.Fatalf("WatchJetton() error = %v", err)
+	}
+	watcher.Unwatch(address.NewAddress(0, 0, unwatched.Data()).Bounce(false).String())
+
+	var deposits []*ton.Deposit
+	handle := func(ctx context.Context, d *ton.Deposit) error {
+		deposits = append(deposits, d)
+		if string(d.Hash) == string(jettonDeposit.Hash) {
+			cancel()
+		}
+		return nil
+	}
+
+	if err := watcher.Run(ctx, handle); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	want := []struct {
+		tx           *tlb.Transaction
+		amount       string
+		jettonWallet string
+	}{
+		{transfer, "1000000000", ""},
+		{nonBounceable, "2000000000", ""},
+		{jettonDeposit, "700", address.NewAddress(0, 0, ownerJetton.Data()).String()},
+	}
+
+	if len(deposits) != len(want) {
+		t.Fatalf("Run() handled %d deposits, want %d", len(deposits), len(want))
+	}
+
+	for i, w := range want {
+		d := deposits[i]
+		if string(d.Hash) != string(w.tx.Hash) || d.Amount != w.amount || d.JettonWallet != w.jettonWallet {
+			t.Errorf("deposit %d = %s of %q at %d, want %s of %q at %d", i, d.Amount, d.JettonWallet, d.Lt, w.amount, w.jettonWallet, w.tx.LT)
+		}
+		if d.Address != address.NewAddress(0, 0, owner.Data()).Bounce(false).String() || d.MasterSeqno != 10 {
+			t.Errorf("deposit %d to %s in block %d, want the last watched format of %s in block 10", i, d.Address, d.MasterSeqno, owner)
+		}
+	}
+}
+
+func TestDepositWatcherResume(t *testing.T) {
+	wallet := newTestAddress(20)
+	sender := newTestAddress(21)
+
+	chain := newBlockChain(11)
+	deposit := func(block uint32) *tlb.Transaction {
+		msg := message(t, wallet, "1", nil)
+		msg.SrcAddr, msg.CreatedLT = sender, chain.lt+1
+
+		tx := chain.add(t, wallet, &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: msg})
+		chain.commit(block, tx)
+		return tx
+	}
+
+	first, second, third := deposit(10), deposit(10), deposit(11)
+
+	tests := []struct {
+		name       string
+		checkpoint ton.DepositCheckpoint
+		want       []*tlb.Transaction
+	}{
+		{"start of a block", ton.DepositCheckpoint{MasterSeqno: 10}, []*tlb.Transaction{first, second, third}},
+		{"middle of a block", ton.DepositCheckpoint{MasterSeqno: 10, Lt: first.LT, Hash: first.Hash}, []*tlb.Transaction{second, third}},
+		{"end of a block", ton.DepositCheckpoint{MasterSeqno: 10, Lt: second.LT, Hash: second.Hash}, []*tlb.Transaction{third}},
+		{"next block", ton.DepositCheckpoint{MasterSeqno: 11}, []*tlb.Transaction{third}},
+	}
+
+	tonApi := ton.NewTonApiV2(api.NewSignerProvider(), nil, chain, zap.NewNop())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := ton.NewMemoryDepositCheckpointStore()
+			_ = store.SaveDepositCheckpoint(context.Background(), tt.checkpoint)
+
+			watcher := tonApi.NewDepositWatcher(store)
+			if err := watcher.Watch(context.Background(), wallet.String()); err != nil {
+				t.Fatalf("Watch() error = %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			got := &collector{last: third, cancel: cancel}
+			if err := watcher.Run(ctx, got.handle); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+			}
+
+			if !slices.Equal(got.hashes, hashes(tt.want...)) {
+				t.Errorf("handled %d deposits, want %d", len(got.hashes), len(tt.want))
+			}
+		})
+	}
+
+	t.Run("failing handler", func(t *testing.T) {
+		store := ton.NewMemoryDepositCheckpointStore()
+		_ = store.SaveDepositCheckpoint(context.Background(), ton.DepositCheckpoint{MasterSeqno: 10})
+
+		watcher := tonApi.NewDepositWatcher(store)
+		if err := watcher.Watch(context.Background(), wallet.String()); err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+
+		got := &collector{failing: second}
+		if err := watcher.Run(context.Background(), got.handle); err == nil {
+			t.Fatalf("Run() error = nil, want the handler error")
+		}
+
+		checkpoint, _ := store.LoadDepositCheckpoint(context.Background())
+		if checkpoint == nil || checkpoint.MasterSeqno != 10 || checkpoint.Lt != first.LT {
+			t.Fatalf("checkpoint = %+v, want block 10 at lt %d", checkpoint, first.LT)
+		}
+
+		// a new watcher resumes in the middle of the block
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		resumed := tonApi.NewDepositWatcher(store)
+		if err := resumed.Watch(ctx, wallet.String()); err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+
+		got = &collector{last: third, cancel: cancel}
+		if err := resumed.Run(ctx, got.handle); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+		}
+
+		if !slices.Equal(got.hashes, hashes(second, third)) {
+			t.Errorf("resumed watcher handled %d deposits, want the 2 after the checkpoint", len(got.hashes))
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package ton
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// GasFee, ForwardFee and StorageFee expose the fee formulas over config params to the tests.
+
+func GasFee(param *cell.Cell, gas uint64) (*big.Int, error) {
+	prices, err := parseGasPrices(param)
+	if err != nil {
+		return nil, err
+	}
+
+	return prices.fee(gas), nil
+}
+
+func ForwardFee(param, msg *cell.Cell) (*big.Int, error) {
+	prices, err := parseMsgForwardPrices(param)
+	if err != nil {
+		return nil, err
+	}
+
+	return prices.fee(msg), nil
+}
+
+func StorageFee(param *cell.Cell, now uint32, bits, cells, seconds int64, masterchain bool) (*big.Int, error) {
+	prices, err := parseStoragePrices(param, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return prices.fee(big.NewInt(bits), big.NewInt(cells), seconds, masterchain), nil
+}
+
+// RelayedAmount exposes the TON a fee payer attaches to relayed transfers.
+var RelayedAmount = relayedAmount
+
+// NftTransferAmounts exposes the forward and attached TON of NFT transfers.
+var NftTransferAmounts = nftTransferAmounts
+
+// DetectWalletVersion and WalletFromSigner expose the wallet version detection over the options.
+
+func DetectWalletVersion(ctx context.Context, client wallet.TonAPI, addr *address.Address, opts ...Option) (wallet.VersionConfig, error) {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return detectWalletVersion(ctx, client, addr, options)
+}
+
+func WalletFromSigner(ctx context.Context, client wallet.TonAPI, signer api.Signer, fromAddress string, opts ...Option) (*wallet.Wallet, error) {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return walletFromSigner(ctx, client, signer, fromAddress, options)
+}
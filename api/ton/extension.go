@@ -0,0 +1,71 @@
+package ton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"go.uber.org/zap"
+)
+
+// ExtensionsInput installs and removes extensions of a V5R1 wallet, contracts allowed to make the
+// wallet send messages without the signature of its owner.
+type ExtensionsInput struct {
+	AppId       string
+	Network     string
+	FromAddress string
+	// addresses of the extensions to install
+	Add []string
+	// addresses of the extensions to remove
+	Remove []string
+}
+
+// ManageExtensions sends the external message installing and removing the extensions of the wallet.
+func (a *TonApiV2) ManageExtensions(ctx context.Context, input *ExtensionsInput) (*types.TransferOutput, error) {
+	var actions []wallet.V5R1ExtensionAction
+	for _, list := range []struct {
+		addresses []string
+		remove    bool
+	}{{input.Add, false}, {input.Remove, true}} {
+		for _, extension := range list.addresses {
+			addr, err := address.ParseAddr(extension)
+			if err != nil {
+				return nil, types.WrapErr(types.ErrInvalidAddress, err)
+			}
+
+			actions = append(actions, wallet.V5R1ExtensionAction{Address: addr, Remove: list.remove})
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, errors.New("no extensions to add or remove")
+	}
+
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	w, err := a.getWallet(ctx, &types.TransferInput{
+		AppId:       input.AppId,
+		Network:     input.Network,
+		FromAddress: input.FromAddress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := w.GetSpec().(*wallet.SpecV5R1)
+	if !ok {
+		return nil, fmt.Errorf("extensions need a V5R1 wallet: %w", wallet.ErrUnsupportedWalletVersion)
+	}
+
+	body, err := spec.BuildExtensionsMessage(ctx, actions, nil)
+	if err != nil {
+		a.logger.Error("BuildExtensionsMessage failed", zap.Error(err))
+		return nil, err
+	}
+
+	return a.sendWalletRequest(ctx, w, body, nil)
+}
@@ -0,0 +1,430 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+const (
+	// config params with the prices of storage, gas and forwarding on the masterchain and the basechain
+	configStoragePrices        = 18
+	configMasterchainGasPrices = 20
+	configBasechainGasPrices   = 21
+	configMasterchainFwdPrices = 24
+	configBasechainFwdPrices   = 25
+
+	tagGasPrices        = 0xdd
+	tagGasPricesExt     = 0xde
+	tagGasFlatPfx       = 0xd1
+	tagMsgForwardPrices = 0xea
+	tagStoragePrices    = 0xcc
+
+	// prices in the config are in 1/65536 nanoton
+	priceFractionBits = 16
+
+	// gas wallets use to check an external message, rounded up from typical transfers,
+	// every message they send costs walletMessageGas more
+	walletV3Gas       = 3_000
+	walletV4Gas       = 4_000
+	walletV5Gas       = 5_000
+	highloadWalletGas = 6_000
+	defaultWalletGas  = 5_000
+	walletMessageGas  = 700
+
+	// gas the reference jetton wallet uses to send a transfer and to receive it
+	jettonSendTransferGas    = 10_065
+	jettonReceiveTransferGas = 10_435
+	// legacy jetton wallets charge a fixed 0.01 TON per transfer leg instead of measuring gas, both
+	// keep 0.01 TON for the storage of a newly deployed recipient wallet
+	legacyJettonGasConsumption = 10_000_000
+	jettonMinStorage           = 10_000_000
+)
+
+// FeeEstimate is the breakdown of the TON a transfer costs the sender, in nanotons.
+type FeeEstimate struct {
+	// fee for importing the external message into the blockchain
+	ImportFee *big.Int
+	// compute fee of the wallet transaction
+	GasFee *big.Int
+	// forward fees of the messages the wallet sends
+	ForwardFee *big.Int
+	// storage fee the wallet owes since it last paid
+	StorageFee *big.Int
+	// fees the jetton wallets take from the attached TON, excluding the forward amount. The attached TON is
+	// sent with the transfer and already covers them, they are not part of Total
+	JettonFee *big.Int
+	// fees of the wallet transaction, paid on top of the TON it sends
+	Total *big.Int
+}
+
+type gasPrices struct {
+	flatGasLimit uint64
+	flatGasPrice uint64
+	// price of a gas unit in 1/65536 nanoton
+	gasPrice uint64
+}
+
+type msgForwardPrices struct {
+	lumpPrice uint64
+	// prices of a bit and a cell in 1/65536 nanoton
+	bitPrice  uint64
+	cellPrice uint64
+}
+
+type storagePrices struct {
+	utimeSince uint32
+	// prices of a bit and a cell per second in 1/65536 nanoton
+	bitPrice    uint64
+	cellPrice   uint64
+	mcBitPrice  uint64
+	mcCellPrice uint64
+}
+
+type feeConfig struct {
+	gas         gasPrices
+	forward     msgForwardPrices
+	storage     storagePrices
+	masterchain bool
+}
+
+// estimateFee computes the fees of the external message locally from the blockchain config, the wallet gas
+// and the gas of jetton wallets are the typical amounts used by their contracts. With jetton set the messages
+// are jetton transfers.
+func (a *TonApiV2) estimateFee(ctx context.Context, w *wallet.Wallet, ext *tlb.ExternalMessage, messages []*wallet.Message, jetton bool) (*FeeEstimate, error) {
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return nil, err
+	}
+
+	config, err := a.getFeeConfig(ctx, block, w.WalletAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	extCell, err := tlb.ToCell(ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert external message to cell: %w", err)
+	}
+
+	estimate := &FeeEstimate{
+		ImportFee:  config.forward.fee(extCell),
+		GasFee:     config.gas.fee(walletGas(w) + walletMessageGas*uint64(len(messages))),
+		ForwardFee: new(big.Int),
+		StorageFee: new(big.Int),
+		JettonFee:  new(big.Int),
+	}
+
+	for _, message := range messages {
+		msgCell, err := tlb.ToCell(message.InternalMessage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert message to cell: %w", err)
+		}
+
+		fwdFee := config.forward.fee(msgCell)
+		estimate.ForwardFee.Add(estimate.ForwardFee, fwdFee)
+
+		if jetton {
+			// sender and recipient jetton wallets, the internal transfer and the notification are about
+			// the size of the transfer, the excesses only cost the lump price
+			jettonFee := new(big.Int).Add(config.gas.fee(jettonSendTransferGas), config.gas.fee(jettonReceiveTransferGas))
+			jettonFee.Add(jettonFee, new(big.Int).Mul(fwdFee, big.NewInt(2)))
+			jettonFee.Add(jettonFee, new(big.Int).SetUint64(config.forward.lumpPrice))
+			estimate.JettonFee.Add(estimate.JettonFee, jettonFee)
+		}
+	}
+
+	account, err := a.lclient.WaitForBlock(block.SeqNo).GetAccount(ctx, block, w.WalletAddress())
+	if err != nil {
+		a.logger.Error("get account failed", zap.Error(err))
+		return nil, err
+	}
+
+	if account.IsActive && account.State != nil {
+		info := account.State.StorageInfo
+		seconds := time.Now().Unix() - int64(info.LastPaid)
+		estimate.StorageFee = config.storage.fee(info.StorageUsed.BitsUsed, info.StorageUsed.CellsUsed, seconds, config.masterchain)
+	}
+
+	estimate.Total = new(big.Int).Add(estimate.ImportFee, estimate.GasFee)
+	estimate.Total.Add(estimate.Total, estimate.ForwardFee)
+	estimate.Total.Add(estimate.Total, estimate.StorageFee)
+
+	return estimate, nil
+}
+
+// jettonAttachedAmount derives the TON to attach to a jetton transfer the way jetton wallets check it: the forward
+// amount, a forward fee for the internal transfer and one for the notification, the gas of both jetton wallets
+// and the storage of the recipient wallet. Fees are doubled so that price changes do not bounce the transfer.
+func (a *TonApiV2) jettonAttachedAmount(ctx context.Context, transfer *tlb.InternalMessage, forwardAmount *big.Int) (*big.Int, error) {
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return nil, err
+	}
+
+	config, err := a.getFeeConfig(ctx, block, transfer.DstAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	msgCell, err := tlb.ToCell(transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert message to cell: %w", err)
+	}
+
+	fwdCount := int64(1)
+	if forwardAmount.Sign() > 0 {
+		fwdCount = 2
+	}
+
+	gas := new(big.Int).Add(config.gas.fee(jettonSendTransferGas), config.gas.fee(jettonReceiveTransferGas))
+	if legacy := big.NewInt(2 * legacyJettonGasConsumption); gas.Cmp(legacy) < 0 {
+		gas = legacy
+	}
+
+	fees := new(big.Int).Mul(config.forward.fee(msgCell), big.NewInt(fwdCount))
+	fees.Add(fees, gas)
+	fees.Lsh(fees, 1)
+
+	attached := new(big.Int).Add(forwardAmount, fees)
+	return attached.Add(attached, big.NewInt(jettonMinStorage)), nil
+}
+
+func walletGas(w *wallet.Wallet) uint64 {
+	switch w.GetSpec().(type) {
+	case *wallet.SpecV3:
+		return walletV3Gas
+	case *wallet.SpecV4R2:
+		return walletV4Gas
+	case *wallet.SpecV5R1:
+		return walletV5Gas
+	case *wallet.SpecHighloadV2R2, *wallet.SpecHighloadV3:
+		return highloadWalletGas
+	}
+
+	return defaultWalletGas
+}
+
+func (a *TonApiV2) getFeeConfig(ctx context.Context, block *ton.BlockIDExt, addr *address.Address) (*feeConfig, error) {
+	config := &feeConfig{masterchain: addr.Workchain() == address.MasterchainID}
+
+	gasParam, fwdParam := int32(configBasechainGasPrices), int32(configBasechainFwdPrices)
+	if config.masterchain {
+		gasParam, fwdParam = configMasterchainGasPrices, configMasterchainFwdPrices
+	}
+
+	params, err := a.lclient.GetBlockchainConfig(ctx, block, configStoragePrices, gasParam, fwdParam)
+	if err != nil {
+		a.logger.Error("get blockchain config failed", zap.Error(err))
+		return nil, err
+	}
+
+	if config.gas, err = parseGasPrices(params.Get(gasParam)); err != nil {
+		return nil, fmt.Errorf("failed to parse config param %d: %w", gasParam, err)
+	}
+
+	if config.forward, err = parseMsgForwardPrices(params.Get(fwdParam)); err != nil {
+		return nil, fmt.Errorf("failed to parse config param %d: %w", fwdParam, err)
+	}
+
+	if config.storage, err = parseStoragePrices(params.Get(configStoragePrices), uint32(time.Now().Unix())); err != nil {
+		return nil, fmt.Errorf("failed to parse config param %d: %w", configStoragePrices, err)
+	}
+
+	return config, nil
+}
+
+// parseGasPrices reads GasLimitsPrices, a flat price for the first gas units optionally prefixes the prices.
+func parseGasPrices(c *cell.Cell) (gasPrices, error) {
+	var prices gasPrices
+	if c == nil {
+		return prices, fmt.Errorf("param is missing")
+	}
+
+	s := c.BeginParse()
+	tag, err := s.LoadUInt(8)
+	if err != nil {
+		return prices, err
+	}
+
+	if tag == tagGasFlatPfx {
+		if prices.flatGasLimit, err = s.LoadUInt(64); err != nil {
+			return prices, err
+		}
+		if prices.flatGasPrice, err = s.LoadUInt(64); err != nil {
+			return prices, err
+		}
+		if tag, err = s.LoadUInt(8); err != nil {
+			return prices, err
+		}
+	}
+
+	if tag != tagGasPrices && tag != tagGasPricesExt {
+		return prices, fmt.Errorf("unknown gas prices tag %x", tag)
+	}
+
+	if prices.gasPrice, err = s.LoadUInt(64); err != nil {
+		return prices, err
+	}
+
+	return prices, nil
+}
+
+func parseMsgForwardPrices(c *cell.Cell) (msgForwardPrices, error) {
+	var prices msgForwardPrices
+	if c == nil {
+		return prices, fmt.Errorf("param is missing")
+	}
+
+	s := c.BeginParse()
+	tag, err := s.LoadUInt(8)
+	if err != nil {
+		return prices, err
+	}
+
+	if tag != tagMsgForwardPrices {
+		return prices, fmt.Errorf("unknown forward prices tag %x", tag)
+	}
+
+	for _, price := range []*uint64{&prices.lumpPrice, &prices.bitPrice, &prices.cellPrice} {
+		if *price, err = s.LoadUInt(64); err != nil {
+			return prices, err
+		}
+	}
+
+	return prices, nil
+}
+
+// parseStoragePrices returns the storage prices in effect at now from the prices keyed by the time they apply since.
+func parseStoragePrices(c *cell.Cell, now uint32) (storagePrices, error) {
+	var current storagePrices
+	if c == nil {
+		return current, fmt.Errorf("param is missing")
+	}
+
+	dict, err := c.BeginParse().ToDict(32)
+	if err != nil {
+		return current, err
+	}
+
+	entries, err := dict.LoadAll()
+	if err != nil {
+		return current, err
+	}
+
+	found := false
+	for _, entry := range entries {
+		var prices storagePrices
+
+		tag, err := entry.Value.LoadUInt(8)
+		if err != nil {
+			return current, err
+		}
+
+		if tag != tagStoragePrices {
+			return current, fmt.Errorf("unknown storage prices tag %x", tag)
+		}
+
+		utimeSince, err := entry.Value.LoadUInt(32)
+		if err != nil {
+			return current, err
+		}
+		prices.utimeSince = uint32(utimeSince)
+
+		for _, price := range []*uint64{&prices.bitPrice, &prices.cellPrice, &prices.mcBitPrice, &prices.mcCellPrice} {
+			if *price, err = entry.Value.LoadUInt(64); err != nil {
+				return current, err
+			}
+		}
+
+		if prices.utimeSince <= now && (!found || prices.utimeSince > current.utimeSince) {
+			current, found = prices, true
+		}
+	}
+
+	if !found {
+		return current, fmt.Errorf("no storage prices in effect")
+	}
+
+	return current, nil
+}
+
+func (p gasPrices) fee(gas uint64) *big.Int {
+	if gas <= p.flatGasLimit {
+		return new(big.Int).SetUint64(p.flatGasPrice)
+	}
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas-p.flatGasLimit), new(big.Int).SetUint64(p.gasPrice))
+	return fee.Add(ceilPrice(fee), new(big.Int).SetUint64(p.flatGasPrice))
+}
+
+// fee is the forward fee of a message, its root cell is not paid for.
+func (p msgForwardPrices) fee(msg *cell.Cell) *big.Int {
+	bits, cells := cellStats(msg)
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(bits), new(big.Int).SetUint64(p.bitPrice))
+	fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(cells), new(big.Int).SetUint64(p.cellPrice)))
+
+	return fee.Add(ceilPrice(fee), new(big.Int).SetUint64(p.lumpPrice))
+}
+
+func (p storagePrices) fee(bits, cells *big.Int, seconds int64, masterchain bool) *big.Int {
+	if seconds <= 0 || bits == nil || cells == nil {
+		return new(big.Int)
+	}
+
+	bitPrice, cellPrice := p.bitPrice, p.cellPrice
+	if masterchain {
+		bitPrice, cellPrice = p.mcBitPrice, p.mcCellPrice
+	}
+
+	fee := new(big.Int).Mul(bits, new(big.Int).SetUint64(bitPrice))
+	fee.Add(fee, new(big.Int).Mul(cells, new(big.Int).SetUint64(cellPrice)))
+	fee.Mul(fee, big.NewInt(seconds))
+
+	return ceilPrice(fee)
+}
+
+// ceilPrice converts an amount in 1/65536 nanoton to nanotons, rounding up.
+func ceilPrice(v *big.Int) *big.Int {
+	v = new(big.Int).Add(v, big.NewInt(1<<priceFractionBits-1))
+	return v.Rsh(v, priceFractionBits)
+}
+
+// cellStats counts the bits and the distinct cells referenced by root, root itself excluded.
+func cellStats(root *cell.Cell) (bits, cells uint64) {
+	seen := make(map[string]bool)
+
+	var walk func(c *cell.Cell)
+	walk = func(c *cell.Cell) {
+		for i := 0; i < int(c.RefsNum()); i++ {
+			ref, err := c.PeekRef(i)
+			if err != nil {
+				continue
+			}
+
+			hash := string(ref.Hash())
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			bits += uint64(ref.BitsSize())
+			cells++
+			walk(ref)
+		}
+	}
+	walk(root)
+
+	return bits, cells
+}
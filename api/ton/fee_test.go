@@ -0,0 +1,155 @@
+package ton_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// mainnet config params: storage prices, gas prices of the masterchain and the basechain, then their
+// forward prices
+const (
+	configParam18 = "b5ee9c7241010101002900004dd06600000000000000000000000080000000000000fa00000000000001f4000000000003d09040954b559b"
+	configParam20 = "b5ee9c7241010101004c000094d1000000000000006400000000000f4240de000000002710000000000000000f42400000000002160ec0000000000000271000000000002625a0000000174876e800000000e8d4a510006cb91161"
+	configParam21 = "b5ee9c7241010101004c000094d100000000000000640000000000009c40de000000000190000000000000000f424000000000000f4240000000000000271000000000009896800000000005f5e100000000003b9aca00191ef071"
+	configParam24 = "b5ee9c72410101010023000042ea000000000098968000000000271000000000000f4240000000018000555555557da3432f"
+	configParam25 = "b5ee9c72410101010023000042ea0000000000061a800000000001900000000000009c400000000180005555555576254774"
+)
+
+func configParam(t *testing.T, boc string) *cell.Cell {
+	t.Helper()
+
+	data, err := hex.DecodeString(boc)
+	if err != nil {
+		t.Fatalf("decode config param: %v", err)
+	}
+
+	c, err := cell.FromBOC(data)
+	if err != nil {
+		t.Fatalf("FromBOC() error = %v", err)
+	}
+
+	return c
+}
+
+// storagePrices builds config param 18 from the basechain prices of a bit and a cell, keyed by the time they apply from.
+func storagePrices(t *testing.T, prices map[uint32][2]uint64) *cell.Cell {
+	t.Helper()
+
+	dict := cell.NewDict(32)
+	for since, p := range prices {
+		value := cell.BeginCell().MustStoreUInt(0xcc, 8).MustStoreUInt(uint64(since), 32).
+			MustStoreUInt(p[0], 64).MustStoreUInt(p[1], 64).MustStoreUInt(p[0]*1000, 64).MustStoreUInt(p[1]*1000, 64).EndCell()
+		if err := dict.SetIntKey(big.NewInt(int64(since)), value); err != nil {
+			t.Fatalf("SetIntKey() error = %v", err)
+		}
+	}
+
+	return dict.AsCell()
+}
+
+func TestGasFee(t *testing.T) {
+	tests := []struct {
+		name  string
+		param string
+		gas   uint64
+		want  int64
+	}{
+		{"basechain below the flat limit", configParam21, 50, 40_000},
+		{"basechain at the flat limit", configParam21, 100, 40_000},
+		{"basechain one unit above the flat limit", configParam21, 101, 40_400},
+		{"basechain wallet v4 transfer", configParam21, 4_700, 1_880_000},
+		{"masterchain wallet v4 transfer", configParam20, 4_700, 47_000_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ton.GasFee(configParam(t, tt.param), tt.gas)
+			if err != nil {
+				t.Fatalf("GasFee() error = %v", err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("GasFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ton.GasFee(configParam(t, configParam25), 4_700); err == nil {
+		t.Errorf("GasFee() of forward prices error = nil, want an unknown tag")
+	}
+	if _, err := ton.GasFee(nil, 4_700); err == nil {
+		t.Errorf("GasFee() of a missing param error = nil, want an error")
+	}
+}
+
+func TestForwardFee(t *testing.T) {
+	body := cell.BeginCell().MustStoreUInt(0, 64).MustStoreUInt(0, 64).MustStoreUInt(0, 64).MustStoreUInt(0, 64).EndCell()
+
+	tests := []struct {
+		name  string
+		param string
+		msg   *cell.Cell
+		want  int64
+	}{
+		{"root cell only", configParam25, body, 400_000},
+		{"body in a ref", configParam25, cell.BeginCell().MustStoreRef(body).EndCell(), 400_000 + 256*400 + 40_000},
+		{"same cell twice", configParam25, cell.BeginCell().MustStoreRef(body).MustStoreRef(body).EndCell(), 400_000 + 256*400 + 40_000},
+		{"masterchain", configParam24, cell.BeginCell().MustStoreRef(body).EndCell(), 10_000_000 + 256*10_000 + 1_000_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ton.ForwardFee(configParam(t, tt.param), tt.msg)
+			if err != nil {
+				t.Fatalf("ForwardFee() error = %v", err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("ForwardFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ton.ForwardFee(configParam(t, configParam21), body); err == nil {
+		t.Errorf("ForwardFee() of gas prices error = nil, want an unknown tag")
+	}
+}
+
+func TestStorageFee(t *testing.T) {
+	changing := storagePrices(t, map[uint32][2]uint64{0: {1, 500}, 2_000_000_000: {2, 1_000}})
+
+	tests := []struct {
+		name        string
+		param       *cell.Cell
+		now         uint32
+		seconds     int64
+		masterchain bool
+		want        int64
+	}{
+		// (1000 bits * 1 + 10 cells * 500) * 86400 / 65536 rounded up
+		{"basechain day", configParam(t, configParam18), 1_700_000_000, 86_400, false, 7_911},
+		{"masterchain day", configParam(t, configParam18), 1_700_000_000, 86_400, true, 7_910_157},
+		{"paid in the same second", configParam(t, configParam18), 1_700_000_000, 0, false, 0},
+		{"prices before a change", changing, 1_700_000_000, 65_536, false, 6_000},
+		{"prices after a change", changing, 2_100_000_000, 65_536, false, 12_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ton.StorageFee(tt.param, tt.now, 1_000, 10, tt.seconds, tt.masterchain)
+			if err != nil {
+				t.Fatalf("StorageFee() error = %v", err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("StorageFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	future := storagePrices(t, map[uint32][2]uint64{2_000_000_000: {1, 500}})
+	if _, err := ton.StorageFee(future, 1_700_000_000, 1_000, 10, 86_400, false); err == nil {
+		t.Errorf("StorageFee() without prices in effect error = nil, want an error")
+	}
+}
@@ -0,0 +1,105 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"go.uber.org/zap"
+)
+
+// LockupWalletInput describes a lockup wallet holding a vesting allocation for the owner of a wallet.
+type LockupWalletInput struct {
+	AppId   string
+	Network string
+	// wallet of the signer owning the lockup wallet, both wallets share the key
+	OwnerAddress string
+	Config       wallet.ConfigLockup
+}
+
+// LockupBalances are the balances of a lockup wallet.
+type LockupBalances struct {
+	Total *big.Int
+	// funds that can only be sent to the allowed destinations yet
+	Restricted *big.Int
+	// funds that cannot be sent yet
+	Locked *big.Int
+	// funds that can be sent anywhere
+	Liquid *big.Int
+}
+
+// GetLockupWalletAddress returns the address of the lockup wallet of the owner with the config. The wallet
+// is deployed by its first transfer, the config has to be registered with WithLockupConfig to send it.
+func (a *TonApiV2) GetLockupWalletAddress(ctx context.Context, input *LockupWalletInput) (string, error) {
+	signer, err := a.signerProvider.Provide(ctx, input.AppId, input.Network, input.OwnerAddress)
+	if err != nil {
+		a.logger.Error("get signer failed", zap.Error(err))
+		return "", err
+	}
+
+	w, err := wallet.FromSigner(ctx, a.lclient, signer, input.Config)
+	if err != nil {
+		a.logger.Error("get lockup wallet failed", zap.Error(err))
+		return "", err
+	}
+
+	return w.WalletAddress().String(), nil
+}
+
+// GetLockupBalances returns the liquid, restricted and locked balances of the lockup wallet.
+func (a *TonApiV2) GetLockupBalances(ctx context.Context, walletAddress string) (*LockupBalances, error) {
+	addr, err := address.ParseAddr(walletAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	balances, err := wallet.GetLockupBalances(ctx, a.lclient, addr)
+	if err != nil {
+		a.logger.Error("get lockup balances failed", zap.Error(err), zap.String("address", walletAddress))
+		return nil, err
+	}
+
+	return &LockupBalances{
+		Total:      balances.Total.Nano(),
+		Restricted: balances.Restricted.Nano(),
+		Locked:     balances.Locked.Nano(),
+		Liquid:     balances.Liquid.Nano(),
+	}, nil
+}
+
+// checkLockupTransfer makes sure a lockup wallet can spend the TON the message carries, only liquid funds
+// can be sent anywhere and restricted funds can also be sent to the allowed destinations.
+func (a *TonApiV2) checkLockupTransfer(ctx context.Context, w *wallet.Wallet, message *wallet.Message) error {
+	spec, ok := w.GetSpec().(*wallet.SpecLockup)
+	if !ok {
+		return nil
+	}
+
+	balances, err := spec.GetBalances(ctx)
+	if err != nil {
+		a.logger.Error("get lockup balances failed", zap.Error(err))
+		return err
+	}
+
+	available := balances.Liquid.Nano()
+	if spec.IsAllowedDestination(message.InternalMessage.DstAddr) {
+		available = new(big.Int).Add(available, balances.Restricted.Nano())
+	}
+
+	amount := message.InternalMessage.Amount.Nano()
+	if available.Cmp(amount) < 0 {
+		a.logger.Info("insufficient lockup balance",
+			zap.String("available", available.String()),
+			zap.String("amount", amount.String()),
+			zap.String("locked", balances.Locked.Nano().String()),
+			zap.String("restricted", balances.Restricted.Nano().String()),
+		)
+		return types.WrapErr(types.ErrInsufficientBalance,
+			fmt.Errorf("lockup wallet can send %s to %s", available.String(), message.InternalMessage.DstAddr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package ton
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+const (
+	// TON forwarded to the new owner by default, enough for the item to send ownership_assigned
+	nftForwardAmount = 1
+	// TON attached on top of the forward amount, the item keeps what it needs for storage and
+	// returns the rest with excesses
+	nftTransferAmount = 50_000_000
+)
+
+// NftContent is the content of an NFT item or collection.
+type NftContent struct {
+	// link to the off-chain metadata, empty when the content is on chain only
+	URI string
+	// attributes stored on chain
+	Name        string
+	Description string
+	Image       string
+}
+
+// NftData is the state of an NFT item returned by get_nft_data.
+type NftData struct {
+	// the item was deployed by its collection, items not initialized have no owner
+	Initialized       bool
+	Index             *big.Int
+	CollectionAddress string
+	OwnerAddress      string
+	// full content of the item, combined with the common content of the collection
+	Content *NftContent
+}
+
+// NftCollectionData is the state of an NFT collection returned by get_collection_data.
+type NftCollectionData struct {
+	NextItemIndex *big.Int
+	OwnerAddress  string
+	Content       *NftContent
+}
+
+// GetNftData returns the owner, the collection and the content of the NFT item.
+func (a *TonApiV2) GetNftData(ctx context.Context, nftAddress string) (*NftData, error) {
+	addr, err := address.ParseAddr(nftAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return nil, err
+	}
+
+	item, err := nft.NewItemClient(a.lclient, addr).GetNFTDataAtBlock(ctx, block)
+	if err != nil {
+		a.logger.Error("get nft data failed", zap.Error(err), zap.String("address", nftAddress))
+		return nil, err
+	}
+
+	data := &NftData{
+		Initialized: item.Initialized,
+		Index:       item.Index,
+		Content:     &NftContent{},
+	}
+
+	if item.OwnerAddress != nil && item.OwnerAddress.Type() == address.StdAddress {
+		data.OwnerAddress = item.OwnerAddress.String()
+	}
+
+	content := item.Content
+	if item.CollectionAddress != nil && item.CollectionAddress.Type() == address.StdAddress {
+		data.CollectionAddress = item.CollectionAddress.String()
+
+		// items of a collection only keep their individual part of the content
+		content, err = nft.NewCollectionClient(a.lclient, item.CollectionAddress).GetNFTContentAtBlock(ctx, item.Index, item.Content, block)
+		if err != nil {
+			a.logger.Error("get nft content failed", zap.Error(err), zap.String("address", nftAddress))
+			return nil, err
+		}
+	}
+
+	if content != nil {
+		data.Content = newNftContent(content)
+	}
+
+	return data, nil
+}
+
+// GetNftCollectionData returns the owner, the content and the next item index of the NFT collection.
+func (a *TonApiV2) GetNftCollectionData(ctx context.Context, collectionAddress string) (*NftCollectionData, error) {
+	addr, err := address.ParseAddr(collectionAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	collection, err := nft.NewCollectionClient(a.lclient, addr).GetCollectionData(ctx)
+	if err != nil {
+		a.logger.Error("get collection data failed", zap.Error(err), zap.String("address", collectionAddress))
+		return nil, err
+	}
+
+	data := &NftCollectionData{
+		NextItemIndex: collection.NextItemIndex,
+		Content:       &NftContent{},
+	}
+
+	if collection.OwnerAddress != nil && collection.OwnerAddress.Type() == address.StdAddress {
+		data.OwnerAddress = collection.OwnerAddress.String()
+	}
+
+	if collection.Content != nil {
+		data.Content = newNftContent(collection.Content)
+	}
+
+	return data, nil
+}
+
+// buildNftTransfer builds the transfer message of the NFT item input.ContractAddress to input.ToAddress,
+// the wallet must own the item.
+func (a *TonApiV2) buildNftTransfer(ctx context.Context, w *wallet.Wallet, input *types.TransferInput) (_ *wallet.Message, err error) {
+	itemAddr, err := address.ParseAddr(input.ContractAddress)
+	if err != nil {
+		a.logger.Error("ParseAddr failed", zap.Error(err), zap.String("address", input.ContractAddress))
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	item, err := nft.NewItemClient(a.lclient, itemAddr).GetNFTData(ctx)
+	if err != nil {
+		a.logger.Error("get nft data failed", zap.Error(err), zap.String("address", input.ContractAddress))
+		return nil, err
+	}
+
+	if item.OwnerAddress == nil || !item.OwnerAddress.Equals(w.WalletAddress()) {
+		a.logger.Error("nft is not owned by the wallet",
+			zap.String("nft", input.ContractAddress),
+			zap.String("wallet", w.WalletAddress().String()),
+		)
+
+		return nil, types.WrapErr(types.ErrNotOwner, nil)
+	}
+
+	opts := input.Nft
+	if opts == nil {
+		opts = &types.NftTransferOptions{}
+	}
+
+	// the forward payload is not optional, it is empty when there is nothing to forward
+	forwardPayload := cell.BeginCell().EndCell()
+	if len(opts.ForwardPayload) > 0 {
+		forwardPayload, err = cell.FromBOC(opts.ForwardPayload)
+		if err != nil {
+			a.logger.Error("parse forward payload failed", zap.Error(err))
+			return nil, err
+		}
+	} else if input.Memo != "" {
+		forwardPayload, err = wallet.CreateCommentCell(input.Memo)
+		if err != nil {
+			a.logger.Error("CreateCommentCell failed", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	var customPayload *cell.Cell
+	if len(opts.CustomPayload) > 0 {
+		customPayload, err = cell.FromBOC(opts.CustomPayload)
+		if err != nil {
+			a.logger.Error("parse custom payload failed", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	to, err := address.ParseAddr(input.ToAddress)
+	if err != nil {
+		a.logger.Error("ParseAddr failed", zap.Error(err))
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	responseTo := w.WalletAddress()
+	if opts.ResponseDestination != "" {
+		responseTo, err = address.ParseAddr(opts.ResponseDestination)
+		if err != nil {
+			a.logger.Error("ParseAddr failed", zap.Error(err), zap.String("address", opts.ResponseDestination))
+			return nil, types.WrapErr(types.ErrInvalidAddress, err)
+		}
+	}
+
+	forwardAmount, attached, err := nftTransferAmounts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := tlb.ToCell(nft.TransferPayload{
+		QueryID:             randomQueryID(),
+		NewOwner:            to,
+		ResponseDestination: responseTo,
+		CustomPayload:       customPayload,
+		ForwardAmount:       tlb.FromNanoTON(forwardAmount),
+		ForwardPayload:      forwardPayload,
+	})
+	if err != nil {
+		a.logger.Error("build nft transfer payload failed", zap.Error(err))
+		return nil, err
+	}
+
+	return &wallet.Message{
+		Mode: wallet.PayGasSeparately + wallet.IgnoreErrors,
+		InternalMessage: &tlb.InternalMessage{
+			IHRDisabled: true,
+			// the item bounces the transfer back when it fails
+			Bounce:  true,
+			DstAddr: itemAddr,
+			Amount:  tlb.FromNanoTON(attached),
+			Body:    body,
+		},
+	}, nil
+}
+
+// nftTransferAmounts returns the TON forwarded to the new owner and the TON attached to the transfer. The
+// item bounces a transfer that cannot pay for the forward amount and its own gas, the attached amount cannot
+// be less.
+func nftTransferAmounts(opts *types.NftTransferOptions) (forward, attached *big.Int, err error) {
+	if (opts.ForwardAmount != nil && opts.ForwardAmount.Sign() < 0) || (opts.AttachedAmount != nil && opts.AttachedAmount.Sign() < 0) {
+		return nil, nil, types.WrapErr(types.ErrInvalidAmount, fmt.Errorf("forward and attached amounts must not be negative"))
+	}
+
+	forward = big.NewInt(nftForwardAmount)
+	if opts.ForwardAmount != nil {
+		forward = opts.ForwardAmount
+	}
+
+	attached = new(big.Int).Add(forward, big.NewInt(nftTransferAmount))
+	if opts.AttachedAmount != nil {
+		if opts.AttachedAmount.Cmp(attached) < 0 {
+			return nil, nil, types.WrapErr(types.ErrInvalidAmount,
+				fmt.Errorf("attached amount %s is below the %s the item needs", opts.AttachedAmount, attached))
+		}
+		attached = opts.AttachedAmount
+	}
+
+	return forward, attached, nil
+}
+
+func newNftContent(content nft.ContentAny) *NftContent {
+	switch c := content.(type) {
+	case *nft.ContentOffchain:
+		return &NftContent{URI: c.URI}
+	case *nft.ContentOnchain:
+		return newOnchainNftContent(c)
+	case *nft.ContentSemichain:
+		nc := newOnchainNftContent(&c.ContentOnchain)
+		nc.URI = c.URI
+		return nc
+	}
+
+	return &NftContent{}
+}
+
+func newOnchainNftContent(c *nft.ContentOnchain) *NftContent {
+	return &NftContent{
+		Name:        c.GetAttribute("name"),
+		Description: c.GetAttribute("description"),
+		Image:       c.GetAttribute("image"),
+	}
+}
+
+// randomQueryID returns a random query id, as tonutils does for jetton and NFT transfers.
+func randomQueryID() uint64 {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return binary.LittleEndian.Uint64(buf)
+}
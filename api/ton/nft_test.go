@@ -0,0 +1,54 @@
+package ton_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/openweb3-io/blockchain/api/types"
+)
+
+func TestNftTransferAmounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         types.NftTransferOptions
+		wantForward  int64
+		wantAttached int64
+		wantErr      bool
+	}{
+		{name: "defaults", wantForward: 1, wantAttached: 50_000_001},
+		{name: "no notification", opts: types.NftTransferOptions{ForwardAmount: big.NewInt(0)}, wantForward: 0, wantAttached: 50_000_000},
+		{name: "forward amount", opts: types.NftTransferOptions{ForwardAmount: big.NewInt(10_000_000)}, wantForward: 10_000_000, wantAttached: 60_000_000},
+		{name: "attached amount", opts: types.NftTransferOptions{AttachedAmount: big.NewInt(100_000_000)}, wantForward: 1, wantAttached: 100_000_000},
+		{name: "attached amount at the minimum", opts: types.NftTransferOptions{AttachedAmount: big.NewInt(50_000_001)}, wantForward: 1, wantAttached: 50_000_001},
+		{name: "attached amount below the item gas", opts: types.NftTransferOptions{AttachedAmount: big.NewInt(50_000_000)}, wantErr: true},
+		{
+			name:    "attached amount below the forward amount",
+			opts:    types.NftTransferOptions{ForwardAmount: big.NewInt(1_000_000_000), AttachedAmount: big.NewInt(500_000_000)},
+			wantErr: true,
+		},
+		{name: "negative forward amount", opts: types.NftTransferOptions{ForwardAmount: big.NewInt(-1)}, wantErr: true},
+		{name: "negative attached amount", opts: types.NftTransferOptions{AttachedAmount: big.NewInt(-1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forward, attached, err := ton.NftTransferAmounts(&tt.opts)
+			if tt.wantErr {
+				var typed *types.Error
+				if !errors.As(err, &typed) || typed.Code != types.ErrInvalidAmount.Code {
+					t.Errorf("NftTransferAmounts() error = %v, want %s", err, types.ErrInvalidAmount.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NftTransferAmounts() error = %v", err)
+			}
+
+			if forward.Int64() != tt.wantForward || attached.Int64() != tt.wantAttached {
+				t.Errorf("NftTransferAmounts() = %s, %s, want %d, %d", forward, attached, tt.wantForward, tt.wantAttached)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ton
+
+import (
+	"time"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+)
+
+const defaultHighloadMessageTTL = 5 * 60
+
+type Options struct {
+	// version used for wallets that are not deployed yet, their code cannot be inspected
+	defaultWalletVersion wallet.VersionConfig
+	// global id of the network signed into V5R1 messages
+	networkGlobalID int32
+	// config used when a wallet turns out to be a highload V3 wallet, overrides the query id allocator
+	highloadV3Config *wallet.ConfigHighloadV3
+	// timeout of highload V3 messages in seconds
+	highloadMessageTTL uint32
+	// nil until a query id store is configured
+	queryIDs *queryIDAllocator
+	// configs of the lockup wallets by raw address, their addresses are derived from them
+	lockupConfigs map[string]wallet.ConfigLockup
+}
+
+type Option func(*Options)
+
+// WithDefaultWalletVersion sets the version assumed for undeployed wallets, V4R2 by default.
+func WithDefaultWalletVersion(v wallet.VersionConfig) Option {
+	return func(o *Options) {
+		o.defaultWalletVersion = v
+	}
+}
+
+// WithNetworkGlobalID sets the network V5R1 messages are signed for, wallet.MainnetGlobalID by default.
+func WithNetworkGlobalID(v int32) Option {
+	return func(o *Options) {
+		o.networkGlobalID = v
+	}
+}
+
+// WithHighloadV3Config sets the config, including the query id source, used for highload V3 wallets.
+func WithHighloadV3Config(v wallet.ConfigHighloadV3) Option {
+	return func(o *Options) {
+		o.highloadV3Config = &v
+	}
+}
+
+// WithHighloadMessageTTL sets how long highload V3 messages stay valid, 5 minutes by default.
+// Query ids are remembered by the contract for up to two timeouts.
+func WithHighloadMessageTTL(v time.Duration) Option {
+	return func(o *Options) {
+		o.highloadMessageTTL = uint32(v / time.Second)
+	}
+}
+
+// WithQueryIDStore sets where the query ids of highload V3 wallets are persisted, highload V3 wallets
+// cannot be used without it or WithHighloadV3Config.
+func WithQueryIDStore(v QueryIDStore) Option {
+	return func(o *Options) {
+		o.queryIDs = newQueryIDAllocator(v)
+	}
+}
+
+// WithLockupConfig sets the config the lockup wallet at addr was created with, lockup wallets cannot
+// be opened without it.
+func WithLockupConfig(addr *address.Address, config wallet.ConfigLockup) Option {
+	return func(o *Options) {
+		o.lockupConfigs[rawAddress(addr)] = config
+	}
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		defaultWalletVersion: wallet.V4R2,
+		networkGlobalID:      wallet.MainnetGlobalID,
+		highloadMessageTTL:   defaultHighloadMessageTTL,
+		lockupConfigs:        map[string]wallet.ConfigLockup{},
+	}
+}
@@ -0,0 +1,452 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+const (
+	// messages a highload V3 wallet sends from a single action list, larger batches are packed
+	// into chains of messages to the wallet itself
+	maxPayoutBatchSize = 253
+	// op of the internal message a highload V3 wallet sends to itself with packed actions
+	opHighloadInternalTransfer = 0xae42e5a4
+	// seconds a masterchain block is past the expiry of a batch before the batch is expired, shard blocks
+	// accepting it may be committed to the masterchain later
+	payoutExpiryMargin = 60
+)
+
+type PayoutStatus int
+
+const (
+	// the message is not processed yet, or only some of its packed actions are
+	PayoutPending PayoutStatus = iota
+	// every transaction of the batch is done, messages that were not sent never will be
+	PayoutProcessed
+	// a masterchain block generated well after the message expired did not process it, its transfers can be
+	// paid out again
+	PayoutExpired
+)
+
+// PayoutInput pays out TON and jetton transfers from a highload V3 wallet. Only the destination, amount,
+// token, memo and uid of the transfers are used, they are sent from FromAddress.
+type PayoutInput struct {
+	AppId       string
+	Network     string
+	FromAddress string
+	Transfers   []*types.TransferInput
+}
+
+// PayoutBatch is an external message of a payout, it is safe to broadcast again until it expires.
+type PayoutBatch struct {
+	WalletAddress string
+	QueryID       HighloadQueryID
+	CreatedAt     int64
+	// unix time the wallet stops accepting the message
+	ExpireAt int64
+	// normalized hash of the external message
+	Hash     []byte
+	Payload  []byte
+	Messages []*PayoutMessage
+}
+
+// PayoutMessage is the internal message the wallet sends for a transfer of the batch, it is found among the
+// out messages of the wallet by destination, attached TON and body. For jetton transfers the destination
+// is the jetton wallet of the sender.
+type PayoutMessage struct {
+	Transfer    *types.TransferInput
+	Destination string
+	Amount      *big.Int
+	BodyHash    []byte
+}
+
+type PayoutBatchStatus struct {
+	Status PayoutStatus
+	// deliveries of the batch messages in batch order
+	Deliveries []*PayoutDelivery
+}
+
+// PayoutDelivery tells whether the wallet sent the message of a transfer. For jetton transfers it only
+// means the jetton wallet of the sender got the transfer request.
+type PayoutDelivery struct {
+	Message *PayoutMessage
+	Sent    bool
+	// wallet transaction that sent the message
+	TxHash []byte
+	Lt     uint64
+}
+
+// BatchPayout packs the transfers into highload V3 messages of up to 253 transfers each, every message
+// taking the next query id of the wallet, and broadcasts them. The batches sent so far are returned along
+// with any error, their status is checked with GetPayoutStatus.
+func (a *TonApiV2) BatchPayout(ctx context.Context, input *PayoutInput) ([]*PayoutBatch, error) {
+	st := time.Now()
+	defer func() {
+		a.logger.Info("batch payout", zap.Duration("cost", time.Since(st)), zap.Int("transfers", len(input.Transfers)))
+	}()
+
+	if len(input.Transfers) == 0 {
+		return nil, errors.New("no transfers to pay out")
+	}
+
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	w, err := a.getWallet(ctx, &types.TransferInput{
+		AppId:       input.AppId,
+		Network:     input.Network,
+		FromAddress: input.FromAddress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := w.GetSpec().(*wallet.SpecHighloadV3); !ok {
+		return nil, fmt.Errorf("wallet %s is not a highload V3 wallet: %w", input.FromAddress, wallet.ErrUnsupportedWalletVersion)
+	}
+
+	messages := make([]*wallet.Message, len(input.Transfers))
+	for i, transfer := range input.Transfers {
+		messages[i], err = a.buildMessage(ctx, w, transfer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build transfer %d: %w", i, err)
+		}
+	}
+
+	if err := a.checkPayoutBalances(ctx, w, input.Transfers, messages); err != nil {
+		return nil, err
+	}
+
+	var batches []*PayoutBatch
+	for start := 0; start < len(messages); start += maxPayoutBatchSize {
+		end := min(start+maxPayoutBatchSize, len(messages))
+
+		batch, err := a.buildPayoutBatch(ctx, w, messages[start:end], input.Transfers[start:end])
+		if err != nil {
+			return batches, err
+		}
+
+		if _, err := a.client.SendMessage(ctx, batch.Payload); err != nil {
+			a.logger.Error("SendBlockchainMessage failed", zap.Error(err), zap.Uint32("queryId", uint32(batch.QueryID)))
+			return batches, err
+		}
+
+		a.logger.Info("SendBlockchainMessage succeeded",
+			zap.String("hash", hex.EncodeToString(batch.Hash)),
+			zap.Uint32("queryId", uint32(batch.QueryID)),
+			zap.Int("messages", len(batch.Messages)),
+		)
+
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// checkPayoutBalances makes sure the wallet holds the TON the messages carry and the jettons of every transfer,
+// the gas of the wallet transactions is not included.
+func (a *TonApiV2) checkPayoutBalances(ctx context.Context, w *wallet.Wallet, transfers []*types.TransferInput, messages []*wallet.Message) error {
+	totalTonAmount := new(big.Int)
+	for _, message := range messages {
+		totalTonAmount.Add(totalTonAmount, message.InternalMessage.Amount.Nano())
+	}
+
+	jettonAmounts := make(map[string]*big.Int)
+	for _, transfer := range transfers {
+		if !isJettonTransfer(transfer) {
+			continue
+		}
+
+		if jettonAmounts[transfer.ContractAddress] == nil {
+			jettonAmounts[transfer.ContractAddress] = new(big.Int)
+		}
+		jettonAmounts[transfer.ContractAddress].Add(jettonAmounts[transfer.ContractAddress], transfer.Amount)
+	}
+
+	balance, err := a.getBalance(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	if balance.Nano().Cmp(totalTonAmount) < 0 {
+		a.logger.Info("insufficient ton balance",
+			zap.String("balance", balance.Nano().String()),
+			zap.String("totalAmount", totalTonAmount.String()),
+		)
+		return types.WrapErr(types.ErrInsufficientBalance, errors.New("insufficient balance of TON"))
+	}
+
+	for contractAddress, amount := range jettonAmounts {
+		contractAddr, err := address.ParseAddr(contractAddress)
+		if err != nil {
+			a.logger.Error("ParseAddr failed", zap.Error(err), zap.String("address", contractAddress))
+			return err
+		}
+
+		tokenWallet, err := jetton.NewJettonMasterClient(a.lclient, contractAddr).GetJettonWallet(ctx, w.WalletAddress())
+		if err != nil {
+			a.logger.Error("GetJettonWallet failed", zap.Error(err), zap.String("address", w.WalletAddress().String()))
+			return err
+		}
+
+		tokenBalance, err := tokenWallet.GetBalance(ctx)
+		if err != nil {
+			a.logger.Error("GetBalance failed", zap.Error(err))
+			return err
+		}
+
+		if tokenBalance.Cmp(amount) < 0 {
+			a.logger.Info("insufficient jetton balance",
+				zap.String("balance", tokenBalance.String()),
+				zap.String("totalAmount", amount.String()),
+				zap.String("contract", contractAddress),
+			)
+			return types.WrapErr(types.ErrInsufficientBalance, fmt.Errorf("insufficient balance of jetton %s", contractAddress))
+		}
+	}
+
+	return nil
+}
+
+func (a *TonApiV2) buildPayoutBatch(ctx context.Context, w *wallet.Wallet, messages []*wallet.Message, transfers []*types.TransferInput) (*PayoutBatch, error) {
+	ext, err := w.BuildExternalMessageForMany(ctx, messages)
+	if err != nil {
+		a.logger.Error("BuildExternalMessage failed", zap.Error(err))
+		return nil, err
+	}
+
+	msgCell, err := tlb.ToCell(ext)
+	if err != nil {
+		a.logger.Error("ToCell failed", zap.Error(err))
+		return nil, err
+	}
+
+	queryID, createdAt, ttl, err := parseHighloadV3Body(ext.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &PayoutBatch{
+		WalletAddress: w.WalletAddress().String(),
+		QueryID:       queryID,
+		CreatedAt:     createdAt,
+		ExpireAt:      createdAt + int64(ttl),
+		Hash:          wrap.NormalizedExtMessageHash(ext),
+		Payload:       msgCell.ToBOCWithFlags(false),
+	}
+
+	for i, message := range messages {
+		batch.Messages = append(batch.Messages, &PayoutMessage{
+			Transfer:    transfers[i],
+			Destination: message.InternalMessage.DstAddr.String(),
+			Amount:      message.InternalMessage.Amount.Nano(),
+			BodyHash:    bodyHash(message.InternalMessage.Body),
+		})
+	}
+
+	return batch, nil
+}
+
+// parseHighloadV3Body reads the query id, creation time and timeout signed into a highload V3 external message.
+func parseHighloadV3Body(body *cell.Cell) (HighloadQueryID, int64, uint32, error) {
+	payload, err := body.BeginParse().LoadRef()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to load highload message payload: %w", err)
+	}
+
+	// subwallet id:32, message:^Cell, send mode:8
+	if _, err := payload.LoadUInt(32 + 8); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to load highload message payload: %w", err)
+	}
+
+	queryID, err := payload.LoadUInt(23)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to load query id: %w", err)
+	}
+
+	createdAt, err := payload.LoadUInt(64)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to load created at: %w", err)
+	}
+
+	ttl, err := payload.LoadUInt(22)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to load timeout: %w", err)
+	}
+
+	return HighloadQueryID(queryID), int64(createdAt), uint32(ttl), nil
+}
+
+// GetPayoutStatus follows the transactions of the batch, including the ones processing packed actions,
+// and reports which of its messages the wallet sent.
+func (a *TonApiV2) GetPayoutStatus(ctx context.Context, batch *PayoutBatch) (*PayoutBatchStatus, error) {
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	addr, err := address.ParseAddr(batch.WalletAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	status := &PayoutBatchStatus{Status: PayoutPending}
+	for _, message := range batch.Messages {
+		status.Deliveries = append(status.Deliveries, &PayoutDelivery{Message: message})
+	}
+
+	tx, err := a.findTransactionByExtMessageHash(ctx, addr, batch.Hash)
+	if err != nil {
+		if !errors.Is(err, ton.ErrTxWasNotFound) {
+			return nil, err
+		}
+
+		processed, blockTime, err := a.isQueryProcessed(ctx, addr, batch.QueryID)
+		if err != nil {
+			return nil, err
+		}
+
+		if processed {
+			return nil, fmt.Errorf("query %d of %s is processed, its transaction is not within the last %d transactions",
+				batch.QueryID, batch.WalletAddress, traceTxScanLimit)
+		}
+
+		// chain time decides, the message may still be in a shard block the masterchain did not commit yet
+		if payoutExpired(batch, blockTime) {
+			status.Status = PayoutExpired
+		}
+
+		return status, nil
+	}
+
+	complete := true
+	for tx != nil {
+		var next *tlb.Transaction
+		next, err = a.deliverPayoutMessages(ctx, addr, tx, status.Deliveries)
+		if err != nil {
+			if !errors.Is(err, ton.ErrTxWasNotFound) {
+				return nil, err
+			}
+
+			// packed actions are still on their way
+			complete = false
+		}
+		tx = next
+	}
+
+	if complete {
+		status.Status = PayoutProcessed
+	}
+
+	return status, nil
+}
+
+// deliverPayoutMessages marks the deliveries of the messages sent by tx and returns the transaction processing
+// the actions the wallet packed into a message to itself, if there is one.
+func (a *TonApiV2) deliverPayoutMessages(ctx context.Context, addr *address.Address, tx *tlb.Transaction, deliveries []*PayoutDelivery) (*tlb.Transaction, error) {
+	if tx.IO.Out == nil {
+		return nil, nil
+	}
+
+	list, err := tx.IO.Out.ToSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list out messages: %w", err)
+	}
+
+	var packed *tlb.InternalMessage
+	for _, m := range list {
+		if m.MsgType != tlb.MsgTypeInternal {
+			continue
+		}
+
+		msg := m.AsInternal()
+		if msg.DstAddr.Equals(addr) && msg.Body != nil {
+			if op, err := msg.Body.BeginParse().LoadUInt(32); err == nil && op == opHighloadInternalTransfer {
+				packed = msg
+				continue
+			}
+		}
+
+		for _, delivery := range deliveries {
+			if delivery.Sent || !delivery.Message.matches(msg) {
+				continue
+			}
+
+			delivery.Sent = true
+			delivery.TxHash = tx.Hash
+			delivery.Lt = tx.LT
+			break
+		}
+	}
+
+	if packed == nil {
+		return nil, nil
+	}
+
+	return a.findTransactionByInMessage(ctx, packed)
+}
+
+// isQueryProcessed tells whether the wallet processed the query in the latest masterchain block, and returns
+// the generation time of the block.
+func (a *TonApiV2) isQueryProcessed(ctx context.Context, addr *address.Address, queryID HighloadQueryID) (bool, int64, error) {
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return false, 0, err
+	}
+
+	data, err := a.lclient.GetBlockData(ctx, block)
+	if err != nil {
+		a.logger.Error("get block data failed", zap.Error(err))
+		return false, 0, err
+	}
+
+	result, err := a.lclient.RunGetMethod(ctx, block, addr, "processed?", uint64(queryID), 0)
+	if err != nil {
+		a.logger.Error("run get method failed", zap.Error(err))
+		return false, 0, err
+	}
+
+	processed, err := result.Int(0)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return processed.Sign() != 0, int64(data.BlockInfo.GenUtime), nil
+}
+
+// payoutExpired tells whether the batch can no longer be accepted by the time of a masterchain block that did
+// not process it.
+func payoutExpired(batch *PayoutBatch, blockTime int64) bool {
+	return blockTime > batch.ExpireAt+payoutExpiryMargin
+}
+
+func (m *PayoutMessage) matches(msg *tlb.InternalMessage) bool {
+	dst, err := address.ParseAddr(m.Destination)
+	if err != nil {
+		return false
+	}
+
+	return msg.DstAddr.Equals(dst) && msg.Amount.Nano().Cmp(m.Amount) == 0 && bytes.Equal(bodyHash(msg.Body), m.BodyHash)
+}
+
+func bodyHash(body *cell.Cell) []byte {
+	if body == nil {
+		body = cell.BeginCell().EndCell()
+	}
+
+	return body.Hash()
+}
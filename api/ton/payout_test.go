@@ -0,0 +1,89 @@
+package ton_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	_ton "github.com/xssnick/tonutils-go/ton"
+	"go.uber.org/zap"
+)
+
+// payoutChain is a chain whose latest masterchain block was generated at now, the highload wallet reports
+// the query as processed or not.
+type payoutChain struct {
+	*fakeChain
+	now       uint32
+	processed bool
+}
+
+func (c *payoutChain) GetBlockData(ctx context.Context, block *_ton.BlockIDExt) (*tlb.Block, error) {
+	b := &tlb.Block{}
+	b.BlockInfo.GenUtime = c.now
+	return b, nil
+}
+
+func (c *payoutChain) RunGetMethod(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address, method string, params ...any) (*_ton.ExecutionResult, error) {
+	if method != "processed?" {
+		return nil, errors.New("unexpected method " + method)
+	}
+
+	processed := big.NewInt(0)
+	if c.processed {
+		processed = big.NewInt(-1)
+	}
+	return _ton.NewExecutionResult([]any{processed}), nil
+}
+
+func TestGetPayoutStatusExpiry(t *testing.T) {
+	highload := newTestAddress(1)
+	batch := &ton.PayoutBatch{
+		WalletAddress: highload.String(),
+		CreatedAt:     1_700_000_000,
+		ExpireAt:      1_700_000_060,
+		Hash:          make([]byte, 32),
+	}
+
+	tests := []struct {
+		name      string
+		now       uint32
+		processed bool
+		want      ton.PayoutStatus
+		wantErr   bool
+	}{
+		{name: "before expiry", now: 1_700_000_030, want: ton.PayoutPending},
+		// a shard block accepting the message may not be committed yet
+		{name: "at expiry", now: 1_700_000_060, want: ton.PayoutPending},
+		{name: "within the margin", now: 1_700_000_120, want: ton.PayoutPending},
+		{name: "past the margin", now: 1_700_000_121, want: ton.PayoutExpired},
+		{name: "processed out of reach", now: 1_700_000_121, processed: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &payoutChain{fakeChain: newFakeChain(), now: tt.now, processed: tt.processed}
+			chain.external(t, highload)
+
+			tonApi := ton.NewTonApiV2(api.NewSignerProvider(), nil, chain, zap.NewNop())
+			status, err := tonApi.GetPayoutStatus(context.Background(), batch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetPayoutStatus() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPayoutStatus() error = %v", err)
+			}
+
+			if status.Status != tt.want {
+				t.Errorf("Status = %v, want %v", status.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+// TON the wallet sends to a plugin it installs or removes by default, the plugin returns what it does not spend
+const pluginNotifyAmount = 50_000_000
+
+const (
+	// ops of the messages a V4 wallet sends to the plugins it installs and removes
+	opPluginInstalled = 0x6e6f7465
+	opPluginRemoved   = 0x64737472
+)
+
+// PluginInput installs or removes a plugin of a V4 wallet, a contract allowed to request funds from it.
+type PluginInput struct {
+	AppId         string
+	Network       string
+	FromAddress   string
+	PluginAddress string
+	// TON sent to the plugin with the request, pluginNotifyAmount when nil
+	Amount *big.Int
+}
+
+// SubscriptionInput sets up recurring payments from a V4 wallet to the beneficiary with a subscription plugin.
+type SubscriptionInput struct {
+	AppId       string
+	Network     string
+	FromAddress string
+	// BOC of the code of the subscription plugin
+	Code        []byte
+	Beneficiary string
+	// TON paid every period
+	Amount *big.Int
+	Period time.Duration
+	// time of the first payment, now when zero
+	StartTime time.Time
+	// how long the plugin waits for a payment it requested before it retries, a period when zero
+	Timeout        time.Duration
+	SubscriptionID uint32
+	// TON sent with the deployment, the first payment and the storage of the plugin,
+	// by default the amount plus pluginNotifyAmount
+	DeployAmount *big.Int
+}
+
+type SubscriptionOutput struct {
+	// normalized hash of the external message, to pass to TrackTransaction
+	Hash          []byte
+	PluginAddress string
+}
+
+// GetPluginList returns the addresses of the plugins installed in the V4 wallet.
+func (a *TonApiV2) GetPluginList(ctx context.Context, walletAddress string) ([]string, error) {
+	addr, err := address.ParseAddr(walletAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	plugins, err := wallet.GetPluginList(ctx, a.lclient, addr)
+	if err != nil {
+		a.logger.Error("get plugin list failed", zap.Error(err), zap.String("address", walletAddress))
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		addresses = append(addresses, plugin.String())
+	}
+
+	return addresses, nil
+}
+
+// InstallPlugin installs a deployed plugin in the V4 wallet.
+func (a *TonApiV2) InstallPlugin(ctx context.Context, input *PluginInput) (*types.TransferOutput, error) {
+	return a.sendPluginRequest(ctx, input, false)
+}
+
+// RemovePlugin removes a plugin from the V4 wallet, the plugin is notified and destroys itself.
+func (a *TonApiV2) RemovePlugin(ctx context.Context, input *PluginInput) (*types.TransferOutput, error) {
+	return a.sendPluginRequest(ctx, input, true)
+}
+
+func (a *TonApiV2) sendPluginRequest(ctx context.Context, input *PluginInput, remove bool) (*types.TransferOutput, error) {
+	plugin, err := address.ParseAddr(input.PluginAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	amount := big.NewInt(pluginNotifyAmount)
+	if input.Amount != nil {
+		amount = input.Amount
+	}
+
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	w, spec, err := a.getV4Wallet(ctx, input.AppId, input.Network, input.FromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	build, op := spec.BuildInstallPluginMessage, uint64(opPluginInstalled)
+	if remove {
+		build, op = spec.BuildRemovePluginMessage, opPluginRemoved
+	}
+
+	queryID := randomQueryID()
+	body, err := build(ctx, plugin, tlb.FromNanoTON(amount), queryID)
+	if err != nil {
+		a.logger.Error("build plugin request failed", zap.Error(err))
+		return nil, err
+	}
+
+	// the message the wallet notifies the plugin with
+	notify := &wallet.Message{
+		Mode: wallet.PayGasSeparately + wallet.IgnoreErrors,
+		InternalMessage: &tlb.InternalMessage{
+			IHRDisabled: true,
+			Bounce:      true,
+			DstAddr:     plugin,
+			Amount:      tlb.FromNanoTON(amount),
+			Body:        cell.BeginCell().MustStoreUInt(op, 32).MustStoreUInt(queryID, 64).EndCell(),
+		},
+	}
+
+	return a.sendWalletRequest(ctx, w, body, notify)
+}
+
+// Subscribe deploys a subscription plugin and installs it in the V4 wallet, the plugin takes the first
+// payment right away and requests the next ones every period.
+func (a *TonApiV2) Subscribe(ctx context.Context, input *SubscriptionInput) (*SubscriptionOutput, error) {
+	code, err := cell.FromBOC(input.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plugin code: %w", err)
+	}
+
+	beneficiary, err := address.ParseAddr(input.Beneficiary)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	if input.Amount == nil || input.Amount.Sign() <= 0 {
+		return nil, fmt.Errorf("subscription amount should be positive")
+	}
+
+	if input.Period < time.Second {
+		return nil, fmt.Errorf("subscription period should be at least a second")
+	}
+
+	startTime := input.StartTime
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+
+	timeout := input.Timeout
+	if timeout == 0 {
+		timeout = input.Period
+	}
+
+	deployAmount := new(big.Int).Add(input.Amount, big.NewInt(pluginNotifyAmount))
+	if input.DeployAmount != nil {
+		deployAmount = input.DeployAmount
+	}
+
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	w, spec, err := a.getV4Wallet(ctx, input.AppId, input.Network, input.FromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	config := wallet.SubscriptionConfig{
+		Code:           code,
+		Beneficiary:    beneficiary,
+		Amount:         tlb.FromNanoTON(input.Amount),
+		Period:         uint32(input.Period / time.Second),
+		StartTime:      uint32(startTime.Unix()),
+		Timeout:        uint32(timeout / time.Second),
+		SubscriptionID: input.SubscriptionID,
+	}
+
+	stateInit, err := config.StateInit(w.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	stateInitCell, err := tlb.ToCell(stateInit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert state init to cell: %w", err)
+	}
+
+	workchain := int8(w.Address().Workchain())
+	body, err := spec.BuildDeployPluginMessage(ctx, workchain, tlb.FromNanoTON(deployAmount), stateInit, wallet.SubscriptionDeployBody())
+	if err != nil {
+		a.logger.Error("BuildDeployPluginMessage failed", zap.Error(err))
+		return nil, err
+	}
+
+	plugin := address.NewAddress(0, byte(workchain), stateInitCell.Hash())
+
+	// the message deploying the plugin
+	deploy := &wallet.Message{
+		Mode: wallet.PayGasSeparately + wallet.IgnoreErrors,
+		InternalMessage: &tlb.InternalMessage{
+			IHRDisabled: true,
+			Bounce:      true,
+			DstAddr:     plugin,
+			Amount:      tlb.FromNanoTON(deployAmount),
+			StateInit:   stateInit,
+			Body:        wallet.SubscriptionDeployBody(),
+		},
+	}
+
+	output, err := a.sendWalletRequest(ctx, w, body, deploy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubscriptionOutput{
+		Hash:          output.Hash,
+		PluginAddress: plugin.String(),
+	}, nil
+}
+
+func (a *TonApiV2) getV4Wallet(ctx context.Context, appId, network, fromAddress string) (*wallet.Wallet, *wallet.SpecV4R2, error) {
+	w, err := a.getWallet(ctx, &types.TransferInput{
+		AppId:       appId,
+		Network:     network,
+		FromAddress: fromAddress,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spec, ok := w.GetSpec().(*wallet.SpecV4R2)
+	if !ok {
+		return nil, nil, fmt.Errorf("plugins need a V4 wallet: %w", wallet.ErrUnsupportedWalletVersion)
+	}
+
+	return w, spec, nil
+}
+
+// sendWalletRequest sends the request of the wallet once it is checked the wallet holds the TON of the
+// message the request makes it send and the fees, the forward fee of the message included. The message is
+// nil when the request sends none.
+func (a *TonApiV2) sendWalletRequest(ctx context.Context, w *wallet.Wallet, body *cell.Cell, message *wallet.Message) (*types.TransferOutput, error) {
+	ext, err := w.BuildExternalMessageWithBody(ctx, body)
+	if err != nil {
+		a.logger.Error("BuildExternalMessageWithBody failed", zap.Error(err))
+		return nil, err
+	}
+
+	var messages []*wallet.Message
+	amount := new(big.Int)
+	if message != nil {
+		messages = append(messages, message)
+		amount = message.InternalMessage.Amount.Nano()
+	}
+
+	estimate, err := a.estimateFee(ctx, w, ext, messages, false)
+	if err != nil {
+		a.logger.Error("estimate fee failed", zap.Error(err))
+		return nil, err
+	}
+
+	balance, err := a.getBalance(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	totalTonAmount := new(big.Int).Add(amount, estimate.Total)
+	if balance.Nano().Cmp(totalTonAmount) < 0 {
+		a.logger.Info("insufficient ton balance",
+			zap.String("balance", balance.Nano().String()),
+			zap.String("totalAmount", totalTonAmount.String()),
+		)
+		return nil, types.WrapErr(types.ErrInsufficientBalance, fmt.Errorf("insufficient balance of TON"))
+	}
+
+	return a.sendExternalMessage(ctx, ext)
+}
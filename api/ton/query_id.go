@@ -0,0 +1,144 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+)
+
+const (
+	// a highload V3 query id is a 13 bit shift and a 10 bit bit number, bit number 1023 is reserved by the contract
+	maxQueryIDShift     = 1<<13 - 1
+	maxQueryIDBitNumber = 1022
+
+	// messages are dated back so that they are valid on lite servers lagging behind the local clock
+	maxHighloadClockSkew = 30
+)
+
+// HighloadQueryID is the query id of a highload V3 message. The shift selects a bitmap in the wallet storage
+// and the bit number the bit marking the query as processed, so a query id cannot be accepted twice while
+// the contract remembers it, which is between one and two message timeouts.
+type HighloadQueryID uint32
+
+func NewHighloadQueryID(shift, bitNumber uint32) (HighloadQueryID, error) {
+	if shift > maxQueryIDShift || bitNumber > maxQueryIDBitNumber {
+		return 0, fmt.Errorf("query id shift %d, bit number %d is out of range", shift, bitNumber)
+	}
+
+	return HighloadQueryID(shift<<10 | bitNumber), nil
+}
+
+func (id HighloadQueryID) Shift() uint32 {
+	return uint32(id) >> 10
+}
+
+func (id HighloadQueryID) BitNumber() uint32 {
+	return uint32(id) & 1023
+}
+
+// Next returns the query id following id, the sequence starts over from zero after the last shift.
+func (id HighloadQueryID) Next() HighloadQueryID {
+	if id.BitNumber() < maxQueryIDBitNumber {
+		return id + 1
+	}
+
+	if id.Shift() < maxQueryIDShift {
+		return HighloadQueryID((id.Shift() + 1) << 10)
+	}
+
+	return 0
+}
+
+// QueryIDStore persists the last query id used by every highload V3 wallet. It must survive restarts,
+// a wallet that starts over from a used query id gets its messages rejected until the contract forgets it.
+// Wallets are keyed by raw address and subwallet id, Load returns a nil query id for wallets that never sent a message.
+type QueryIDStore interface {
+	LoadQueryID(ctx context.Context, wallet string) (*HighloadQueryID, error)
+	SaveQueryID(ctx context.Context, wallet string, id HighloadQueryID) error
+}
+
+// queryIDAllocator hands out the query ids of highload V3 wallets, an id is saved before it is used so that
+// it is never signed twice, even if the message carrying it is never sent.
+type queryIDAllocator struct {
+	mu    sync.Mutex
+	store QueryIDStore
+}
+
+func newQueryIDAllocator(store QueryIDStore) *queryIDAllocator {
+	return &queryIDAllocator{store: store}
+}
+
+func (q *queryIDAllocator) next(ctx context.Context, wallet string) (HighloadQueryID, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	last, err := q.store.LoadQueryID(ctx, wallet)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load query id of %s: %w", wallet, err)
+	}
+
+	var id HighloadQueryID
+	if last != nil {
+		id = last.Next()
+	}
+
+	if err := q.store.SaveQueryID(ctx, wallet, id); err != nil {
+		return 0, fmt.Errorf("failed to save query id of %s: %w", wallet, err)
+	}
+
+	return id, nil
+}
+
+// config returns the highload V3 config of the wallet taking its query ids from the allocator.
+func (q *queryIDAllocator) config(walletAddress string, ttl uint32) wallet.ConfigHighloadV3 {
+	skew := min(int64(ttl/2), maxHighloadClockSkew)
+
+	return wallet.ConfigHighloadV3{
+		MessageTTL: ttl,
+		MessageBuilder: func(ctx context.Context, subWalletId uint32) (uint32, int64, error) {
+			id, err := q.next(ctx, fmt.Sprintf("%s:%d", walletAddress, subWalletId))
+			if err != nil {
+				return 0, 0, err
+			}
+
+			return uint32(id), time.Now().Unix() - skew, nil
+		},
+	}
+}
+
+// MemoryQueryIDStore keeps query ids in memory, it is only safe for wallets used by a single process that
+// does not restart within two message timeouts, it has to be passed to WithQueryIDStore explicitly.
+type MemoryQueryIDStore struct {
+	mu  sync.Mutex
+	ids map[string]HighloadQueryID
+}
+
+func NewMemoryQueryIDStore() *MemoryQueryIDStore {
+	return &MemoryQueryIDStore{
+		ids: make(map[string]HighloadQueryID),
+	}
+}
+
+func (s *MemoryQueryIDStore) LoadQueryID(ctx context.Context, wallet string) (*HighloadQueryID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.ids[wallet]
+	if !ok {
+		return nil, nil
+	}
+
+	return &id, nil
+}
+
+func (s *MemoryQueryIDStore) SaveQueryID(ctx context.Context, wallet string, id HighloadQueryID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ids[wallet] = id
+
+	return nil
+}
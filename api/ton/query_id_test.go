@@ -0,0 +1,36 @@
+package ton_test
+
+import (
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton"
+)
+
+func TestHighloadQueryIDNext(t *testing.T) {
+	tests := []struct {
+		shift, bitNumber         uint32
+		nextShift, nextBitNumber uint32
+	}{
+		{0, 0, 0, 1},
+		{3, 1021, 3, 1022},
+		{3, 1022, 4, 0},
+		{8191, 1022, 0, 0},
+	}
+
+	for _, tt := range tests {
+		id, err := ton.NewHighloadQueryID(tt.shift, tt.bitNumber)
+		if err != nil {
+			t.Fatalf("NewHighloadQueryID(%d, %d) error = %v", tt.shift, tt.bitNumber, err)
+		}
+
+		next := id.Next()
+		if next.Shift() != tt.nextShift || next.BitNumber() != tt.nextBitNumber {
+			t.Errorf("(%d, %d).Next() = (%d, %d), want (%d, %d)",
+				tt.shift, tt.bitNumber, next.Shift(), next.BitNumber(), tt.nextShift, tt.nextBitNumber)
+		}
+	}
+
+	if _, err := ton.NewHighloadQueryID(0, 1023); err == nil {
+		t.Errorf("NewHighloadQueryID(0, 1023) succeeded, bit number 1023 is reserved")
+	}
+}
@@ -0,0 +1,92 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/tlb"
+	"go.uber.org/zap"
+)
+
+// relayMessage wraps the message of the V5R1 wallet w into a request signed by its owner that the wallet of
+// input.FeePayer delivers, so w does not need TON: the fee payer pays the fees of its transaction and
+// attaches the gas and forward fees of w plus the TON its jetton or NFT message needs. It returns the fee
+// payer wallet and the message it sends.
+func (a *TonApiV2) relayMessage(ctx context.Context, w *wallet.Wallet, input *types.TransferInput, message *wallet.Message) (*wallet.Wallet, *wallet.Message, error) {
+	if _, ok := w.GetSpec().(*wallet.SpecV5R1); !ok {
+		return nil, nil, fmt.Errorf("relayed transfers need a V5R1 wallet: %w", wallet.ErrUnsupportedWalletVersion)
+	}
+
+	// reject TON transfers before any request
+	if _, err := relayedAmount(input, message, new(big.Int)); err != nil {
+		return nil, nil, err
+	}
+
+	payer, err := a.getWallet(ctx, &types.TransferInput{
+		AppId:       input.AppId,
+		Network:     input.Network,
+		FromAddress: input.FeePayer,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fees, err := a.relayedWalletFees(ctx, w, []*wallet.Message{message})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount, err := relayedAmount(input, message, fees)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relayed, err := w.BuildInternalSignedMessage(ctx, []*wallet.Message{message}, tlb.FromNanoTON(amount))
+	if err != nil {
+		a.logger.Error("BuildInternalSignedMessage failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	return payer, relayed, nil
+}
+
+// relayedAmount returns the TON the fee payer attaches to relay the message: the fees of the relayed wallet
+// and the TON the jetton or NFT message attaches for the gas of the jetton wallets or the item. A fee payer
+// does not sponsor the value of TON transfers.
+func relayedAmount(input *types.TransferInput, message *wallet.Message, fees *big.Int) (*big.Int, error) {
+	if input.Token == string(types.TOKEN_TYPE_TON) {
+		return nil, types.WrapErr(types.ErrUnsupportedFeePayer, fmt.Errorf("fee payers only relay jetton and NFT transfers"))
+	}
+
+	return new(big.Int).Add(message.InternalMessage.Amount.Nano(), fees), nil
+}
+
+// relayedWalletFees returns the TON the relayed wallet spends on its own transaction, its gas and the forward
+// fees of the messages. They are doubled so that price changes do not fail the request.
+func (a *TonApiV2) relayedWalletFees(ctx context.Context, w *wallet.Wallet, messages []*wallet.Message) (*big.Int, error) {
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return nil, err
+	}
+
+	config, err := a.getFeeConfig(ctx, block, w.WalletAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	fees := config.gas.fee(walletGas(w) + walletMessageGas*uint64(len(messages)))
+	for _, message := range messages {
+		msgCell, err := tlb.ToCell(message.InternalMessage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert message to cell: %w", err)
+		}
+
+		fees.Add(fees, config.forward.fee(msgCell))
+	}
+
+	return fees.Lsh(fees, 1), nil
+}
@@ -0,0 +1,63 @@
+package ton_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+func TestRelayedAmount(t *testing.T) {
+	fees := tlb.MustFromTON("0.02").Nano()
+
+	tests := []struct {
+		name    string
+		token   string
+		message *wallet.Message
+		want    *big.Int
+		wantErr *types.Error
+	}{
+		{
+			name:    "jetton transfer",
+			token:   "USDT",
+			message: &wallet.Message{Mode: wallet.PayGasSeparately, InternalMessage: message(t, newTestAddress(1), "0.05", nil)},
+			want:    tlb.MustFromTON("0.07").Nano(),
+		},
+		{
+			name:    "nft transfer",
+			token:   string(types.TOKEN_TYPE_NFT),
+			message: &wallet.Message{Mode: wallet.PayGasSeparately, InternalMessage: message(t, newTestAddress(1), "0.06", nil)},
+			want:    tlb.MustFromTON("0.08").Nano(),
+		},
+		{
+			// the sponsor would pay the payment itself
+			name:    "ton transfer",
+			token:   string(types.TOKEN_TYPE_TON),
+			message: &wallet.Message{Mode: wallet.PayGasSeparately, InternalMessage: message(t, newTestAddress(1), "100", nil)},
+			wantErr: types.ErrUnsupportedFeePayer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ton.RelayedAmount(&types.TransferInput{Token: tt.token}, tt.message, fees)
+			if tt.wantErr != nil {
+				var typed *types.Error
+				if !errors.As(err, &typed) || typed.Code != tt.wantErr.Code {
+					t.Fatalf("RelayedAmount() error = %v, want %s", err, tt.wantErr.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RelayedAmount() error = %v", err)
+			}
+			if got.Cmp(tt.want) != 0 {
+				t.Errorf("RelayedAmount() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
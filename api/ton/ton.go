@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	"github.com/openweb3-io/blockchain/api"
-	"github.com/openweb3-io/blockchain/api/ton/wallet"
 	"github.com/openweb3-io/blockchain/api/types"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
@@ -16,10 +15,17 @@ import (
 type TonApi struct {
 	signerProvider *api.SignerProvider
 	client         ton.APIClientWrapped
+	opts           *Options
 }
 
-func NewTonApi(signerProvider *api.SignerProvider, client ton.APIClientWrapped) *TonApi {
-	return &TonApi{signerProvider, client}
+func NewTonApi(signerProvider *api.SignerProvider, client ton.APIClientWrapped, o ...Option) *TonApi {
+	opts := defaultOptions()
+
+	for _, opt := range o {
+		opt(opts)
+	}
+
+	return &TonApi{signerProvider, client, opts}
 }
 
 func (a *TonApi) Transfer(ctx context.Context, input *types.TransferInput) (*types.TransferMessage, error) {
@@ -33,7 +39,7 @@ func (a *TonApi) Transfer(ctx context.Context, input *types.TransferInput) (*typ
 		return nil, err
 	}
 
-	w, err := wallet.FromSigner(ctx, a.client, signer, wallet.V4R2)
+	w, err := walletFromSigner(ctx, a.client, signer, input.FromAddress, a.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -2,7 +2,6 @@ package ton
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 	"github.com/openweb3-io/blockchain/api"
 	"github.com/openweb3-io/blockchain/api/ton/contract"
 	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
 	"github.com/openweb3-io/blockchain/api/types"
 	"github.com/tonkeeper/tonapi-go"
 	"github.com/xssnick/tonutils-go/address"
@@ -27,6 +27,7 @@ type TonApiV2 struct {
 	client         *tonapi.Client
 	lclient        ton.APIClientWrapped
 	logger         *zap.Logger
+	opts           *Options
 }
 
 func NewTonApiV2(
@@ -34,8 +35,15 @@ func NewTonApiV2(
 	client *tonapi.Client,
 	lclient ton.APIClientWrapped,
 	logger *zap.Logger,
+	o ...Option,
 ) *TonApiV2 {
-	return &TonApiV2{signerProvider, client, lclient, logger}
+	opts := defaultOptions()
+
+	for _, opt := range o {
+		opt(opts)
+	}
+
+	return &TonApiV2{signerProvider, client, lclient, logger, opts}
 }
 
 func (a *TonApiV2) getWallet(ctx context.Context, input *types.TransferInput) (*wallet.Wallet, error) {
@@ -45,7 +53,7 @@ func (a *TonApiV2) getWallet(ctx context.Context, input *types.TransferInput) (*
 		return nil, err
 	}
 
-	w, err := wallet.FromSigner(ctx, a.lclient, signer, wallet.V4R2)
+	w, err := walletFromSigner(ctx, a.lclient, signer, input.FromAddress, a.opts)
 	if err != nil {
 		a.logger.Error("get wallet from signer failed", zap.Error(err))
 		return nil, err
@@ -54,45 +62,101 @@ func (a *TonApiV2) getWallet(ctx context.Context, input *types.TransferInput) (*
 	return w, nil
 }
 
-func (a *TonApiV2) createTransfer(ctx context.Context, w *wallet.Wallet, input *types.TransferInput) ([]byte, []byte, error) {
-	dstAddr, err := address.ParseAddr(input.ToAddress)
+// transfer is a signed external message of a single transfer.
+type transfer struct {
+	// wallet sending the external message and paying its fees, the fee payer of relayed transfers
+	payer   *wallet.Wallet
+	ext     *tlb.ExternalMessage
+	message *wallet.Message
+	boc     []byte
+	// normalized hash of the external message
+	hash []byte
+}
+
+func (a *TonApiV2) createTransfer(ctx context.Context, w *wallet.Wallet, input *types.TransferInput) (*transfer, error) {
+	message, err := a.buildMessage(ctx, w, input)
 	if err != nil {
-		a.logger.Error("ParseAddr failed", zap.Error(err))
-		return nil, nil, err
+		return nil, err
 	}
 
-	var message *wallet.Message
-	if input.Token != string(types.TOKEN_TYPE_TON) {
+	// lockup wallets only spend released funds, and restricted ones on allowed destinations
+	if err = a.checkLockupTransfer(ctx, w, message); err != nil {
+		return nil, err
+	}
+
+	// a fee payer relays the transfer of a V5R1 wallet signed by its owner
+	payer := w
+	if input.FeePayer != "" && input.FeePayer != input.FromAddress {
+		payer, message, err = a.relayMessage(ctx, w, input, message)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ext, err := payer.BuildExternalMessageForMany(ctx, []*wallet.Message{message})
+	if err != nil {
+		a.logger.Error("BuildExternalMessage failed", zap.Error(err))
+		return nil, err
+	}
+
+	msgCell, err := tlb.ToCell(ext)
+	if err != nil {
+		a.logger.Error("ToCell failed", zap.Error(err))
+		return nil, err
+	}
+
+	return &transfer{
+		payer:   payer,
+		ext:     ext,
+		message: message,
+		boc:     msgCell.ToBOCWithFlags(false),
+		hash:    wrap.NormalizedExtMessageHash(ext),
+	}, nil
+}
+
+// buildMessage builds the internal message of a TON, jetton or NFT transfer from the wallet.
+func (a *TonApiV2) buildMessage(ctx context.Context, w *wallet.Wallet, input *types.TransferInput) (*wallet.Message, error) {
+	if input.Token == string(types.TOKEN_TYPE_NFT) {
 		if input.ContractAddress == "" {
-			return nil, nil, errors.New("contract address is required")
+			return nil, errors.New("nft address is required")
 		}
 
-		message, err = a.buildJettonTransfer(ctx, w, input)
+		message, err := a.buildNftTransfer(ctx, w, input)
 		if err != nil {
-			a.logger.Error("buildJettonTransfer failed", zap.Error(err))
-			return nil, nil, err
+			a.logger.Error("buildNftTransfer failed", zap.Error(err))
+			return nil, err
 		}
-	} else {
-		message, err = w.BuildTransfer(dstAddr, tlb.FromNanoTON(input.Amount), dstAddr.IsBounceable(), input.Memo)
+
+		return message, nil
+	}
+
+	if input.Token != string(types.TOKEN_TYPE_TON) {
+		if input.ContractAddress == "" {
+			return nil, errors.New("contract address is required")
+		}
+
+		message, err := a.buildJettonTransfer(ctx, w, input)
 		if err != nil {
-			a.logger.Error("BuildTransfer failed", zap.Error(err))
-			return nil, nil, err
+			a.logger.Error("buildJettonTransfer failed", zap.Error(err))
+			return nil, err
 		}
+
+		return message, nil
 	}
 
-	ext, err := w.BuildExternalMessageForMany(ctx, []*wallet.Message{message})
+	dstAddr, err := address.ParseAddr(input.ToAddress)
 	if err != nil {
-		a.logger.Error("BuildExternalMessage failed", zap.Error(err))
-		return nil, nil, err
+		a.logger.Error("ParseAddr failed", zap.Error(err))
+		return nil, err
 	}
 
-	msgCell, err := tlb.ToCell(ext)
+	message, err := w.BuildTransfer(dstAddr, tlb.FromNanoTON(input.Amount), dstAddr.IsBounceable(), input.Memo)
 	if err != nil {
-		a.logger.Error("ToCell failed", zap.Error(err))
-		return nil, nil, err
+		a.logger.Error("BuildTransfer failed", zap.Error(err))
+		return nil, err
 	}
 
-	return msgCell.ToBOCWithFlags(false), ext.Body.Hash(), nil
+	return message, nil
 }
 
 func (a *TonApiV2) buildJettonTransfer(ctx context.Context, w *wallet.Wallet, input *types.TransferInput) (_ *wallet.Message, err error) {
@@ -126,15 +190,39 @@ func (a *TonApiV2) buildJettonTransfer(ctx context.Context, w *wallet.Wallet, in
 		return nil, fmt.Errorf("insufficient balance of %s", input.Token)
 	}
 
-	var comment *cell.Cell
-	if input.Memo != "" {
-		comment, err = wallet.CreateCommentCell(input.Memo)
+	opts := input.Jetton
+	if opts == nil {
+		opts = &types.JettonTransferOptions{}
+	}
+
+	if (opts.ForwardAmount != nil && opts.ForwardAmount.Sign() < 0) || (opts.AttachedAmount != nil && opts.AttachedAmount.Sign() < 0) {
+		return nil, types.WrapErr(types.ErrInvalidAmount, fmt.Errorf("forward and attached amounts must not be negative"))
+	}
+
+	var forwardPayload *cell.Cell
+	if len(opts.ForwardPayload) > 0 {
+		forwardPayload, err = cell.FromBOC(opts.ForwardPayload)
+		if err != nil {
+			a.logger.Error("parse forward payload failed", zap.Error(err))
+			return nil, err
+		}
+	} else if input.Memo != "" {
+		forwardPayload, err = wallet.CreateCommentCell(input.Memo)
 		if err != nil {
 			a.logger.Error("CreateCommentCell failed", zap.Error(err))
 			return nil, err
 		}
 	}
 
+	var customPayload *cell.Cell
+	if len(opts.CustomPayload) > 0 {
+		customPayload, err = cell.FromBOC(opts.CustomPayload)
+		if err != nil {
+			a.logger.Error("parse custom payload failed", zap.Error(err))
+			return nil, err
+		}
+	}
+
 	amountTokens, err := tlb.FromNano(input.Amount, int(input.TokenDecimals))
 	if err != nil {
 		a.logger.Error("FromNano failed",
@@ -154,35 +242,66 @@ func (a *TonApiV2) buildJettonTransfer(ctx context.Context, w *wallet.Wallet, in
 	}
 
 	responseTo := w.WalletAddress()
+	if opts.ResponseDestination != "" {
+		responseTo, err = address.ParseAddr(opts.ResponseDestination)
+		if err != nil {
+			a.logger.Error("ParseAddr failed", zap.Error(err), zap.String("address", opts.ResponseDestination))
+			return nil, types.WrapErr(types.ErrInvalidAddress, err)
+		}
+	}
+
 	amountForwardTON := tlb.MustFromTON(types.JettonForwardAmount)
-	transferPayload, err := tokenWallet.BuildTransferPayloadV2(to, responseTo, amountTokens, amountForwardTON, comment, nil)
+	if opts.ForwardAmount != nil {
+		amountForwardTON = tlb.FromNanoTON(opts.ForwardAmount)
+	}
+
+	transferPayload, err := tokenWallet.BuildTransferPayloadV2(to, responseTo, amountTokens, amountForwardTON, forwardPayload, customPayload)
 	if err != nil {
 		a.logger.Error("BuildTransferPayloadV2 failed", zap.Error(err))
 		return nil, err
 	}
 
-	return &wallet.Message{
+	message := &wallet.Message{
 		Mode: wallet.PayGasSeparately + wallet.IgnoreErrors,
 		InternalMessage: &tlb.InternalMessage{
 			IHRDisabled: true,
 			Bounce:      to.IsBounceable(),
 			DstAddr:     tokenWallet.Address(), // send message to token contract address, the message will be processed by contract
-			Amount:      tlb.MustFromTON(types.JettonTransferAttachedTonAmount),
 			Body:        transferPayload,
 		},
-	}, nil
+	}
+
+	attached, err := a.jettonAttachedAmount(ctx, message.InternalMessage, amountForwardTON.Nano())
+	if err != nil {
+		return nil, err
+	}
+
+	// jetton wallets bounce a transfer that cannot pay for its chain, keeping the gas they spent
+	if opts.AttachedAmount != nil {
+		if opts.AttachedAmount.Cmp(attached) < 0 {
+			return nil, types.WrapErr(types.ErrInvalidAmount,
+				fmt.Errorf("attached amount %s is below the %s the jetton wallets need", opts.AttachedAmount, attached))
+		}
+		attached = opts.AttachedAmount
+	}
+	message.InternalMessage.Amount = tlb.FromNanoTON(attached)
+
+	return message, nil
 }
 
-func (a *TonApiV2) estimateGas(ctx context.Context, boc []byte) (*big.Int, error) {
-	res, err := a.client.EmulateMessageToWallet(ctx, &tonapi.EmulateMessageToWalletReq{
-		Boc: base64.StdEncoding.EncodeToString(boc),
-	}, tonapi.EmulateMessageToWalletParams{})
+func (a *TonApiV2) estimateGas(ctx context.Context, t *transfer, input *types.TransferInput) (*big.Int, error) {
+	estimate, err := a.estimateFee(ctx, t.payer, t.ext, []*wallet.Message{t.message}, isJettonTransfer(input))
 	if err != nil {
-		a.logger.Error("EmulateMessageToWallet failed", zap.Error(err))
+		a.logger.Error("estimate fee failed", zap.Error(err))
 		return nil, err
 	}
 
-	return new(big.Int).SetInt64(res.Event.Extra * -1), nil
+	return estimate.Total, nil
+}
+
+// isJettonTransfer reports whether the input transfers jettons, any token that is not TON or an NFT.
+func isJettonTransfer(input *types.TransferInput) bool {
+	return input.Token != string(types.TOKEN_TYPE_TON) && input.Token != string(types.TOKEN_TYPE_NFT)
 }
 
 func (a *TonApiV2) getBalance(ctx context.Context, w *wallet.Wallet) (*tlb.Coins, error) {
@@ -208,13 +327,13 @@ func (a *TonApiV2) Transfer(ctx context.Context, input *types.TransferInput) (*t
 		return nil, err
 	}
 
-	boc, hash, err := a.createTransfer(ctx, w, input)
+	t, err := a.createTransfer(ctx, w, input)
 	if err != nil {
 		return nil, err
 	}
 
 	// estimate gas
-	gas, err := a.estimateGas(ctx, boc)
+	gas, err := a.estimateGas(ctx, t, input)
 	if err != nil {
 		return nil, err
 	}
@@ -222,17 +341,13 @@ func (a *TonApiV2) Transfer(ctx context.Context, input *types.TransferInput) (*t
 	a.logger.Debug("estimate gas", zap.String("gas", gas.String()), zap.String("amount", input.Amount.String()))
 
 	// check balance
-	balance, err := a.getBalance(ctx, w)
+	balance, err := a.getBalance(ctx, t.payer)
 	if err != nil {
 		return nil, err
 	}
 
-	totalTonAmount := gas
-	if input.Token == string(types.TOKEN_TYPE_TON) {
-		totalTonAmount = new(big.Int).Add(input.Amount, gas)
-	} else {
-		totalTonAmount = totalTonAmount.Add(totalTonAmount, tlb.MustFromTON(types.JettonTransferAttachedTonAmount).Nano())
-	}
+	// the message carries the TON of the transfer, or the TON jetton and NFT transfers and relayed requests attach
+	totalTonAmount := new(big.Int).Add(gas, t.message.InternalMessage.Amount.Nano())
 
 	if balance.Nano().Cmp(totalTonAmount) < 0 {
 		a.logger.Info("insufficient ton balance",
@@ -242,19 +357,18 @@ func (a *TonApiV2) Transfer(ctx context.Context, input *types.TransferInput) (*t
 		return nil, errors.New("insufficient balance of TON")
 	}
 
-	_, err = a.client.SendMessage(ctx, boc)
+	_, err = a.client.SendMessage(ctx, t.boc)
 	if err != nil {
 		a.logger.Error("SendBlockchainMessage failed", zap.Error(err))
 		return nil, err
 	}
 
-	a.logger.Info("SendBlockchainMessage succeeded", zap.String("hash", hex.EncodeToString(hash)))
+	a.logger.Info("SendBlockchainMessage succeeded", zap.String("hash", hex.EncodeToString(t.hash)))
 
-	// this hash is not the transaction hash, the transaction hash
-	// needs to traverse the corresponding block's transaction for comparison,
-	// here transfer needs to be put into the queue for asynchronous processing
+	// this hash is not the transaction hash, pass it to TrackTransaction
+	// to find the transaction and its final result once it is processed
 	return &types.TransferOutput{
-		Hash: hash,
+		Hash: t.hash,
 	}, nil
 }
 
@@ -272,12 +386,12 @@ func (a *TonApiV2) EstimateGas(ctx context.Context, input *types.TransferInput)
 		return types.TOKEN_TYPE_NONE, nil, err
 	}
 
-	boc, _, err := a.createTransfer(ctx, w, input)
+	t, err := a.createTransfer(ctx, w, input)
 	if err != nil {
 		return types.TOKEN_TYPE_NONE, nil, err
 	}
 
-	gas, err := a.estimateGas(ctx, boc)
+	gas, err := a.estimateGas(ctx, t, input)
 	if err != nil {
 		return types.TOKEN_TYPE_NONE, nil, err
 	}
@@ -285,6 +399,24 @@ func (a *TonApiV2) EstimateGas(ctx context.Context, input *types.TransferInput)
 	return types.TOKEN_TYPE_TON, gas, nil
 }
 
+// EstimateFee returns the breakdown of the fees of the transfer, computed locally from the blockchain config.
+func (a *TonApiV2) EstimateFee(ctx context.Context, input *types.TransferInput) (*FeeEstimate, error) {
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	w, err := a.getWallet(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := a.createTransfer(ctx, w, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.estimateFee(ctx, t.payer, t.ext, []*wallet.Message{t.message}, isJettonTransfer(input))
+}
+
 func (a *TonApiV2) PrepareTransaction(ctx context.Context, input *types.TransferInput) (*types.TransferMessage, error) {
 	st := time.Now()
 	defer func() {
@@ -299,31 +431,26 @@ func (a *TonApiV2) PrepareTransaction(ctx context.Context, input *types.Transfer
 		return nil, err
 	}
 
-	boc, hash, err := a.createTransfer(ctx, w, input)
+	t, err := a.createTransfer(ctx, w, input)
 	if err != nil {
 		return nil, err
 	}
 
 	// estimate gas
-	gas, err := a.estimateGas(ctx, boc)
+	gas, err := a.estimateGas(ctx, t, input)
 	if err != nil {
 		return nil, err
 	}
 
 	a.logger.Debug("estimate gas", zap.String("gas", gas.String()), zap.String("amount", input.Amount.String()))
 
-	balance, err := a.getBalance(ctx, w)
+	balance, err := a.getBalance(ctx, t.payer)
 	if err != nil {
 		return nil, err
 	}
 
-	totalTonAmount := gas
-	if input.Token == string(types.TOKEN_TYPE_TON) {
-		totalTonAmount = new(big.Int).Add(input.Amount, gas)
-
-	} else {
-		totalTonAmount = totalTonAmount.Add(totalTonAmount, tlb.MustFromTON(types.JettonTransferAttachedTonAmount).Nano())
-	}
+	// the message carries the TON of the transfer, or the TON jetton and NFT transfers and relayed requests attach
+	totalTonAmount := new(big.Int).Add(gas, t.message.InternalMessage.Amount.Nano())
 
 	if balance.Nano().Cmp(totalTonAmount) < 0 {
 		a.logger.Info("insufficient ton balance",
@@ -333,8 +460,8 @@ func (a *TonApiV2) PrepareTransaction(ctx context.Context, input *types.Transfer
 		return nil, errors.New("insufficient balance of TON")
 	}
 	return &types.TransferMessage{
-		Hash:    hash,
-		Payload: boc,
+		Hash:    t.hash,
+		Payload: t.boc,
 	}, nil
 }
 
@@ -358,6 +485,28 @@ func (a *TonApiV2) BroadcastTransaction(ctx context.Context, input *types.Transf
 	return nil
 }
 
+// sendExternalMessage sends an external message that is not a transfer, the returned hash is the normalized
+// hash of the message to pass to TrackTransaction.
+func (a *TonApiV2) sendExternalMessage(ctx context.Context, ext *tlb.ExternalMessage) (*types.TransferOutput, error) {
+	msgCell, err := tlb.ToCell(ext)
+	if err != nil {
+		a.logger.Error("ToCell failed", zap.Error(err))
+		return nil, err
+	}
+
+	hash := wrap.NormalizedExtMessageHash(ext)
+	if _, err = a.client.SendMessage(ctx, msgCell.ToBOCWithFlags(false)); err != nil {
+		a.logger.Error("SendBlockchainMessage failed", zap.Error(err))
+		return nil, err
+	}
+
+	a.logger.Info("SendBlockchainMessage succeeded", zap.String("hash", hex.EncodeToString(hash)))
+
+	return &types.TransferOutput{
+		Hash: hash,
+	}, nil
+}
+
 func (a *TonApiV2) GetWalletData(ctx context.Context, walletAddress string) (*types.WalletData, error) {
 	st := time.Now()
 	defer func() {
@@ -381,9 +530,9 @@ func (a *TonApiV2) GetWalletData(ctx context.Context, walletAddress string) (*ty
 	ownerAddr := result.MustSlice(1).MustLoadAddr().String()
 	jettonMasterAddr := result.MustSlice(2).MustLoadAddr().String()
 
-	contr, err := contract.GetByAddress(jettonMasterAddr)
+	contr, err := contract.FindAndRegisterByAddress(ctx, a.lclient, jettonMasterAddr)
 	if err != nil {
-		a.logger.Error("get contract by address failed", zap.Error(err))
+		a.logger.Error("find contract by address failed", zap.Error(err))
 		return nil, err
 	}
 
@@ -392,5 +541,6 @@ func (a *TonApiV2) GetWalletData(ctx context.Context, walletAddress string) (*ty
 		OwnerAddress:        ownerAddr,
 		JettonMasterAddress: jettonMasterAddr,
 		JettonTokenName:     contr.GetTokenName(),
+		JettonDecimals:      contr.GetDecimals(),
 	}, nil
 }
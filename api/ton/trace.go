@@ -0,0 +1,295 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"go.uber.org/zap"
+)
+
+// TransferTrace is the chain of transactions of a transfer read as a single transfer, from the wallet that
+// signed it to its final recipient.
+type TransferTrace struct {
+	Status TransactionStatus
+	// wallet processing the external message that started the chain
+	From string
+	// recipient of the TON, owner of the receiving jetton wallet, or new owner of the NFT
+	To string
+	// jetton wallet of the sender for jetton transfers
+	Jetton string
+	// item of NFT transfers
+	Nft string
+	// nanotons, jetton units, or 1 for NFTs
+	Amount  string
+	Comment string
+	// fees of all the transactions of the chain in nanotons
+	TotalFees *big.Int
+	// transactions of the chain, the wallet transaction first
+	Transactions []*TraceTransaction
+}
+
+// TraceTransfer follows the transaction with the lt and hash on the account back to the external message
+// that started its chain, then follows all the messages of the chain, across jetton wallets and bounces. The
+// message the wallet sent on the way to the transaction is read as the transfer.
+func (a *TonApiV2) TraceTransfer(ctx context.Context, accountAddress string, txLt uint64, txHash []byte) (*TransferTrace, error) {
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	addr, err := address.ParseAddr(accountAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	tx, err := a.getTransaction(ctx, addr, txLt, txHash)
+	if err != nil {
+		a.logger.Error("get transaction failed", zap.Error(err), zap.String("address", accountAddress))
+		return nil, err
+	}
+
+	addr, path, err := a.findRootTransaction(ctx, addr, tx)
+	if err != nil {
+		a.logger.Error("find root transaction failed", zap.Error(err), zap.String("address", accountAddress))
+		return nil, err
+	}
+
+	// the transactions on the way to the traced one may be too old to find from the accounts again
+	result, err := a.followTransaction(ctx, addr, path[len(path)-1], path...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransferTrace(result, txHash)
+}
+
+// getTransaction fetches the transaction of addr with the lt and hash, however old it is.
+func (a *TonApiV2) getTransaction(ctx context.Context, addr *address.Address, lt uint64, hash []byte) (*tlb.Transaction, error) {
+	list, err := a.lclient.ListTransactions(ctx, addr, 1, lt, hash)
+	if err != nil {
+		if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+			return nil, ton.ErrTxWasNotFound
+		}
+
+		return nil, err
+	}
+
+	if len(list) == 0 || !bytes.Equal(list[len(list)-1].Hash, hash) {
+		return nil, ton.ErrTxWasNotFound
+	}
+
+	return list[len(list)-1], nil
+}
+
+// findRootTransaction follows the in messages of the transaction back to the wallet transaction processing
+// the external message that started the chain. It returns the wallet and the transactions on the way, the
+// wallet transaction last.
+func (a *TonApiV2) findRootTransaction(ctx context.Context, addr *address.Address, tx *tlb.Transaction) (*address.Address, []*tlb.Transaction, error) {
+	path := []*tlb.Transaction{tx}
+	for depth := 0; ; depth++ {
+		in := tx.IO.In
+		if in == nil || in.MsgType != tlb.MsgTypeInternal {
+			return addr, path, nil
+		}
+
+		if depth >= maxTraceDepth {
+			return nil, nil, fmt.Errorf("chain is longer than %d transactions", maxTraceDepth)
+		}
+
+		msg := in.AsInternal()
+		prev, err := a.findTransactionByOutMessage(ctx, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		addr, tx = msg.SrcAddr, prev
+		path = append(path, tx)
+	}
+}
+
+// findTransactionByOutMessage finds the transaction of the sender that created the internal message. Out
+// messages get the lts following the one of their transaction, so it is the last transaction of the sender
+// before the creation lt of the message, the scan starts from the state of the sender in the block of that lt.
+func (a *TonApiV2) findTransactionByOutMessage(ctx context.Context, msg *tlb.InternalMessage) (*tlb.Transaction, error) {
+	block, err := a.lookupBlockByLt(ctx, msg.SrcAddr, msg.CreatedLT)
+	if err != nil {
+		a.logger.Error("lookup block failed", zap.Error(err), zap.String("address", msg.SrcAddr.String()))
+		return nil, err
+	}
+
+	account, err := a.lclient.GetAccount(ctx, block, msg.SrcAddr)
+	if err != nil {
+		a.logger.Error("get account failed", zap.Error(err), zap.String("address", msg.SrcAddr.String()))
+		return nil, err
+	}
+
+	if !account.IsActive {
+		return nil, ton.ErrTxWasNotFound
+	}
+
+	tx, err := a.scanTransactions(ctx, msg.SrcAddr, account.LastTxLT, account.LastTxHash, 0, func(tx *tlb.Transaction) bool {
+		return tx.LT < msg.CreatedLT
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.IO.Out != nil {
+		list, err := tx.IO.Out.ToSlice()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list out messages: %w", err)
+		}
+
+		for _, m := range list {
+			if m.MsgType == tlb.MsgTypeInternal && m.AsInternal().CreatedLT == msg.CreatedLT {
+				return tx, nil
+			}
+		}
+	}
+
+	return nil, ton.ErrTxWasNotFound
+}
+
+// newTransferTrace reads the transfer of the chain as the message that left the wallet on the way to the
+// traced transaction, highload wallets first send their batch to themselves. A traced transaction of the
+// wallet reads the first message that left it. Jetton transfers take the amount and the recipient from the
+// receiving jetton wallet once it processed the transfer.
+func newTransferTrace(result *TransactionResult, txHash []byte) (*TransferTrace, error) {
+	wallet := result.Transaction.addr
+
+	var traced *TraceTransaction
+	for _, t := range result.Transactions {
+		if bytes.Equal(t.Hash, txHash) {
+			traced = t
+			break
+		}
+	}
+
+	// the transaction that received the transfer from the wallet, nil while the message is in flight
+	var leg *TraceTransaction
+	for t := traced; t != nil && t.parent != nil; t = t.parent {
+		if !t.addr.Equals(wallet) && t.parent.addr.Equals(wallet) {
+			leg = t
+		}
+	}
+
+	msg := leg.inMessage()
+	if leg == nil {
+		msg = walletMessage(result, wallet)
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("wallet %s sent no transfer", wallet.String())
+	}
+
+	transfer := messageTransaction(msg)
+	addresses, err := transfer.GetTxAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &TransferTrace{
+		Status:       result.Status,
+		From:         addresses.From,
+		To:           addresses.To,
+		Jetton:       addresses.Jetton,
+		Nft:          addresses.Nft,
+		Amount:       transfer.GetAmount(),
+		Comment:      transfer.GetComment(),
+		TotalFees:    new(big.Int),
+		Transactions: result.Transactions,
+	}
+
+	if leg == nil {
+		// the transaction of the message was not found yet
+		leg = received(result, wallet, msg)
+	}
+
+	if body, err := wrap.ParseMessageBody(msg.Body); err == nil && body.OpName == wrap.DecodedOpNameJettonTransfer && leg != nil {
+		if internal := child(result, leg, wrap.DecodedOpNameJettonInternalTransfer); internal != nil {
+			trace.Amount = (&wrap.LiteTransactionWrapper{Transaction: internal.tx}).GetAmount()
+
+			if notify := child(result, internal, wrap.DecodedOpNameJettonNotify); notify != nil {
+				if notified, err := (&wrap.LiteTransactionWrapper{Transaction: notify.tx}).GetTxAddresses(); err == nil {
+					trace.To = notified.To
+				}
+			}
+		}
+	}
+
+	for _, t := range result.Transactions {
+		trace.TotalFees.Add(trace.TotalFees, t.tx.TotalFees.Coins.Nano())
+	}
+
+	return trace, nil
+}
+
+// inMessage returns the internal message the transaction processed, nil for the wallet transaction.
+func (t *TraceTransaction) inMessage() *tlb.InternalMessage {
+	if t == nil || t.tx.IO.In == nil || t.tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return nil
+	}
+
+	return t.tx.IO.In.AsInternal()
+}
+
+// walletMessage returns the first internal message the transactions of the wallet sent to another account.
+func walletMessage(result *TransactionResult, wallet *address.Address) *tlb.InternalMessage {
+	for _, t := range result.Transactions {
+		if !t.addr.Equals(wallet) || t.tx.IO.Out == nil {
+			continue
+		}
+
+		list, err := t.tx.IO.Out.ToSlice()
+		if err != nil {
+			continue
+		}
+
+		for _, m := range list {
+			if m.MsgType == tlb.MsgTypeInternal && !m.AsInternal().DstAddr.Equals(wallet) {
+				return m.AsInternal()
+			}
+		}
+	}
+
+	return nil
+}
+
+// received returns the transaction of the chain processing the message the wallet sent.
+func received(result *TransactionResult, wallet *address.Address, msg *tlb.InternalMessage) *TraceTransaction {
+	for _, t := range result.Transactions {
+		if in := t.inMessage(); in != nil && in.CreatedLT == msg.CreatedLT && in.SrcAddr.Equals(wallet) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// child returns the transaction processing the message with the op the parent sent, bounces excluded.
+func child(result *TransactionResult, parent *TraceTransaction, opName string) *TraceTransaction {
+	for _, t := range result.Transactions {
+		if t.parent != parent || t.Bounce {
+			continue
+		}
+
+		if body, err := wrap.ParseMessageBody(t.inMessage().Body); err == nil && body.OpName == opName {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// messageTransaction reads the internal message like the transaction processing it.
+func messageTransaction(msg *tlb.InternalMessage) *wrap.LiteTransactionWrapper {
+	tx := &tlb.Transaction{}
+	tx.IO.In = &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: msg}
+
+	return &wrap.LiteTransactionWrapper{Transaction: tx}
+}
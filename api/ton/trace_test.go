@@ -0,0 +1,390 @@
+package ton_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton"
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tl"
+	"github.com/xssnick/tonutils-go/tlb"
+	_ton "github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+// fakeChain answers the liteserver calls of the tracker from the transactions added to it. The state of an
+// account in a shard block is the one after its transactions up to the lt of the block lookup.
+type fakeChain struct {
+	_ton.APIClientWrapped
+
+	// transactions of every account, oldest first
+	txs map[string][]*tlb.Transaction
+	// accounts left without state by their transactions
+	nonexistent map[string]bool
+	lt          uint64
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{txs: make(map[string][]*tlb.Transaction), nonexistent: make(map[string]bool)}
+}
+
+func accountKey(addr *address.Address) string {
+	return string(addr.Data())
+}
+
+// add adds the transaction of addr processing in and sending out, the lts follow the order of the calls.
+func (c *fakeChain) add(t *testing.T, addr *address.Address, in *tlb.Message, out ...*tlb.InternalMessage) *tlb.Transaction {
+	t.Helper()
+
+	c.lt += 10
+	tx := &tlb.Transaction{AccountAddr: addr.Data(), LT: c.lt}
+	if list := c.txs[accountKey(addr)]; len(list) > 0 {
+		tx.PrevTxLT, tx.PrevTxHash = list[len(list)-1].LT, list[len(list)-1].Hash
+	}
+
+	hash := sha256.New()
+	hash.Write(addr.Data())
+	_ = binary.Write(hash, binary.BigEndian, tx.LT)
+	tx.Hash = hash.Sum(nil)
+
+	messages := cell.NewDict(15)
+	for i, msg := range out {
+		msg.SrcAddr = addr
+		msg.CreatedLT = tx.LT + 1 + uint64(i)
+
+		msgCell, err := tlb.ToCell(msg)
+		if err != nil {
+			t.Fatalf("ToCell() error = %v", err)
+		}
+		if err = messages.SetIntKey(big.NewInt(int64(i)), cell.BeginCell().MustStoreRef(msgCell).EndCell()); err != nil {
+			t.Fatalf("SetIntKey() error = %v", err)
+		}
+	}
+
+	tx.IO.In = in
+	if len(out) > 0 {
+		tx.IO.Out = &tlb.MessagesList{List: messages}
+	}
+	tx.TotalFees.Coins = tlb.MustFromTON("0.001")
+	tx.Description.Description = tlb.TransactionDescriptionOrdinary{
+		ComputePhase: tlb.ComputePhase{Phase: tlb.ComputePhaseVM{Success: true}},
+		ActionPhase: &tlb.ActionPhase{
+			Success:         true,
+			Valid:           true,
+			TotalActions:    uint16(len(out)),
+			MessagesCreated: uint16(len(out)),
+		},
+	}
+
+	c.txs[accountKey(addr)] = append(c.txs[accountKey(addr)], tx)
+	return tx
+}
+
+// external adds the wallet transaction of an external message, the bodies differ like their seqnos would.
+func (c *fakeChain) external(t *testing.T, addr *address.Address, out ...*tlb.InternalMessage) *tlb.Transaction {
+	body := cell.BeginCell().MustStoreUInt(c.lt, 64).EndCell()
+	return c.add(t, addr, &tlb.Message{MsgType: tlb.MsgTypeExternalIn, Msg: &tlb.ExternalMessage{DstAddr: addr, Body: body}}, out...)
+}
+
+// receive adds the transaction processing the out message of the transaction, it sends out.
+func (c *fakeChain) receive(t *testing.T, tx *tlb.Transaction, index int, out ...*tlb.InternalMessage) *tlb.Transaction {
+	t.Helper()
+
+	list, err := tx.IO.Out.ToSlice()
+	if err != nil {
+		t.Fatalf("ToSlice() error = %v", err)
+	}
+
+	msg := list[index]
+	return c.add(t, msg.AsInternal().DstAddr, &msg, out...)
+}
+
+func (c *fakeChain) Client() _ton.LiteClient {
+	return fakeLiteClient{}
+}
+
+func (c *fakeChain) CurrentMasterchainInfo(ctx context.Context) (*_ton.BlockIDExt, error) {
+	return &_ton.BlockIDExt{Workchain: address.MasterchainID, Shard: -1 << 63, SeqNo: 1}, nil
+}
+
+func (c *fakeChain) WaitForBlock(seqno uint32) _ton.APIClientWrapped {
+	return c
+}
+
+// bounce makes the transaction processing the out message of tx abort without creating the account, and sends
+// the message back to its sender.
+func (c *fakeChain) bounce(t *testing.T, tx *tlb.Transaction, index int) *tlb.Transaction {
+	t.Helper()
+
+	list, err := tx.IO.Out.ToSlice()
+	if err != nil {
+		t.Fatalf("ToSlice() error = %v", err)
+	}
+
+	msg := list[index].AsInternal()
+	back := &tlb.InternalMessage{Bounced: true, DstAddr: msg.SrcAddr, Amount: msg.Amount}
+	bounced := c.receive(t, tx, index, back)
+	bounced.Description.Description = tlb.TransactionDescriptionOrdinary{
+		Aborted:      true,
+		ComputePhase: tlb.ComputePhase{Phase: tlb.ComputePhaseSkipped{Reason: tlb.ComputeSkipReason{Type: tlb.ComputeSkipReasonNoState}}},
+	}
+	c.nonexistent[accountKey(msg.DstAddr)] = true
+
+	return bounced
+}
+
+func (c *fakeChain) GetAccount(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address) (*tlb.Account, error) {
+	if c.nonexistent[accountKey(addr)] {
+		return &tlb.Account{}, nil
+	}
+
+	list := c.txs[accountKey(addr)]
+	for i := len(list) - 1; i >= 0; i-- {
+		// shard blocks are looked up by lt, which the fake keeps as their seqno
+		if block.Workchain == address.MasterchainID || list[i].LT <= uint64(block.SeqNo) {
+			return &tlb.Account{IsActive: true, LastTxLT: list[i].LT, LastTxHash: list[i].Hash}, nil
+		}
+	}
+
+	return &tlb.Account{}, nil
+}
+
+func (c *fakeChain) ListTransactions(ctx context.Context, addr *address.Address, num uint32, lt uint64, txHash []byte) ([]*tlb.Transaction, error) {
+	list := c.txs[accountKey(addr)]
+	for i := range list {
+		if list[i].LT == lt {
+			return list[max(0, i+1-int(num)) : i+1], nil
+		}
+	}
+
+	return nil, _ton.ErrNoTransactionsWereFound
+}
+
+func (c *fakeChain) GetTransaction(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address, lt uint64) (*tlb.Transaction, error) {
+	for _, tx := range c.txs[accountKey(addr)] {
+		if tx.LT == lt {
+			return tx, nil
+		}
+	}
+
+	return nil, _ton.ErrNoTransactionsWereFound
+}
+
+type fakeLiteClient struct {
+	_ton.LiteClient
+}
+
+func (fakeLiteClient) StickyContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (fakeLiteClient) QueryLiteserver(ctx context.Context, payload tl.Serializable, result tl.Serializable) error {
+	lookup := payload.(_ton.LookupBlock)
+	*result.(*tl.Serializable) = _ton.BlockHeader{
+		ID: &_ton.BlockIDExt{Workchain: lookup.ID.Workchain, Shard: lookup.ID.Shard, SeqNo: uint32(lookup.LT)},
+	}
+	return nil
+}
+
+// internalTransfer is the message a jetton wallet sends to the jetton wallet of the recipient.
+type internalTransfer struct {
+	_               tlb.Magic        `tlb:"#178d4519"`
+	QueryID         uint64           `tlb:"## 64"`
+	Amount          tlb.Coins        `tlb:"."`
+	From            *address.Address `tlb:"addr"`
+	ResponseAddress *address.Address `tlb:"addr"`
+	ForwardAmount   tlb.Coins        `tlb:"."`
+	ForwardPayload  *cell.Cell       `tlb:"either . ^"`
+}
+
+func message(t *testing.T, to *address.Address, amount string, body any) *tlb.InternalMessage {
+	t.Helper()
+
+	msg := &tlb.InternalMessage{Bounce: true, DstAddr: to, Amount: tlb.MustFromTON(amount)}
+	switch b := body.(type) {
+	case nil:
+	case string:
+		msg.Body, _ = wallet.CreateCommentCell(b)
+	case *cell.Cell:
+		msg.Body = b
+	default:
+		c, err := tlb.ToCell(b)
+		if err != nil {
+			t.Fatalf("ToCell() error = %v", err)
+		}
+		msg.Body = c
+	}
+
+	return msg
+}
+
+func newTestAddress(seed byte) *address.Address {
+	data := make([]byte, 32)
+	data[0], data[31] = seed, seed
+	return address.NewAddress(0, 0, data)
+}
+
+func TestTraceTransfer(t *testing.T) {
+	var (
+		highload       = newTestAddress(1)
+		alice          = newTestAddress(2)
+		bob            = newTestAddress(3)
+		sender         = newTestAddress(4)
+		senderJetton   = newTestAddress(5)
+		receiver       = newTestAddress(6)
+		receiverJetton = newTestAddress(7)
+		carol          = newTestAddress(8)
+	)
+
+	chain := newFakeChain()
+
+	// a highload V3 batch goes through a message of the wallet to itself
+	batch := chain.external(t, highload, message(t, highload, "3.1", cell.BeginCell().MustStoreUInt(0xae42e5a4, 32).EndCell()))
+	payout := chain.receive(t, batch, 0, message(t, alice, "1", "first"), message(t, bob, "2", "second"))
+	chain.receive(t, payout, 0)
+	toBob := chain.receive(t, payout, 1)
+
+	// a payout older than a scan from the last transaction of the recipient reaches
+	old := chain.external(t, highload, message(t, carol, "4", "old"))
+	toCarol := chain.receive(t, old, 0)
+	for i := 0; i < 120; i++ {
+		chain.external(t, carol)
+	}
+
+	comment, _ := wallet.CreateCommentCell("order 7")
+	transfer := chain.external(t, sender, message(t, senderJetton, "0.1", jetton.TransferPayload{
+		QueryID:             1,
+		Amount:              tlb.MustFromNano(big.NewInt(500), 0),
+		Destination:         receiver,
+		ResponseDestination: sender,
+		ForwardTONAmount:    tlb.MustFromNano(big.NewInt(1), 9),
+		ForwardPayload:      comment,
+	}))
+	sent := chain.receive(t, transfer, 0, message(t, receiverJetton, "0.09", internalTransfer{
+		QueryID:         1,
+		Amount:          tlb.MustFromNano(big.NewInt(500), 0),
+		From:            sender,
+		ResponseAddress: sender,
+		ForwardAmount:   tlb.MustFromNano(big.NewInt(1), 9),
+		ForwardPayload:  comment,
+	}))
+	received := chain.receive(t, sent, 0, message(t, receiver, "0.000000001", jetton.TransferNotification{
+		QueryID:        1,
+		Amount:         tlb.MustFromNano(big.NewInt(500), 0),
+		Sender:         sender,
+		ForwardPayload: comment,
+	}), message(t, sender, "0.05", cell.BeginCell().MustStoreUInt(0xd53276db, 32).MustStoreUInt(1, 64).EndCell()))
+	notified := chain.receive(t, received, 0)
+
+	// the sender keeps transacting, more than a scan from its last transaction covers
+	for i := 0; i < 120; i++ {
+		chain.external(t, sender)
+	}
+	chain.receive(t, received, 1)
+
+	friendly := func(addr *address.Address) string {
+		return address.NewAddress(0, 0, addr.Data()).String()
+	}
+
+	tests := []struct {
+		name    string
+		account *address.Address
+		tx      *tlb.Transaction
+		to      string
+		jetton  string
+		amount  string
+		comment string
+		from    string
+		txs     int
+	}{
+		{"highload batch", highload, batch, friendly(alice), "", "1000000000", "first", friendly(highload), 4},
+		{"highload batch payout", bob, toBob, friendly(bob), "", "2000000000", "second", friendly(highload), 4},
+		{"old payout", carol, toCarol, friendly(carol), "", "4000000000", "old", friendly(highload), 2},
+		{"jetton transfer", receiver, notified, friendly(receiver), friendly(senderJetton), "500", "order 7", friendly(sender), 5},
+	}
+
+	tonApi := ton.NewTonApiV2(api.NewSignerProvider(), nil, chain, zap.NewNop())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := tonApi.TraceTransfer(context.Background(), tt.account.String(), tt.tx.LT, tt.tx.Hash)
+			if err != nil {
+				t.Fatalf("TraceTransfer() error = %v", err)
+			}
+
+			if trace.Status != ton.TransactionSucceeded {
+				t.Errorf("Status = %v, want %v", trace.Status, ton.TransactionSucceeded)
+			}
+			if trace.From != tt.from || trace.To != tt.to || trace.Jetton != tt.jetton {
+				t.Errorf("transfer from %s to %s of jetton %q, want from %s to %s of jetton %q", trace.From, trace.To, trace.Jetton, tt.from, tt.to, tt.jetton)
+			}
+			if trace.Amount != tt.amount || trace.Comment != tt.comment {
+				t.Errorf("Amount, Comment = %s, %q, want %s, %q", trace.Amount, trace.Comment, tt.amount, tt.comment)
+			}
+			if len(trace.Transactions) != tt.txs {
+				t.Errorf("traced %d transactions, want %d", len(trace.Transactions), tt.txs)
+			}
+		})
+	}
+}
+
+func TestTrackTransaction(t *testing.T) {
+	var (
+		sender  = newTestAddress(1)
+		alice   = newTestAddress(2)
+		missing = newTestAddress(3)
+		later   = newTestAddress(4)
+	)
+
+	chain := newFakeChain()
+
+	delivered := chain.external(t, sender, message(t, alice, "1", "paid"))
+	chain.receive(t, delivered, 0)
+
+	// a bounceable transfer to an account that does not exist bounces and leaves it without state
+	bounced := chain.external(t, sender, message(t, missing, "2", nil))
+	chain.receive(t, chain.bounce(t, bounced, 0), 0)
+	for i := 0; i < 3; i++ {
+		chain.external(t, sender)
+	}
+
+	sent := chain.external(t, sender, message(t, later, "3", nil))
+
+	tests := []struct {
+		name   string
+		tx     *tlb.Transaction
+		status ton.TransactionStatus
+		txs    int
+	}{
+		{"delivered", delivered, ton.TransactionSucceeded, 2},
+		{"bounced by an account that does not exist", bounced, ton.TransactionFailed, 3},
+		{"not delivered yet", sent, ton.TransactionPending, 1},
+	}
+
+	tonApi := ton.NewTonApiV2(api.NewSignerProvider(), nil, chain, zap.NewNop())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := wrap.NormalizedExtMessageHash(tt.tx.IO.In.AsExternalIn())
+			result, err := tonApi.TrackTransaction(context.Background(), sender.String(), hash)
+			if err != nil {
+				t.Fatalf("TrackTransaction() error = %v", err)
+			}
+
+			if result.Status != tt.status {
+				t.Errorf("Status = %v, want %v", result.Status, tt.status)
+			}
+			if len(result.Transactions) != tt.txs {
+				t.Errorf("traced %d transactions, want %d", len(result.Transactions), tt.txs)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package ton
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	wrap "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tl"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"go.uber.org/zap"
+)
+
+const (
+	// hops followed from the wallet transaction, a jetton transfer takes four: sender jetton wallet,
+	// recipient jetton wallet, then the transfer notification and the excesses
+	maxTraceDepth        = 8
+	maxTraceTransactions = 64
+	// transactions of an account scanned for the one processing a message
+	traceTxScanLimit = 100
+)
+
+type TransactionStatus int
+
+const (
+	// the external message or a message of its chain is not processed yet
+	TransactionPending TransactionStatus = iota
+	TransactionSucceeded
+	// the wallet transaction was aborted or skipped sending its messages, or a transaction processing a
+	// bounceable message of the chain was aborted
+	TransactionFailed
+)
+
+// TraceTransaction is a transaction of the chain started by an external message.
+type TraceTransaction struct {
+	Address string
+	Hash    []byte
+	Lt      uint64
+	// changes of the transaction were rolled back, a bounceable message bounces back to its sender
+	Aborted bool
+	// the transaction processes a message bounced back by a failed transaction
+	Bounce bool
+
+	addr *address.Address
+	tx   *tlb.Transaction
+	// transaction that sent the in message, nil for the wallet transaction
+	parent *TraceTransaction
+}
+
+type TransactionResult struct {
+	Status TransactionStatus
+	// transaction of the wallet processing the external message, nil until it is found
+	Transaction *TraceTransaction
+	// transactions of the chain found so far, the wallet transaction first
+	Transactions []*TraceTransaction
+}
+
+// TrackTransaction finds the wallet transaction processing the external message with the normalized hash
+// msgHash, as returned by Transfer and PrepareTransaction, and follows the messages it sent, through jetton
+// wallets and bounces, to tell whether the transfer finally succeeded.
+func (a *TonApiV2) TrackTransaction(ctx context.Context, walletAddress string, msgHash []byte) (*TransactionResult, error) {
+	// route all requests to the same node
+	ctx = a.lclient.Client().StickyContext(ctx)
+
+	addr, err := address.ParseAddr(walletAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	tx, err := a.findTransactionByExtMessageHash(ctx, addr, msgHash)
+	if err != nil {
+		if errors.Is(err, ton.ErrTxWasNotFound) {
+			return &TransactionResult{Status: TransactionPending}, nil
+		}
+
+		return nil, err
+	}
+
+	return a.followTransaction(ctx, addr, tx)
+}
+
+// followTransaction follows the messages the transaction of addr sent, through jetton wallets and bounces,
+// and tells whether the chain they start finally succeeded. Known transactions of the chain are used
+// instead of looking for them.
+func (a *TonApiV2) followTransaction(ctx context.Context, addr *address.Address, tx *tlb.Transaction, known ...*tlb.Transaction) (*TransactionResult, error) {
+	result := &TransactionResult{}
+
+	type hop struct {
+		tx     *tlb.Transaction
+		addr   *address.Address
+		depth  int
+		parent *TraceTransaction
+	}
+
+	var failed, pending bool
+	queue := []hop{{tx, addr, 0, nil}}
+	for len(queue) > 0 && len(result.Transactions) < maxTraceTransactions {
+		current := queue[0]
+		queue = queue[1:]
+
+		traced := newTraceTransaction(current.addr, current.tx)
+		traced.parent = current.parent
+		result.Transactions = append(result.Transactions, traced)
+
+		var bounceable bool
+		if in := current.tx.IO.In; in != nil && in.MsgType == tlb.MsgTypeInternal {
+			bounceable = in.AsInternal().Bounce
+		}
+
+		// the wallet transaction fails on its own, others only when the sender gets its message back
+		if traced.Bounce || (traced.Aborted && bounceable) || (current.depth == 0 && walletFailed(current.tx)) {
+			failed = true
+		}
+
+		if current.tx.IO.Out == nil || current.depth >= maxTraceDepth {
+			continue
+		}
+
+		list, err := current.tx.IO.Out.ToSlice()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list out messages: %w", err)
+		}
+
+		for _, m := range list {
+			if m.MsgType != tlb.MsgTypeInternal {
+				continue
+			}
+
+			msg := m.AsInternal()
+			next := knownTransaction(known, msg)
+			if next == nil {
+				next, err = a.findTransactionByInMessage(ctx, msg)
+				if err != nil {
+					if !errors.Is(err, ton.ErrTxWasNotFound) {
+						return nil, err
+					}
+
+					pending = true
+					continue
+				}
+			}
+
+			queue = append(queue, hop{next, msg.DstAddr, current.depth + 1, traced})
+		}
+	}
+
+	result.Transaction = result.Transactions[0]
+	switch {
+	case failed:
+		result.Status = TransactionFailed
+	case pending:
+		result.Status = TransactionPending
+	default:
+		result.Status = TransactionSucceeded
+	}
+
+	return result, nil
+}
+
+// knownTransaction returns the known transaction processing the internal message, nil when none does.
+func knownTransaction(known []*tlb.Transaction, msg *tlb.InternalMessage) *tlb.Transaction {
+	for _, tx := range known {
+		if processes(tx, msg) {
+			return tx
+		}
+	}
+
+	return nil
+}
+
+// walletFailed tells whether the wallet transaction did not send what it was asked to. Wallets send with the
+// ignore errors mode, a transfer they cannot pay for commits with its action skipped and no message sent.
+func walletFailed(tx *tlb.Transaction) bool {
+	status, err := (&wrap.LiteTransactionWrapper{Transaction: tx}).GetStatus()
+	if err != nil || status.Outcome != wrap.TransactionOutcomeSuccess {
+		return true
+	}
+
+	description := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	phase := description.ActionPhase
+
+	return phase != nil && phase.TotalActions > 0 && phase.MessagesCreated == 0
+}
+
+// findTransactionByExtMessageHash finds the transaction of addr processing the external message with
+// the normalized hash.
+func (a *TonApiV2) findTransactionByExtMessageHash(ctx context.Context, addr *address.Address, msgHash []byte) (*tlb.Transaction, error) {
+	return a.findTransaction(ctx, addr, 0, func(tx *tlb.Transaction) bool {
+		in := tx.IO.In
+		return in != nil && in.MsgType == tlb.MsgTypeExternalIn &&
+			bytes.Equal(wrap.NormalizedExtMessageHash(in.AsExternalIn()), msgHash)
+	})
+}
+
+// findTransactionByInMessage finds the transaction of the destination processing the internal message.
+func (a *TonApiV2) findTransactionByInMessage(ctx context.Context, msg *tlb.InternalMessage) (*tlb.Transaction, error) {
+	// the transaction processing the message comes after it was created
+	tx, err := a.findTransaction(ctx, msg.DstAddr, msg.CreatedLT, func(tx *tlb.Transaction) bool {
+		return processes(tx, msg)
+	})
+	if errors.Is(err, errNoAccountState) && msg.Bounce {
+		// a bounceable message to an account that does not exist leaves it without state, the transaction
+		// is found from the bounce it sent back
+		return a.findTransactionByBounce(ctx, msg)
+	}
+
+	return tx, err
+}
+
+// findTransactionByBounce finds the transaction of the destination that bounced the internal message back to
+// its sender. The bounce is the only message of the transaction, it is created right after it.
+func (a *TonApiV2) findTransactionByBounce(ctx context.Context, msg *tlb.InternalMessage) (*tlb.Transaction, error) {
+	var found *tlb.Transaction
+	var lookupErr error
+	_, err := a.findTransaction(ctx, msg.SrcAddr, msg.CreatedLT, func(tx *tlb.Transaction) bool {
+		in := tx.IO.In
+		if in == nil || in.MsgType != tlb.MsgTypeInternal {
+			return false
+		}
+
+		bounce := in.AsInternal()
+		if !bounce.Bounced || !bounce.SrcAddr.Equals(msg.DstAddr) || bounce.CreatedLT <= msg.CreatedLT+1 {
+			return false
+		}
+
+		bounced, err := a.transactionAt(ctx, msg.DstAddr, bounce.CreatedLT-1)
+		if err != nil {
+			lookupErr = err
+			return true
+		}
+
+		if !processes(bounced, msg) {
+			return false
+		}
+
+		found = bounced
+		return true
+	})
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// processes tells whether the transaction processes the internal message, it is identified by its sender and
+// creation lt, which are unique unlike the hash of its body.
+func processes(tx *tlb.Transaction, msg *tlb.InternalMessage) bool {
+	in := tx.IO.In
+	if in == nil || in.MsgType != tlb.MsgTypeInternal {
+		return false
+	}
+
+	inMsg := in.AsInternal()
+	return inMsg.CreatedLT == msg.CreatedLT && inMsg.SrcAddr.Equals(msg.SrcAddr)
+}
+
+// transactionAt reads the transaction of addr with the logical time lt from its shard block.
+func (a *TonApiV2) transactionAt(ctx context.Context, addr *address.Address, lt uint64) (*tlb.Transaction, error) {
+	block, err := a.lookupBlockByLt(ctx, addr, lt)
+	if err != nil {
+		a.logger.Error("lookup block failed", zap.Error(err), zap.String("address", addr.String()))
+		return nil, err
+	}
+
+	tx, err := a.lclient.GetTransaction(ctx, block, addr, lt)
+	if err != nil {
+		a.logger.Error("get transaction failed", zap.Error(err), zap.String("address", addr.String()))
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// errNoAccountState is returned when the account has no state to scan the transactions from, it does not
+// exist yet or was deleted.
+var errNoAccountState = fmt.Errorf("account has no state: %w", ton.ErrTxWasNotFound)
+
+// findTransaction walks the transactions of addr newer than afterLt, newest first, and returns the first
+// one matching, or ton.ErrTxWasNotFound when none of the last traceTxScanLimit transactions does.
+func (a *TonApiV2) findTransaction(ctx context.Context, addr *address.Address, afterLt uint64, match func(tx *tlb.Transaction) bool) (*tlb.Transaction, error) {
+	block, err := a.lclient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		a.logger.Error("get master block failed", zap.Error(err))
+		return nil, err
+	}
+
+	account, err := a.lclient.WaitForBlock(block.SeqNo).GetAccount(ctx, block, addr)
+	if err != nil {
+		a.logger.Error("get account failed", zap.Error(err), zap.String("address", addr.String()))
+		return nil, err
+	}
+
+	if !account.IsActive {
+		return nil, errNoAccountState
+	}
+
+	return a.scanTransactions(ctx, addr, account.LastTxLT, account.LastTxHash, afterLt, match)
+}
+
+// scanTransactions walks the transactions of addr from the one with lt and hash back to afterLt, newest
+// first, and returns the first one matching.
+func (a *TonApiV2) scanTransactions(ctx context.Context, addr *address.Address, lt uint64, hash []byte, afterLt uint64, match func(tx *tlb.Transaction) bool) (*tlb.Transaction, error) {
+	scanned := 0
+	for lt > afterLt && scanned < traceTxScanLimit {
+		list, err := a.lclient.ListTransactions(ctx, addr, 15, lt, hash)
+		if err != nil {
+			if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+				return nil, ton.ErrTxWasNotFound
+			}
+
+			a.logger.Error("ListTransactions failed", zap.Error(err), zap.String("address", addr.String()))
+			return nil, err
+		}
+
+		if len(list) == 0 {
+			break
+		}
+
+		// the oldest transaction is first
+		for i := len(list) - 1; i >= 0; i-- {
+			if list[i].LT <= afterLt {
+				return nil, ton.ErrTxWasNotFound
+			}
+
+			if match(list[i]) {
+				return list[i], nil
+			}
+		}
+
+		scanned += len(list)
+		lt, hash = list[0].PrevTxLT, list[0].PrevTxHash
+	}
+
+	return nil, ton.ErrTxWasNotFound
+}
+
+// lookupBlockByLt finds the shard block of addr holding the logical time lt.
+func (a *TonApiV2) lookupBlockByLt(ctx context.Context, addr *address.Address, lt uint64) (*ton.BlockIDExt, error) {
+	var resp tl.Serializable
+	err := a.lclient.Client().QueryLiteserver(ctx, ton.LookupBlock{
+		Mode: 2,
+		// the liteserver picks the shard holding the account prefix
+		ID: &ton.BlockInfoShort{
+			Workchain: addr.Workchain(),
+			Shard:     int64(binary.BigEndian.Uint64(addr.Data())),
+		},
+		LT: lt,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := resp.(type) {
+	case ton.BlockHeader:
+		return t.ID, nil
+	case ton.LSError:
+		return nil, t
+	}
+
+	return nil, fmt.Errorf("unexpected response %T to block lookup", resp)
+}
+
+func newTraceTransaction(addr *address.Address, tx *tlb.Transaction) *TraceTransaction {
+	traced := &TraceTransaction{
+		Address: addr.String(),
+		Hash:    tx.Hash,
+		Lt:      tx.LT,
+		addr:    addr,
+		tx:      tx,
+	}
+
+	if description, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary); ok {
+		traced.Aborted = description.Aborted
+	}
+
+	if in := tx.IO.In; in != nil && in.MsgType == tlb.MsgTypeInternal {
+		traced.Bounce = in.AsInternal().Bounced
+	}
+
+	return traced
+}
@@ -57,11 +57,15 @@ func GetStateInit(pubKey ed25519.PublicKey, version VersionConfig, subWallet uin
 			return nil, fmt.Errorf("use ConfigHighloadV3 for highload v3 spec")
 		case V5R1:
 			return nil, fmt.Errorf("use ConfigV5R1 for v5 spec")
+		case Lockup:
+			return nil, fmt.Errorf("use ConfigLockup for lockup spec")
 		}
 	case ConfigHighloadV3:
 		ver = HighloadV3
 	case ConfigV5R1:
 		ver = V5R1
+	case ConfigLockup:
+		ver = Lockup
 	}
 
 	code, ok := walletCode[ver]
@@ -115,6 +119,12 @@ func GetStateInit(pubKey ed25519.PublicKey, version VersionConfig, subWallet uin
 			MustStoreUInt(0, 66).
 			MustStoreUInt(uint64(timeout), 22).
 			EndCell()
+	case Lockup:
+		var err error
+		data, err = version.(ConfigLockup).data(pubKey, subWallet)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, ErrUnsupportedWalletVersion
 	}
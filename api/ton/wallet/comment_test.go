@@ -0,0 +1,61 @@
+package wallet_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/adnl"
+)
+
+type testSigner struct {
+	key ed25519.PrivateKey
+}
+
+func (s *testSigner) PublicKey(context.Context) ([]byte, error) {
+	return append([]byte{}, s.key.Public().(ed25519.PublicKey)...), nil
+}
+
+func (s *testSigner) SharedKey(theirKey []byte) ([]byte, error) {
+	return adnl.SharedKey(s.key, theirKey)
+}
+
+func (s *testSigner) Sign(_ context.Context, payload []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, payload), nil
+}
+
+func TestDecryptCommentCellWithSigner(t *testing.T) {
+	ctx := context.Background()
+
+	_, senderKey, _ := ed25519.GenerateKey(nil)
+	_, receiverKey, _ := ed25519.GenerateKey(nil)
+	sender := &testSigner{senderKey}
+	receiver := &testSigner{receiverKey}
+	senderAddr := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+
+	tests := []struct {
+		name string
+		salt *address.Address
+	}{
+		{name: "bounceable", salt: senderAddr.Bounce(true)},
+		{name: "non-bounceable", salt: senderAddr.Bounce(false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := wallet.CreateEncryptedCommentCell(ctx, "deposit 42", tt.salt, sender, receiverKey.Public().(ed25519.PublicKey))
+			if err != nil {
+				t.Fatalf("CreateEncryptedCommentCell() error = %v", err)
+			}
+
+			comment, err := wallet.DecryptCommentCellWithSigner(ctx, body, senderAddr, receiver, senderKey.Public().(ed25519.PublicKey))
+			if err != nil {
+				t.Fatalf("DecryptCommentCellWithSigner() error = %v", err)
+			}
+			if string(comment) != "deposit 42" {
+				t.Errorf("comment = %q, want %q", comment, "deposit 42")
+			}
+		})
+	}
+}
@@ -1,4 +1,265 @@
 package wallet
 
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
 // https://github.com/toncenter/tonweb/blob/master/src/contract/wallet/WalletSources.md#lockup-wallet
 const _LockupCodeHex = "B5EE9C7241021E01000261000114FF00F4A413F4BCF2C80B010201200203020148040501F2F28308D71820D31FD31FD31F802403F823BB13F2F2F003802251A9BA1AF2F4802351B7BA1BF2F4801F0BF9015410C5F9101AF2F4F8005057F823F0065098F823F0062071289320D74A8E8BD30731D4511BDB3C12B001E8309229A0DF72FB02069320D74A96D307D402FB00E8D103A4476814154330F004ED541D0202CD0607020120131402012008090201200F100201200A0B002D5ED44D0D31FD31FD3FFD3FFF404FA00F404FA00F404D1803F7007434C0C05C6C2497C0F83E900C0871C02497C0F80074C7C87040A497C1383C00D46D3C00608420BABE7114AC2F6C2497C338200A208420BABE7106EE86BCBD20084AE0840EE6B2802FBCBD01E0C235C62008087E4055040DBE4404BCBD34C7E00A60840DCEAA7D04EE84BCBD34C034C7CC0078C3C412040DD78CA00C0D0E00130875D27D2A1BE95B0C60000C1039480AF00500161037410AF0050810575056001010244300F004ED540201201112004548E1E228020F4966FA520933023BB9131E2209835FA00D113A14013926C21E2B3E6308003502323287C5F287C572FFC4F2FFFD00007E80BD00007E80BD00326000431448A814C4E0083D039BE865BE803444E800A44C38B21400FE809004E0083D10C06002012015160015BDE9F780188242F847800C02012017180201481B1C002DB5187E006D88868A82609E00C6207E00C63F04EDE20B30020158191A0017ADCE76A268699F98EB85FFC00017AC78F6A268698F98EB858FC00011B325FB513435C2C7E00017B1D1BE08E0804230FB50F620002801D0D3030178B0925B7FE0FA4031FA403001F001A80EDAA4"
+
+// LockupFunds are funds of a lockup wallet that are released at the unlock time.
+type LockupFunds struct {
+	// unix time the funds are released at
+	UnlockTime uint32
+	Amount     tlb.Coins
+}
+
+// ConfigLockup is the initial state of a lockup wallet holding a vesting allocation, the address of the
+// wallet depends on it.
+type ConfigLockup struct {
+	// key of the party allowed to lock more funds in the wallet
+	ConfigPublicKey ed25519.PublicKey
+	// addresses restricted funds can be sent to before they are released
+	AllowedDestinations []*address.Address
+	// funds that cannot be sent anywhere before they are released
+	Locked []LockupFunds
+	// funds that can only be sent to the allowed destinations before they are released
+	Restricted []LockupFunds
+}
+
+// SpecLockup sends messages like a V3 wallet, the contract refuses to spend locked funds and spends
+// restricted funds only on the allowed destinations.
+type SpecLockup struct {
+	SpecV3
+
+	config ConfigLockup
+}
+
+// LockupBalances are the balances of a lockup wallet returned by get_balances.
+type LockupBalances struct {
+	Total      tlb.Coins
+	Restricted tlb.Coins
+	Locked     tlb.Coins
+	// funds the wallet can send anywhere
+	Liquid tlb.Coins
+}
+
+// Config returns the config the wallet was created with.
+func (s *SpecLockup) Config() ConfigLockup {
+	return s.config
+}
+
+// IsAllowedDestination reports whether restricted funds can be sent to addr.
+func (s *SpecLockup) IsAllowedDestination(addr *address.Address) bool {
+	for _, dst := range s.config.AllowedDestinations {
+		if dst.Equals(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBalances returns the balances of the wallet.
+func (s *SpecLockup) GetBalances(ctx context.Context) (*LockupBalances, error) {
+	return GetLockupBalances(ctx, s.wallet.api, s.wallet.addr)
+}
+
+// GetLockupBalances returns the balances of the lockup wallet at addr, funds past their unlock time
+// are counted as liquid even when the wallet has not released them yet.
+func GetLockupBalances(ctx context.Context, api TonAPI, addr *address.Address) (*LockupBalances, error) {
+	block, err := api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+
+	res, err := api.WaitForBlock(block.SeqNo).RunGetMethod(ctx, block, addr, "get_balances")
+	if err != nil {
+		return nil, fmt.Errorf("get balances err: %w", err)
+	}
+
+	total, err := res.Int(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse balance: %w", err)
+	}
+
+	restricted, err := res.Int(1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse restricted balance: %w", err)
+	}
+
+	locked, err := res.Int(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse locked balance: %w", err)
+	}
+
+	liquid := new(big.Int).Sub(total, restricted)
+	liquid.Sub(liquid, locked)
+	if liquid.Sign() < 0 {
+		liquid.SetInt64(0)
+	}
+
+	return &LockupBalances{
+		Total:      tlb.FromNanoTON(total),
+		Restricted: tlb.FromNanoTON(restricted),
+		Locked:     tlb.FromNanoTON(locked),
+		Liquid:     tlb.FromNanoTON(liquid),
+	}, nil
+}
+
+func (c ConfigLockup) data(pubKey ed25519.PublicKey, subWallet uint32) (*cell.Cell, error) {
+	if len(c.ConfigPublicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("lockup config public key is required")
+	}
+
+	destinations, err := packLockupDestinations(c.AllowedDestinations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack allowed destinations: %w", err)
+	}
+
+	lockedTotal, locked, err := packLockupFunds(c.Locked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack locked funds: %w", err)
+	}
+
+	restrictedTotal, restricted, err := packLockupFunds(c.Restricted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack restricted funds: %w", err)
+	}
+
+	return cell.BeginCell().
+		MustStoreUInt(0, 32). // seqno
+		MustStoreUInt(uint64(subWallet), 32).
+		MustStoreSlice(pubKey, 256).
+		MustStoreSlice(c.ConfigPublicKey, 256).
+		MustStoreMaybeRef(destinations).
+		MustStoreBigCoins(lockedTotal).
+		MustStoreDict(locked).
+		MustStoreBigCoins(restrictedTotal).
+		MustStoreDict(restricted).
+		EndCell(), nil
+}
+
+// packLockupFunds returns the total of the funds and the dict of the amounts released at each unlock time.
+func packLockupFunds(funds []LockupFunds) (*big.Int, *cell.Dictionary, error) {
+	amounts := map[uint32]*big.Int{}
+	total := new(big.Int)
+	for _, f := range funds {
+		amount := f.Amount.Nano()
+		if amount.Sign() <= 0 {
+			continue
+		}
+
+		if amounts[f.UnlockTime] == nil {
+			amounts[f.UnlockTime] = new(big.Int)
+		}
+		amounts[f.UnlockTime].Add(amounts[f.UnlockTime], amount)
+		total.Add(total, amount)
+	}
+
+	if len(amounts) == 0 {
+		return total, nil, nil
+	}
+
+	dict := cell.NewDict(32)
+	for unlockTime, amount := range amounts {
+		err := dict.SetIntKey(big.NewInt(int64(unlockTime)), cell.BeginCell().MustStoreBigCoins(amount).EndCell())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return total, dict, nil
+}
+
+// packLockupDestinations returns the prefix dictionary the contract looks destinations up in, its keys
+// are the serialized addresses and its values are empty.
+func packLockupDestinations(destinations []*address.Address) (*cell.Cell, error) {
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(destinations))
+	seen := map[string]bool{}
+	for _, dst := range destinations {
+		if dst == nil || dst.Type() != address.StdAddress {
+			return nil, errors.New("allowed destinations should be standard addresses")
+		}
+
+		key := addressBits(dst)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return packPfxDictNode(keys, len(keys[0])), nil
+}
+
+// packPfxDictNode packs the node of a prefix dictionary holding the keys, all of the same length n:
+// a label with the common prefix followed by a leaf, or by a fork splitting the keys on their next bit.
+func packPfxDictNode(keys []string, n int) *cell.Cell {
+	prefix := keys[0]
+	for _, key := range keys[1:] {
+		i := 0
+		for i < len(prefix) && prefix[i] == key[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+
+	b := cell.BeginCell()
+
+	// hml_long$10 n:(#<= m) s:(n * Bit)
+	b.MustStoreUInt(0b10, 2)
+	b.MustStoreUInt(uint64(len(prefix)), uint(bits.Len(uint(n))))
+	for _, bit := range prefix {
+		b.MustStoreBoolBit(bit == '1')
+	}
+
+	if len(prefix) == n {
+		// leaf$0 value:X, the value is empty
+		return b.MustStoreBoolBit(false).EndCell()
+	}
+
+	var left, right []string
+	for _, key := range keys {
+		if key[len(prefix)] == '0' {
+			left = append(left, key[len(prefix)+1:])
+		} else {
+			right = append(right, key[len(prefix)+1:])
+		}
+	}
+
+	// fork$1 left:^(PfxHashmap n-1 X) right:^(PfxHashmap n-1 X)
+	m := n - len(prefix) - 1
+	return b.MustStoreBoolBit(true).
+		MustStoreRef(packPfxDictNode(left, m)).
+		MustStoreRef(packPfxDictNode(right, m)).
+		EndCell()
+}
+
+// addressBits returns the bits of the serialized address as a string of 0 and 1.
+func addressBits(addr *address.Address) string {
+	c := cell.BeginCell().MustStoreAddr(addr).EndCell()
+	data := c.BeginParse().MustLoadSlice(c.BitsSize())
+
+	var sb strings.Builder
+	for i := uint(0); i < c.BitsSize(); i++ {
+		if data[i/8]&(0x80>>(i%8)) != 0 {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package wallet_test
+
+import (
+	"crypto/ed25519"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+func TestGetStateInit_Lockup(t *testing.T) {
+	key := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	configKey := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	configKey[0] = 1
+
+	config := wallet.ConfigLockup{
+		ConfigPublicKey: configKey,
+		AllowedDestinations: []*address.Address{
+			address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N"),
+			address.MustParseAddr("EQBvW8Z5huBkMJYdnfAEM5JqTNkuWX3diqYENkWsIL0XggGG"),
+		},
+		Locked: []wallet.LockupFunds{
+			{UnlockTime: 1700000000, Amount: tlb.MustFromTON("1")},
+			{UnlockTime: 1700000000, Amount: tlb.MustFromTON("2")},
+			{UnlockTime: 1800000000, Amount: tlb.MustFromTON("4")},
+		},
+		Restricted: []wallet.LockupFunds{
+			{UnlockTime: 1750000000, Amount: tlb.MustFromTON("8")},
+		},
+	}
+
+	state, err := wallet.GetStateInit(key, config, wallet.DefaultSubwallet)
+	if err != nil {
+		t.Fatalf("GetStateInit() error = %v", err)
+	}
+
+	data := state.Data.BeginParse()
+	data.MustLoadUInt(32) // seqno
+	if subwallet := data.MustLoadUInt(32); subwallet != wallet.DefaultSubwallet {
+		t.Errorf("wallet id = %d, want %d", subwallet, wallet.DefaultSubwallet)
+	}
+	data.MustLoadSlice(256) // public key
+	if got := data.MustLoadSlice(256); got[0] != 1 {
+		t.Errorf("config public key = %x, want %x", got, []byte(configKey))
+	}
+	if destinations := data.MustLoadMaybeRef(); destinations == nil {
+		t.Errorf("allowed destinations are empty")
+	}
+
+	tests := []struct {
+		name    string
+		total   *big.Int
+		amounts map[int64]*big.Int
+	}{
+		{
+			name:  "locked",
+			total: tlb.MustFromTON("7").Nano(),
+			amounts: map[int64]*big.Int{
+				1700000000: tlb.MustFromTON("3").Nano(),
+				1800000000: tlb.MustFromTON("4").Nano(),
+			},
+		},
+		{
+			name:  "restricted",
+			total: tlb.MustFromTON("8").Nano(),
+			amounts: map[int64]*big.Int{
+				1750000000: tlb.MustFromTON("8").Nano(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		if total := data.MustLoadBigCoins(); total.Cmp(tt.total) != 0 {
+			t.Errorf("%s total = %s, want %s", tt.name, total, tt.total)
+		}
+
+		dict := data.MustLoadDict(32)
+		if dict.Size() != len(tt.amounts) {
+			t.Errorf("%s unlock times = %d, want %d", tt.name, dict.Size(), len(tt.amounts))
+		}
+		for unlockTime, want := range tt.amounts {
+			value, err := dict.LoadValueByIntKey(big.NewInt(unlockTime))
+			if err != nil {
+				t.Errorf("%s funds at %d: %v", tt.name, unlockTime, err)
+				continue
+			}
+			if got := value.MustLoadBigCoins(); got.Cmp(want) != 0 {
+				t.Errorf("%s funds at %d = %s, want %s", tt.name, unlockTime, got, want)
+			}
+		}
+	}
+
+	if _, err = wallet.GetStateInit(key, wallet.Lockup, wallet.DefaultSubwallet); err == nil {
+		t.Errorf("GetStateInit() without config should fail")
+	}
+}
@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// first payment of a subscription, the plugin handles it as the response to a payment request
+const subscriptionDeployOp = 0xf06c7567
+
+// SubscriptionConfig is the data of a subscription plugin of a V4 wallet, the plugin requests the amount
+// from the wallet every period and sends it to the beneficiary.
+type SubscriptionConfig struct {
+	// code of the subscription plugin
+	Code        *cell.Cell
+	Beneficiary *address.Address
+	Amount      tlb.Coins
+	// seconds between payments
+	Period    uint32
+	StartTime uint32
+	// seconds the plugin waits for a payment it requested before it retries
+	Timeout        uint32
+	SubscriptionID uint32
+}
+
+// StateInit returns the state init of the subscription plugin of the wallet.
+func (c SubscriptionConfig) StateInit(wallet *address.Address) (*tlb.StateInit, error) {
+	if c.Code == nil {
+		return nil, errors.New("subscription plugin code is required")
+	}
+
+	if c.Beneficiary == nil {
+		return nil, errors.New("subscription beneficiary is required")
+	}
+
+	data := cell.BeginCell().
+		MustStoreAddr(wallet).
+		MustStoreAddr(c.Beneficiary).
+		MustStoreBigCoins(c.Amount.Nano()).
+		MustStoreUInt(uint64(c.Period), 32).
+		MustStoreUInt(uint64(c.StartTime), 32).
+		MustStoreUInt(uint64(c.Timeout), 32).
+		MustStoreUInt(0, 32). // last payment time
+		MustStoreUInt(0, 32). // last request time
+		MustStoreUInt(0, 8).  // failed attempts
+		MustStoreUInt(uint64(c.SubscriptionID), 32).
+		EndCell()
+
+	return &tlb.StateInit{
+		Code: c.Code,
+		Data: data,
+	}, nil
+}
+
+// SubscriptionDeployBody returns the body of the message deploying a subscription plugin, the TON it carries
+// is the first payment.
+func SubscriptionDeployBody() *cell.Cell {
+	return cell.BeginCell().MustStoreUInt(subscriptionDeployOp, 32).EndCell()
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
+	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
 
@@ -23,6 +25,14 @@ type SpecV4R2 struct {
 	SpecSeqno
 }
 
+const (
+	// operations of a V4 request, plugins are contracts allowed to request funds from the wallet
+	v4OpSend                   = 0
+	v4OpDeployAndInstallPlugin = 1
+	v4OpInstallPlugin          = 2
+	v4OpRemovePlugin           = 3
+)
+
 func (s *SpecV4R2) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt, messages []*Message) (_ *cell.Cell, err error) {
 	// TODO: remove block, now it is here for backwards compatibility
 
@@ -30,6 +40,67 @@ func (s *SpecV4R2) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt,
 		return nil, errors.New("for this type of wallet max 4 messages can be sent in the same time")
 	}
 
+	return s.buildRequest(ctx, v4OpSend, func(payload *cell.Builder) error {
+		for i, message := range messages {
+			intMsg, err := tlb.ToCell(message.InternalMessage)
+			if err != nil {
+				return fmt.Errorf("failed to convert internal message %d to cell: %w", i, err)
+			}
+
+			payload.MustStoreUInt(uint64(message.Mode), 8).MustStoreRef(intMsg)
+		}
+		return nil
+	})
+}
+
+// BuildDeployPluginMessage builds the body of a request deploying the plugin with the state init and
+// installing it, the wallet sends it the amount with the body.
+func (s *SpecV4R2) BuildDeployPluginMessage(ctx context.Context, workchain int8, amount tlb.Coins, stateInit *tlb.StateInit, body *cell.Cell) (*cell.Cell, error) {
+	stateInitCell, err := tlb.ToCell(stateInit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert state init to cell: %w", err)
+	}
+
+	if body == nil {
+		body = cell.BeginCell().EndCell()
+	}
+
+	return s.buildRequest(ctx, v4OpDeployAndInstallPlugin, func(payload *cell.Builder) error {
+		payload.MustStoreInt(int64(workchain), 8).
+			MustStoreBigCoins(amount.Nano()).
+			MustStoreRef(stateInitCell).
+			MustStoreRef(body)
+		return nil
+	})
+}
+
+// BuildInstallPluginMessage builds the body of a request installing the deployed plugin, the wallet
+// notifies the plugin sending it the amount.
+func (s *SpecV4R2) BuildInstallPluginMessage(ctx context.Context, plugin *address.Address, amount tlb.Coins, queryID uint64) (*cell.Cell, error) {
+	return s.buildPluginRequest(ctx, v4OpInstallPlugin, plugin, amount, queryID)
+}
+
+// BuildRemovePluginMessage builds the body of a request removing the plugin, the wallet notifies the
+// plugin sending it the amount.
+func (s *SpecV4R2) BuildRemovePluginMessage(ctx context.Context, plugin *address.Address, amount tlb.Coins, queryID uint64) (*cell.Cell, error) {
+	return s.buildPluginRequest(ctx, v4OpRemovePlugin, plugin, amount, queryID)
+}
+
+func (s *SpecV4R2) buildPluginRequest(ctx context.Context, op uint64, plugin *address.Address, amount tlb.Coins, queryID uint64) (*cell.Cell, error) {
+	if plugin == nil || plugin.Type() != address.StdAddress {
+		return nil, errors.New("plugin address should be a standard address")
+	}
+
+	return s.buildRequest(ctx, op, func(payload *cell.Builder) error {
+		payload.MustStoreInt(int64(plugin.Workchain()), 8).
+			MustStoreSlice(plugin.Data(), 256).
+			MustStoreBigCoins(amount.Nano()).
+			MustStoreUInt(queryID, 64)
+		return nil
+	})
+}
+
+func (s *SpecV4R2) buildRequest(ctx context.Context, op uint64, storeBody func(payload *cell.Builder) error) (_ *cell.Cell, err error) {
 	seq, err := s.seqnoFetcher(ctx, s.wallet.subwallet)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch seqno: %w", err)
@@ -38,15 +109,10 @@ func (s *SpecV4R2) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt,
 	payload := cell.BeginCell().MustStoreUInt(uint64(s.wallet.subwallet), 32).
 		MustStoreUInt(uint64(timeNow().Add(time.Duration(s.messagesTTL)*time.Second).UTC().Unix()), 32).
 		MustStoreUInt(uint64(seq), 32).
-		MustStoreInt(0, 8) // op
-
-	for i, message := range messages {
-		intMsg, err := tlb.ToCell(message.InternalMessage)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert internal message %d to cell: %w", i, err)
-		}
+		MustStoreUInt(op, 8)
 
-		payload.MustStoreUInt(uint64(message.Mode), 8).MustStoreRef(intMsg)
+	if err = storeBody(payload); err != nil {
+		return nil, err
 	}
 
 	var sign []byte
@@ -64,4 +130,53 @@ func (s *SpecV4R2) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt,
 	return msg, nil
 }
 
-// TODO: implement plugins
+// GetPluginList returns the plugins installed in the V4 wallet.
+func (s *SpecV4R2) GetPluginList(ctx context.Context) ([]*address.Address, error) {
+	return GetPluginList(ctx, s.wallet.api, s.wallet.addr)
+}
+
+// GetPluginList returns the plugins installed in the V4 wallet at addr, read with get_plugin_list.
+func GetPluginList(ctx context.Context, api TonAPI, addr *address.Address) ([]*address.Address, error) {
+	block, err := api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+
+	res, err := api.WaitForBlock(block.SeqNo).RunGetMethod(ctx, block, addr, "get_plugin_list")
+	if err != nil {
+		if cErr, ok := err.(ton.ContractExecError); ok && cErr.Code == ton.ErrCodeContractNotInitialized {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get plugin list err: %w", err)
+	}
+
+	if len(res.AsTuple()) == 0 {
+		return nil, nil
+	}
+
+	// the plugins are a list of [workchain, address hash] pairs, each node is a [head, tail] pair
+	var plugins []*address.Address
+	node := res.AsTuple()[0]
+	for node != nil {
+		pair, ok := node.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("unexpected plugin list node %T", node)
+		}
+
+		plugin, ok := pair[0].([]any)
+		if !ok || len(plugin) != 2 {
+			return nil, fmt.Errorf("unexpected plugin %T", pair[0])
+		}
+
+		workchain, okWc := plugin[0].(*big.Int)
+		hash, okHash := plugin[1].(*big.Int)
+		if !okWc || !okHash {
+			return nil, errors.New("unexpected plugin address")
+		}
+
+		plugins = append(plugins, address.NewAddress(0, byte(workchain.Int64()), hash.FillBytes(make([]byte, 32))))
+		node = pair[1]
+	}
+
+	return plugins, nil
+}
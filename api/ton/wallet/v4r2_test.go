@@ -0,0 +1,264 @@
+package wallet_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// loadV4Request checks the signature and the header of a V4 request and returns the slice after its op.
+func loadV4Request(t *testing.T, key ed25519.PrivateKey, body *cell.Cell, op uint64) *cell.Slice {
+	t.Helper()
+
+	s := body.BeginParse()
+	sign := s.MustLoadSlice(512)
+
+	payload, err := s.ToCell()
+	if err != nil {
+		t.Fatalf("ToCell() error = %v", err)
+	}
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), payload.Hash(), sign) {
+		t.Errorf("signature does not match the request")
+	}
+
+	if got := s.MustLoadUInt(32); got != wallet.DefaultSubwallet {
+		t.Errorf("subwallet = %d, want %d", got, wallet.DefaultSubwallet)
+	}
+	s.MustLoadUInt(32) // valid until
+	if got := s.MustLoadUInt(32); got != 5 {
+		t.Errorf("seqno = %d, want 5", got)
+	}
+	if got := s.MustLoadUInt(8); got != op {
+		t.Errorf("op = %d, want %d", got, op)
+	}
+
+	return s
+}
+
+func TestSpecV4R2PluginRequests(t *testing.T) {
+	ctx := context.Background()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	w, err := wallet.FromPrivateKey(nil, key, wallet.V4R2)
+	if err != nil {
+		t.Fatalf("FromPrivateKey() error = %v", err)
+	}
+	spec := w.GetSpec().(*wallet.SpecV4R2)
+	spec.SetCustomSeqnoFetcher(func() uint32 { return 5 })
+
+	plugin := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+
+	t.Run("deploy and install", func(t *testing.T) {
+		stateInit := &tlb.StateInit{
+			Code: cell.BeginCell().MustStoreUInt(1, 8).EndCell(),
+			Data: cell.BeginCell().MustStoreUInt(2, 8).EndCell(),
+		}
+		stateInitCell, _ := tlb.ToCell(stateInit)
+
+		body, err := spec.BuildDeployPluginMessage(ctx, -1, tlb.MustFromTON("1.05"), stateInit, wallet.SubscriptionDeployBody())
+		if err != nil {
+			t.Fatalf("BuildDeployPluginMessage() error = %v", err)
+		}
+
+		s := loadV4Request(t, key, body, 1)
+		if got := s.MustLoadInt(8); got != -1 {
+			t.Errorf("workchain = %d, want -1", got)
+		}
+		if got := s.MustLoadBigCoins(); got.Cmp(tlb.MustFromTON("1.05").Nano()) != 0 {
+			t.Errorf("amount = %s, want 1.05 TON", got)
+		}
+		if got := s.MustLoadRef().MustToCell(); string(got.Hash()) != string(stateInitCell.Hash()) {
+			t.Errorf("state init does not match")
+		}
+		if got := s.MustLoadRef().MustLoadUInt(32); got != 0xf06c7567 {
+			t.Errorf("deploy body op = %#x, want %#x", got, 0xf06c7567)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		op    uint64
+		build func(context.Context, *address.Address, tlb.Coins, uint64) (*cell.Cell, error)
+	}{
+		{"install", 2, spec.BuildInstallPluginMessage},
+		{"remove", 3, spec.BuildRemovePluginMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := tt.build(ctx, plugin, tlb.MustFromTON("0.05"), 42)
+			if err != nil {
+				t.Fatalf("build error = %v", err)
+			}
+
+			s := loadV4Request(t, key, body, tt.op)
+			if got := s.MustLoadInt(8); got != 0 {
+				t.Errorf("workchain = %d, want 0", got)
+			}
+			if got := s.MustLoadSlice(256); string(got) != string(plugin.Data()) {
+				t.Errorf("plugin = %x, want %x", got, plugin.Data())
+			}
+			if got := s.MustLoadBigCoins(); got.Cmp(tlb.MustFromTON("0.05").Nano()) != 0 {
+				t.Errorf("amount = %s, want 0.05 TON", got)
+			}
+			if got := s.MustLoadUInt(64); got != 42 {
+				t.Errorf("query id = %d, want 42", got)
+			}
+			if s.BitsLeft() != 0 || s.RefsNum() != 0 {
+				t.Errorf("%d bits and %d refs left, want none", s.BitsLeft(), s.RefsNum())
+			}
+
+			if _, err = tt.build(ctx, nil, tlb.MustFromTON("0.05"), 42); err == nil {
+				t.Errorf("build without a plugin error = nil, want an error")
+			}
+		})
+	}
+}
+
+// pluginAPI answers get_plugin_list with the result.
+type pluginAPI struct {
+	ton.APIClientWrapped
+	result []any
+	err    error
+}
+
+func (a *pluginAPI) CurrentMasterchainInfo(ctx context.Context) (*ton.BlockIDExt, error) {
+	return &ton.BlockIDExt{Workchain: address.MasterchainID, SeqNo: 1}, nil
+}
+
+func (a *pluginAPI) WaitForBlock(seqno uint32) ton.APIClientWrapped {
+	return a
+}
+
+func (a *pluginAPI) RunGetMethod(ctx context.Context, block *ton.BlockIDExt, addr *address.Address, method string, params ...any) (*ton.ExecutionResult, error) {
+	if method != "get_plugin_list" {
+		return nil, errors.New("unexpected method " + method)
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return ton.NewExecutionResult(a.result), nil
+}
+
+func TestGetPluginList(t *testing.T) {
+	first := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+	second := address.NewAddress(0, 255, first.Data())
+	owner := address.MustParseAddr("EQBvW8Z5huBkMJYdnfAEM5JqTNkuWX3diqYENkWsIL0XggGG")
+
+	pair := func(addr *address.Address) []any {
+		return []any{big.NewInt(int64(addr.Workchain())), new(big.Int).SetBytes(addr.Data())}
+	}
+
+	tests := []struct {
+		name    string
+		api     *pluginAPI
+		want    []*address.Address
+		wantErr bool
+	}{
+		{
+			name: "two plugins",
+			api:  &pluginAPI{result: []any{[]any{pair(first), []any{pair(second), nil}}}},
+			want: []*address.Address{first, second},
+		},
+		{name: "no plugins", api: &pluginAPI{result: []any{nil}}},
+		{name: "not deployed", api: &pluginAPI{err: ton.ContractExecError{Code: ton.ErrCodeContractNotInitialized}}},
+		{name: "not a list", api: &pluginAPI{result: []any{big.NewInt(1)}}, wantErr: true},
+		{name: "not an address", api: &pluginAPI{result: []any{[]any{[]any{big.NewInt(0)}, nil}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wallet.GetPluginList(context.Background(), tt.api, owner)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetPluginList() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPluginList() error = %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetPluginList() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equals(tt.want[i]) || got[i].Workchain() != tt.want[i].Workchain() {
+					t.Errorf("plugin %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionConfigStateInit(t *testing.T) {
+	owner := address.MustParseAddr("EQBvW8Z5huBkMJYdnfAEM5JqTNkuWX3diqYENkWsIL0XggGG")
+	beneficiary := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+	code := cell.BeginCell().MustStoreUInt(0xc0de, 16).EndCell()
+
+	config := wallet.SubscriptionConfig{
+		Code:           code,
+		Beneficiary:    beneficiary,
+		Amount:         tlb.MustFromTON("1"),
+		Period:         2_592_000,
+		StartTime:      1_700_000_000,
+		Timeout:        3_600,
+		SubscriptionID: 9,
+	}
+
+	stateInit, err := config.StateInit(owner)
+	if err != nil {
+		t.Fatalf("StateInit() error = %v", err)
+	}
+	if stateInit.Code != code {
+		t.Errorf("Code is not the plugin code")
+	}
+
+	data := stateInit.Data.BeginParse()
+	if got := data.MustLoadAddr(); !got.Equals(owner) {
+		t.Errorf("wallet = %s, want %s", got, owner)
+	}
+	if got := data.MustLoadAddr(); !got.Equals(beneficiary) {
+		t.Errorf("beneficiary = %s, want %s", got, beneficiary)
+	}
+	if got := data.MustLoadBigCoins(); got.Cmp(tlb.MustFromTON("1").Nano()) != 0 {
+		t.Errorf("amount = %s, want 1 TON", got)
+	}
+
+	fields := []struct {
+		name string
+		bits uint
+		want uint64
+	}{
+		{"period", 32, 2_592_000},
+		{"start time", 32, 1_700_000_000},
+		{"timeout", 32, 3_600},
+		{"last payment time", 32, 0},
+		{"last request time", 32, 0},
+		{"failed attempts", 8, 0},
+		{"subscription id", 32, 9},
+	}
+	for _, f := range fields {
+		if got := data.MustLoadUInt(f.bits); got != f.want {
+			t.Errorf("%s = %d, want %d", f.name, got, f.want)
+		}
+	}
+	if data.BitsLeft() != 0 {
+		t.Errorf("%d bits left in the data, want none", data.BitsLeft())
+	}
+
+	if _, err = (wallet.SubscriptionConfig{Beneficiary: beneficiary}).StateInit(owner); err == nil {
+		t.Errorf("StateInit() without code error = nil, want an error")
+	}
+	if _, err = (wallet.SubscriptionConfig{Code: code}).StateInit(owner); err == nil {
+		t.Errorf("StateInit() without beneficiary error = nil, want an error")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
 
@@ -30,11 +31,63 @@ type SpecV5R1 struct {
 const MainnetGlobalID = -239
 const TestnetGlobalID = -3
 
+const (
+	// prefixes of the requests the wallet accepts, signed by the owner in an external or an internal
+	// message, or sent by an installed extension
+	v5OpExternalSigned  = 0x7369676e
+	v5OpInternalSigned  = 0x73696e74
+	v5OpExtensionAction = 0x6578746e
+
+	v5ActionAddExtension    = 0x1c40db9f
+	v5ActionRemoveExtension = 0x5eaef4a4
+)
+
+// V5R1ExtensionAction installs or removes an extension, a contract allowed to make the wallet send
+// messages without the signature of the owner.
+type V5R1ExtensionAction struct {
+	Address *address.Address
+	Remove  bool
+}
+
 func (s *SpecV5R1) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt, messages []*Message) (_ *cell.Cell, err error) {
 	// TODO: remove block, now it is here for backwards compatibility
+	return s.buildSignedRequest(ctx, v5OpExternalSigned, messages, nil)
+}
+
+// BuildExtensionsMessage builds the body of an external message installing and removing extensions,
+// the messages are sent in the same transaction.
+func (s *SpecV5R1) BuildExtensionsMessage(ctx context.Context, actions []V5R1ExtensionAction, messages []*Message) (*cell.Cell, error) {
+	if len(actions) == 0 {
+		return nil, errors.New("no extension actions")
+	}
+
+	return s.buildSignedRequest(ctx, v5OpExternalSigned, messages, actions)
+}
+
+// BuildInternalSignedMessage builds a request signed by the owner that is delivered in the body of an
+// internal message instead of an external one, so any wallet can relay it and pay for it.
+func (s *SpecV5R1) BuildInternalSignedMessage(ctx context.Context, messages []*Message) (*cell.Cell, error) {
+	return s.buildSignedRequest(ctx, v5OpInternalSigned, messages, nil)
+}
+
+// BuildV5R1ExtensionRequest builds the body an installed extension sends to the wallet to make it send
+// the messages, it is not signed.
+func BuildV5R1ExtensionRequest(queryID uint64, messages []*Message) (*cell.Cell, error) {
+	actions, err := packV5Actions(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build actions: %w", err)
+	}
+
+	return cell.BeginCell().
+		MustStoreUInt(v5OpExtensionAction, 32).
+		MustStoreUInt(queryID, 64).
+		MustStoreBuilder(actions).
+		EndCell(), nil
+}
 
+func (s *SpecV5R1) buildSignedRequest(ctx context.Context, op uint64, messages []*Message, extActions []V5R1ExtensionAction) (_ *cell.Cell, err error) {
 	if len(messages) > 255 {
-		return nil, errors.New("for this type of wallet max 4 messages can be sent in the same time")
+		return nil, errors.New("for this type of wallet max 255 messages can be sent in the same time")
 	}
 
 	seq, err := s.seqnoFetcher(ctx, s.wallet.subwallet)
@@ -47,8 +100,13 @@ func (s *SpecV5R1) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt,
 		return nil, fmt.Errorf("failed to build actions: %w", err)
 	}
 
+	actions, err = packV5ExtendedActions(actions, extActions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build extended actions: %w", err)
+	}
+
 	payload := cell.BeginCell().
-		MustStoreUInt(0x7369676e, 32). // external sign op code
+		MustStoreUInt(op, 32).
 		MustStoreInt(int64(s.config.NetworkGlobalID), 32).
 		MustStoreInt(int64(s.config.Workchain), 8).
 		MustStoreUInt(0, 8). // version of v5
@@ -72,6 +130,37 @@ func (s *SpecV5R1) BuildMessage(ctx context.Context, _ bool, _ *ton.BlockIDExt,
 	return msg, nil
 }
 
+// packV5ExtendedActions prepends the extension actions to the basic action list, the wallet runs them
+// in order before it sends the messages.
+func packV5ExtendedActions(basic *cell.Builder, extActions []V5R1ExtensionAction) (*cell.Builder, error) {
+	/*
+		action_list_basic$0 {n:#} actions:^(OutList n) = ActionList n 0;
+		action_list_extended$1 {m:#} {n:#} action:ExtendedAction prev:^(ActionList n m) = ActionList n (m+1);
+		action_add_ext#1c40db9f addr:MsgAddressInt = ExtendedAction;
+		action_delete_ext#5eaef4a4 addr:MsgAddressInt = ExtendedAction;
+	*/
+	list := basic
+	for i := len(extActions) - 1; i >= 0; i-- {
+		action := extActions[i]
+		if action.Address == nil {
+			return nil, errors.New("extension address is required")
+		}
+
+		op := uint64(v5ActionAddExtension)
+		if action.Remove {
+			op = v5ActionRemoveExtension
+		}
+
+		list = cell.BeginCell().
+			MustStoreUInt(1, 1).
+			MustStoreUInt(op, 32).
+			MustStoreAddr(action.Address).
+			MustStoreRef(list.EndCell())
+	}
+
+	return list, nil
+}
+
 func packV5Actions(messages []*Message) (*cell.Builder, error) {
 	if len(messages) > 255 {
 		return nil, fmt.Errorf("max 255 messages allowed for v5")
@@ -0,0 +1,186 @@
+package wallet_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func newV5R1Wallet(t *testing.T, seqno uint32) *wallet.Wallet {
+	t.Helper()
+
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	w, err := wallet.FromPrivateKey(nil, key, wallet.ConfigV5R1{NetworkGlobalID: wallet.MainnetGlobalID})
+	if err != nil {
+		t.Fatalf("FromPrivateKey() error = %v", err)
+	}
+	w.GetSpec().(*wallet.SpecV5R1).SetCustomSeqnoFetcher(func() uint32 { return seqno })
+
+	return w
+}
+
+// loadV5Request checks the header of a signed V5R1 request and returns the slice at its action list.
+func loadV5Request(t *testing.T, body *cell.Cell, op uint64, seqno uint32) *cell.Slice {
+	t.Helper()
+
+	s := body.BeginParse()
+	if got := s.MustLoadUInt(32); got != op {
+		t.Errorf("op = %#x, want %#x", got, op)
+	}
+	if got := s.MustLoadInt(32); got != wallet.MainnetGlobalID {
+		t.Errorf("network global id = %d, want %d", got, wallet.MainnetGlobalID)
+	}
+	if got := s.MustLoadInt(8); got != 0 {
+		t.Errorf("workchain = %d, want 0", got)
+	}
+	s.MustLoadUInt(8)  // version
+	s.MustLoadUInt(32) // subwallet
+	s.MustLoadUInt(32) // valid until
+	if got := s.MustLoadUInt(32); got != uint64(seqno) {
+		t.Errorf("seqno = %d, want %d", got, seqno)
+	}
+
+	return s
+}
+
+// loadV5Messages reads the basic action list and returns the messages it sends, in order.
+func loadV5Messages(t *testing.T, actions *cell.Slice) []*tlb.InternalMessage {
+	t.Helper()
+
+	if actions.MustLoadUInt(1) != 0 {
+		t.Fatalf("action list is extended, want basic")
+	}
+
+	var messages []*tlb.InternalMessage
+	list := actions.MustLoadRef()
+	for list.RefsNum() > 0 {
+		prev := list.MustLoadRef()
+		if op := list.MustLoadUInt(32); op != 0x0ec3c86d {
+			t.Fatalf("action op = %#x, want send_msg", op)
+		}
+		list.MustLoadUInt(8) // mode
+
+		var msg tlb.InternalMessage
+		if err := tlb.LoadFromCell(&msg, list.MustLoadRef()); err != nil {
+			t.Fatalf("LoadFromCell() error = %v", err)
+		}
+		messages = append([]*tlb.InternalMessage{&msg}, messages...)
+		list = prev
+	}
+
+	return messages
+}
+
+func TestPrepareInternalSignedMessage(t *testing.T) {
+	ctx := context.Background()
+	w := newV5R1Wallet(t, 7)
+	to := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+	transfer := wallet.SimpleMessage(to, tlb.MustFromTON("0.05"), nil)
+
+	tests := []struct {
+		name          string
+		withStateInit bool
+	}{
+		{"deployed", false},
+		{"not deployed", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relayed, err := w.PrepareInternalSignedMessage(ctx, tt.withStateInit, []*wallet.Message{transfer}, tlb.MustFromTON("0.07"))
+			if err != nil {
+				t.Fatalf("PrepareInternalSignedMessage() error = %v", err)
+			}
+
+			msg := relayed.InternalMessage
+			// only the amount the caller decides is attached, not the TON the messages send
+			if got := msg.Amount.String(); got != "0.07" {
+				t.Errorf("Amount = %s, want 0.07", got)
+			}
+			if !msg.DstAddr.Equals(w.WalletAddress()) {
+				t.Errorf("DstAddr = %s, want %s", msg.DstAddr, w.WalletAddress())
+			}
+			if msg.Bounce == tt.withStateInit || (msg.StateInit != nil) != tt.withStateInit {
+				t.Errorf("Bounce, StateInit = %v, %v, want a state init and no bounce only when not deployed", msg.Bounce, msg.StateInit != nil)
+			}
+
+			actions := loadV5Request(t, msg.Body, 0x73696e74, 7)
+			messages := loadV5Messages(t, actions)
+			if len(messages) != 1 || !messages[0].DstAddr.Equals(to) || messages[0].Amount.String() != "0.05" {
+				t.Errorf("relayed messages = %+v, want 0.05 TON to %s", messages, to)
+			}
+			if actions.BitsLeft() != 512 {
+				t.Errorf("%d bits after the actions, want the 512 bit signature", actions.BitsLeft())
+			}
+		})
+	}
+
+	v4, err := wallet.FromPrivateKey(nil, ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), wallet.V4R2)
+	if err != nil {
+		t.Fatalf("FromPrivateKey() error = %v", err)
+	}
+	_, err = v4.PrepareInternalSignedMessage(ctx, false, []*wallet.Message{transfer}, tlb.MustFromTON("0.07"))
+	if !errors.Is(err, wallet.ErrUnsupportedWalletVersion) {
+		t.Errorf("PrepareInternalSignedMessage() of a V4R2 wallet error = %v, want %v", err, wallet.ErrUnsupportedWalletVersion)
+	}
+}
+
+func TestBuildExtensionsMessage(t *testing.T) {
+	ctx := context.Background()
+	spec := newV5R1Wallet(t, 3).GetSpec().(*wallet.SpecV5R1)
+	added := address.MustParseAddr("EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N")
+	removed := address.MustParseAddr("EQBvW8Z5huBkMJYdnfAEM5JqTNkuWX3diqYENkWsIL0XggGG")
+	transfer := wallet.SimpleMessage(added, tlb.MustFromTON("1"), nil)
+
+	body, err := spec.BuildExtensionsMessage(ctx, []wallet.V5R1ExtensionAction{
+		{Address: added},
+		{Address: removed, Remove: true},
+	}, []*wallet.Message{transfer})
+	if err != nil {
+		t.Fatalf("BuildExtensionsMessage() error = %v", err)
+	}
+
+	actions := loadV5Request(t, body, 0x7369676e, 3)
+
+	// extended actions run in the given order, then the basic list sends the messages
+	tests := []struct {
+		op   uint64
+		addr *address.Address
+	}{
+		{0x1c40db9f, added},
+		{0x5eaef4a4, removed},
+	}
+	list := actions
+	for _, tt := range tests {
+		if list.MustLoadUInt(1) != 1 {
+			t.Fatalf("action list is basic, want extended")
+		}
+		if op := list.MustLoadUInt(32); op != tt.op {
+			t.Errorf("extended action op = %#x, want %#x", op, tt.op)
+		}
+		if addr := list.MustLoadAddr(); !addr.Equals(tt.addr) {
+			t.Errorf("extension = %s, want %s", addr, tt.addr)
+		}
+		list = list.MustLoadRef()
+	}
+
+	if messages := loadV5Messages(t, list); len(messages) != 1 || messages[0].Amount.String() != "1" {
+		t.Errorf("messages = %+v, want 1 TON", messages)
+	}
+	if actions.BitsLeft() != 512 {
+		t.Errorf("%d bits after the actions, want the 512 bit signature", actions.BitsLeft())
+	}
+
+	if _, err = spec.BuildExtensionsMessage(ctx, []wallet.V5R1ExtensionAction{{Remove: true}}, nil); err == nil {
+		t.Errorf("BuildExtensionsMessage() without an extension address error = nil, want an error")
+	}
+	if _, err = spec.BuildExtensionsMessage(ctx, nil, []*wallet.Message{transfer}); err == nil {
+		t.Errorf("BuildExtensionsMessage() without actions error = nil, want an error")
+	}
+}
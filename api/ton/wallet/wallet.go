@@ -220,7 +220,7 @@ func FromPrivateKey(api TonAPI, key ed25519.PrivateKey, version VersionConfig) (
 
 func getSpec(w *Wallet) (any, error) {
 	switch v := w.ver.(type) {
-	case Version, ConfigV5R1:
+	case Version, ConfigV5R1, ConfigLockup:
 		regular := SpecRegular{
 			wallet:      w,
 			messagesTTL: 60 * 3, // default ttl 3 min
@@ -253,6 +253,8 @@ func getSpec(w *Wallet) (any, error) {
 				return nil, fmt.Errorf("NetworkGlobalID should be set in v5 config")
 			}
 			return &SpecV5R1{SpecRegular: regular, SpecSeqno: SpecSeqno{seqnoFetcher: seqnoFetcher}, config: x}, nil
+		case ConfigLockup:
+			return &SpecLockup{SpecV3: SpecV3{regular, SpecSeqno{seqnoFetcher: seqnoFetcher}}, config: x}, nil
 		}
 
 		switch v {
@@ -266,6 +268,8 @@ func getSpec(w *Wallet) (any, error) {
 			return nil, fmt.Errorf("use ConfigHighloadV3 for highload v3 spec")
 		case V5R1:
 			return nil, fmt.Errorf("use ConfigV5R1 for v5 spec")
+		case Lockup:
+			return nil, fmt.Errorf("use ConfigLockup for lockup spec")
 		}
 	case ConfigHighloadV3:
 		return &SpecHighloadV3{wallet: w, config: v}, nil
@@ -340,17 +344,11 @@ func (w *Wallet) BuildMessageForMany(ctx context.Context, messages []*Message) (
 }
 
 func (w *Wallet) BuildExternalMessageForMany(ctx context.Context, messages []*Message) (*tlb.ExternalMessage, error) {
-	block, err := w.api.CurrentMasterchainInfo(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get block: %w", err)
-	}
-
-	acc, err := w.api.WaitForBlock(block.SeqNo).GetAccount(ctx, block, w.addr)
+	initialized, err := w.isInitialized(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get account state: %w", err)
+		return nil, err
 	}
 
-	initialized := acc.IsActive && acc.State.Status == tlb.AccountStatusActive
 	return w.PrepareExternalMessageForMany(ctx, !initialized, messages)
 }
 
@@ -359,26 +357,24 @@ func (w *Wallet) BuildExternalMessageForMany(ctx context.Context, messages []*Me
 func (w *Wallet) PrepareExternalMessageForMany(ctx context.Context, withStateInit bool, messages []*Message) (_ *tlb.ExternalMessage, err error) {
 	var stateInit *tlb.StateInit
 	if withStateInit {
-		publicKey, err := w.signer.PublicKey(ctx)
+		stateInit, err = w.getStateInit(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get public key: %w", err)
-		}
-
-		stateInit, err = GetStateInit(publicKey, w.ver, w.subwallet)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get state init: %w", err)
+			return nil, err
 		}
 	}
 
 	var msg *cell.Cell
 	switch v := w.ver.(type) {
-	case Version, ConfigV5R1:
-		if _, ok := v.(ConfigV5R1); ok {
+	case Version, ConfigV5R1, ConfigLockup:
+		switch v.(type) {
+		case ConfigV5R1:
 			v = V5R1
+		case ConfigLockup:
+			v = Lockup
 		}
 
 		switch v {
-		case V3R2, V3R1, V4R2, V4R1, V5R1:
+		case V3R2, V3R1, V4R2, V4R1, V5R1, Lockup:
 			msg, err = w.spec.(RegularBuilder).BuildMessage(ctx, !withStateInit, nil, messages)
 			if err != nil {
 				return nil, fmt.Errorf("build message err: %w", err)
@@ -409,6 +405,112 @@ func (w *Wallet) PrepareExternalMessageForMany(ctx context.Context, withStateIni
 	}, nil
 }
 
+// BuildInternalSignedMessage builds the message another wallet sends to relay the messages of this V5R1 wallet,
+// it attaches amount and deploys the wallet when it is not deployed yet. The relaying wallet pays the fees of
+// its own transaction, the gas of this wallet and the messages it sends are paid from its balance, which
+// includes the attached TON.
+func (w *Wallet) BuildInternalSignedMessage(ctx context.Context, messages []*Message, amount tlb.Coins) (*Message, error) {
+	initialized, err := w.isInitialized(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.PrepareInternalSignedMessage(ctx, !initialized, messages, amount)
+}
+
+// PrepareInternalSignedMessage - Prepares the message relaying the messages of this V5R1 wallet,
+// can be used directly for offline signing but custom fetchers should be defined in this case
+func (w *Wallet) PrepareInternalSignedMessage(ctx context.Context, withStateInit bool, messages []*Message, amount tlb.Coins) (_ *Message, err error) {
+	spec, ok := w.spec.(*SpecV5R1)
+	if !ok {
+		return nil, fmt.Errorf("internal signed messages are only supported by v5 wallets: %w", ErrUnsupportedWalletVersion)
+	}
+
+	body, err := spec.BuildInternalSignedMessage(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("build message err: %w", err)
+	}
+
+	var stateInit *tlb.StateInit
+	if withStateInit {
+		stateInit, err = w.getStateInit(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Message{
+		Mode: PayGasSeparately + IgnoreErrors,
+		InternalMessage: &tlb.InternalMessage{
+			IHRDisabled: true,
+			// an undeployed wallet would bounce the request before it is deployed
+			Bounce:    !withStateInit,
+			DstAddr:   w.addr,
+			Amount:    amount,
+			StateInit: stateInit,
+			Body:      body,
+		},
+	}, nil
+}
+
+// BuildExternalMessageWithBody wraps a request built by the spec of the wallet, like the plugin requests of
+// SpecV4R2 or the extension requests of SpecV5R1, into an external message deploying the wallet when it is
+// not deployed yet.
+func (w *Wallet) BuildExternalMessageWithBody(ctx context.Context, body *cell.Cell) (*tlb.ExternalMessage, error) {
+	initialized, err := w.isInitialized(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stateInit *tlb.StateInit
+	if !initialized {
+		stateInit, err = w.getStateInit(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &tlb.ExternalMessage{
+		DstAddr:   w.addr,
+		StateInit: stateInit,
+		Body:      body,
+	}, nil
+}
+
+func (w *Wallet) isInitialized(ctx context.Context) (bool, error) {
+	block, err := w.api.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block: %w", err)
+	}
+
+	acc, err := w.api.WaitForBlock(block.SeqNo).GetAccount(ctx, block, w.addr)
+	if err != nil {
+		return false, fmt.Errorf("failed to get account state: %w", err)
+	}
+
+	return acc.IsActive && acc.State.Status == tlb.AccountStatusActive, nil
+}
+
+func (w *Wallet) getStateInit(ctx context.Context) (*tlb.StateInit, error) {
+	var publicKey ed25519.PublicKey
+	if w.signer != nil {
+		var err error
+		publicKey, err = w.signer.PublicKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get public key: %w", err)
+		}
+	} else {
+		publicKey = w.key.Public().(ed25519.PublicKey)
+	}
+
+	stateInit, err := GetStateInit(publicKey, w.ver, w.subwallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state init: %w", err)
+	}
+
+	return stateInit, nil
+}
+
 func (w *Wallet) BuildTransfer(to *address.Address, amount tlb.Coins, bounce bool, comment string) (_ *Message, err error) {
 	var body *cell.Cell
 	if comment != "" {
@@ -644,6 +746,34 @@ func CreateCommentCell(text string) (*cell.Cell, error) {
 const EncryptedCommentOpcode = 0x2167da4b
 
 func DecryptCommentCell(commentCell *cell.Cell, sender *address.Address, ourKey ed25519.PrivateKey, theirKey ed25519.PublicKey) ([]byte, error) {
+	return decryptCommentCell(commentCell, sender.String(), ourKey.Public().(ed25519.PublicKey), theirKey, func() ([]byte, error) {
+		return adnl.SharedKey(ourKey, theirKey)
+	})
+}
+
+// DecryptCommentCellWithSigner decrypts the comment the sender encrypted for the key of the signer.
+// Wallets salt the comment with either the bounceable or the non-bounceable form of their address,
+// both are tried.
+func DecryptCommentCellWithSigner(ctx context.Context, commentCell *cell.Cell, sender *address.Address, signer api.Signer, theirKey ed25519.PublicKey) ([]byte, error) {
+	ourKey, err := signer.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedKey := func() ([]byte, error) {
+		return signer.SharedKey(theirKey)
+	}
+
+	data, err := decryptCommentCell(commentCell, sender.Bounce(true).String(), ourKey, theirKey, sharedKey)
+	if errors.Is(err, errIncorrectMsgKey) {
+		return decryptCommentCell(commentCell, sender.Bounce(false).String(), ourKey, theirKey, sharedKey)
+	}
+	return data, err
+}
+
+var errIncorrectMsgKey = errors.New("incorrect msg key")
+
+func decryptCommentCell(commentCell *cell.Cell, sender string, ourKey, theirKey ed25519.PublicKey, sharedKeyFn func() ([]byte, error)) ([]byte, error) {
 	slc := commentCell.BeginParse()
 	op, err := slc.LoadUInt(32)
 	if err != nil {
@@ -662,7 +792,7 @@ func DecryptCommentCell(commentCell *cell.Cell, sender *address.Address, ourKey
 		xorKey[i] ^= theirKey[i]
 	}
 
-	if !bytes.Equal(xorKey, ourKey.Public().(ed25519.PublicKey)) {
+	if !bytes.Equal(xorKey, ourKey) {
 		return nil, fmt.Errorf("message was encrypted not for the given keys")
 	}
 
@@ -671,7 +801,7 @@ func DecryptCommentCell(commentCell *cell.Cell, sender *address.Address, ourKey
 		return nil, fmt.Errorf("failed to load xor key: %w", err)
 	}
 
-	sharedKey, err := adnl.SharedKey(ourKey, theirKey)
+	sharedKey, err := sharedKeyFn()
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute shared key: %w", err)
 	}
@@ -700,10 +830,10 @@ func DecryptCommentCell(commentCell *cell.Cell, sender *address.Address, ourKey
 		return nil, fmt.Errorf("invalid prefix size %d", data[0])
 	}
 
-	h = hmac.New(sha512.New, []byte(sender.String()))
+	h = hmac.New(sha512.New, []byte(sender))
 	h.Write(data)
 	if !bytes.Equal(msgKey, h.Sum(nil)[:16]) {
-		return nil, fmt.Errorf("incorrect msg key")
+		return nil, errIncorrectMsgKey
 	}
 
 	return data[data[0]:], nil
@@ -0,0 +1,117 @@
+package ton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+)
+
+// walletFromSigner opens the wallet deployed at fromAddress with the version detected from its code,
+// undeployed wallets use the configured default version. The address derived from the signer key has
+// to match fromAddress, otherwise the signer belongs to another wallet.
+func walletFromSigner(ctx context.Context, client wallet.TonAPI, signer api.Signer, fromAddress string, opts *Options) (*wallet.Wallet, error) {
+	addr, err := address.ParseAddr(fromAddress)
+	if err != nil {
+		return nil, types.WrapErr(types.ErrInvalidAddress, err)
+	}
+
+	version, err := detectWalletVersion(ctx, client, addr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := wallet.FromSigner(ctx, client, signer, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if !w.WalletAddress().Equals(addr) {
+		return nil, types.WrapErr(types.ErrInvalidAddress,
+			fmt.Errorf("signer key derives %s wallet %s, not %s", versionName(version), w.WalletAddress().String(), fromAddress))
+	}
+
+	return w, nil
+}
+
+// detectWalletVersion maps the code deployed at addr to the version config of the wallet.
+func detectWalletVersion(ctx context.Context, client wallet.TonAPI, addr *address.Address, opts *Options) (wallet.VersionConfig, error) {
+	block, err := client.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master block: %w", err)
+	}
+
+	account, err := client.WaitForBlock(block.SeqNo).GetAccount(ctx, block, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", addr.String(), err)
+	}
+
+	if !account.IsActive || account.State == nil || account.Code == nil {
+		if config, ok := opts.lockupConfigs[rawAddress(addr)]; ok {
+			return config, nil
+		}
+		if version, ok := opts.defaultWalletVersion.(wallet.Version); ok {
+			return versionConfig(version, addr, opts)
+		}
+		return opts.defaultWalletVersion, nil
+	}
+
+	version := wallet.GetWalletVersion(account)
+	if version == wallet.Unknown {
+		return nil, fmt.Errorf("contract at %s is not a known wallet: %w", addr.String(), wallet.ErrUnsupportedWalletVersion)
+	}
+
+	return versionConfig(version, addr, opts)
+}
+
+// versionConfig adds the settings V5R1, highload V3 and lockup wallets cannot be opened without. Highload V3
+// wallets need a persisted query id source, a wallet that forgets its query ids on restart reuses ones the
+// contract still remembers and gets its messages rejected.
+func versionConfig(version wallet.Version, addr *address.Address, opts *Options) (wallet.VersionConfig, error) {
+	switch version {
+	case wallet.V5R1:
+		return wallet.ConfigV5R1{
+			NetworkGlobalID: opts.networkGlobalID,
+			Workchain:       int8(addr.Workchain()),
+		}, nil
+	case wallet.HighloadV3:
+		if opts.highloadV3Config != nil {
+			return *opts.highloadV3Config, nil
+		}
+		if opts.queryIDs == nil {
+			return nil, fmt.Errorf("highload V3 wallet %s needs a query id store, see WithQueryIDStore", addr.String())
+		}
+		// every form of the address shares the query ids of the wallet
+		return opts.queryIDs.config(rawAddress(addr), opts.highloadMessageTTL), nil
+	case wallet.Lockup:
+		if config, ok := opts.lockupConfigs[rawAddress(addr)]; ok {
+			return config, nil
+		}
+		return version, nil
+	default:
+		return version, nil
+	}
+}
+
+func versionName(version wallet.VersionConfig) string {
+	switch v := version.(type) {
+	case wallet.Version:
+		return v.String()
+	case wallet.ConfigV5R1:
+		return wallet.V5R1.String()
+	case wallet.ConfigHighloadV3:
+		return "highload V3"
+	case wallet.ConfigLockup:
+		return wallet.Lockup.String()
+	}
+
+	return wallet.Unknown.String()
+}
+
+// rawAddress returns the workchain:hash form of the address, which does not depend on its flags.
+func rawAddress(addr *address.Address) string {
+	return fmt.Sprintf("%d:%x", addr.Workchain(), addr.Data())
+}
@@ -0,0 +1,204 @@
+package ton_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/openweb3-io/blockchain/api/types"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	_ton "github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// accountChain keeps the accounts deployed on it, every other address has no state.
+type accountChain struct {
+	_ton.APIClientWrapped
+	accounts map[string]*tlb.Account
+}
+
+func (c *accountChain) CurrentMasterchainInfo(ctx context.Context) (*_ton.BlockIDExt, error) {
+	return &_ton.BlockIDExt{Workchain: address.MasterchainID, SeqNo: 1}, nil
+}
+
+func (c *accountChain) WaitForBlock(seqno uint32) _ton.APIClientWrapped {
+	return c
+}
+
+func (c *accountChain) GetAccount(ctx context.Context, block *_ton.BlockIDExt, addr *address.Address) (*tlb.Account, error) {
+	if account, ok := c.accounts[accountKey(addr)]; ok {
+		return account, nil
+	}
+
+	return &tlb.Account{}, nil
+}
+
+func activeAccount(code *cell.Cell) *tlb.Account {
+	return &tlb.Account{
+		IsActive: true,
+		State:    &tlb.AccountState{IsValid: true, AccountStorage: tlb.AccountStorage{Status: tlb.AccountStatusActive}},
+		Code:     code,
+	}
+}
+
+func walletCode(t *testing.T, version wallet.VersionConfig) *cell.Cell {
+	t.Helper()
+
+	state, err := wallet.GetStateInit(make(ed25519.PublicKey, ed25519.PublicKeySize), version, wallet.DefaultSubwallet)
+	if err != nil {
+		t.Fatalf("GetStateInit() error = %v", err)
+	}
+
+	return state.Code
+}
+
+func TestDetectWalletVersion(t *testing.T) {
+	ctx := context.Background()
+	addr := newTestAddress(1)
+	basechain := address.NewAddress(0, 0, addr.Data())
+	lockup := wallet.ConfigLockup{ConfigPublicKey: make(ed25519.PublicKey, ed25519.PublicKeySize)}
+	highload := wallet.ConfigHighloadV3{MessageTTL: 120}
+
+	tests := []struct {
+		name    string
+		account *tlb.Account
+		opts    []ton.Option
+		want    wallet.VersionConfig
+		wantErr bool
+		errIs   error
+	}{
+		{name: "v3r2", account: activeAccount(walletCode(t, wallet.V3R2)), want: wallet.V3R2},
+		{name: "v4r2", account: activeAccount(walletCode(t, wallet.V4R2)), want: wallet.V4R2},
+		{name: "highload v2r2", account: activeAccount(walletCode(t, wallet.HighloadV2R2)), want: wallet.HighloadV2R2},
+		{
+			name:    "v5r1 takes the network and the workchain",
+			account: activeAccount(walletCode(t, wallet.ConfigV5R1{})),
+			opts:    []ton.Option{ton.WithNetworkGlobalID(wallet.TestnetGlobalID)},
+			want:    wallet.ConfigV5R1{NetworkGlobalID: wallet.TestnetGlobalID, Workchain: 0},
+		},
+		{
+			name:    "highload v3 with a config",
+			account: activeAccount(walletCode(t, highload)),
+			opts:    []ton.Option{ton.WithHighloadV3Config(highload)},
+			want:    highload,
+		},
+		{
+			name:    "highload v3 without a query id store",
+			account: activeAccount(walletCode(t, highload)),
+			wantErr: true,
+		},
+		{
+			name:    "lockup with a config",
+			account: activeAccount(walletCode(t, lockup)),
+			opts:    []ton.Option{ton.WithLockupConfig(basechain, lockup)},
+			want:    lockup,
+		},
+		{name: "lockup without a config", account: activeAccount(walletCode(t, lockup)), want: wallet.Lockup},
+		{
+			name:    "unknown code",
+			account: activeAccount(cell.BeginCell().MustStoreUInt(0xc0de, 16).EndCell()),
+			wantErr: true,
+			errIs:   wallet.ErrUnsupportedWalletVersion,
+		},
+		{name: "uninitialized uses the default", want: wallet.V4R2},
+		{
+			name: "uninitialized uses the configured default",
+			opts: []ton.Option{ton.WithDefaultWalletVersion(wallet.V5R1)},
+			want: wallet.ConfigV5R1{NetworkGlobalID: wallet.MainnetGlobalID},
+		},
+		{
+			name: "uninitialized uses a default config as is",
+			opts: []ton.Option{ton.WithDefaultWalletVersion(wallet.ConfigV5R1{NetworkGlobalID: wallet.TestnetGlobalID, Workchain: -1})},
+			want: wallet.ConfigV5R1{NetworkGlobalID: wallet.TestnetGlobalID, Workchain: -1},
+		},
+		{
+			name: "uninitialized lockup",
+			opts: []ton.Option{ton.WithLockupConfig(basechain, lockup)},
+			want: lockup,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &accountChain{accounts: map[string]*tlb.Account{}}
+			if tt.account != nil {
+				chain.accounts[accountKey(addr)] = tt.account
+			}
+
+			got, err := ton.DetectWalletVersion(ctx, chain, basechain, tt.opts...)
+			if tt.wantErr {
+				if err == nil || (tt.errIs != nil && !errors.Is(err, tt.errIs)) {
+					t.Errorf("DetectWalletVersion() error = %v, want an error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectWalletVersion() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectWalletVersion() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalletFromSigner(t *testing.T) {
+	ctx := context.Background()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	signer := ton.NewLocalSigner(key)
+
+	addressOf := func(version wallet.VersionConfig, subwallet uint32) *address.Address {
+		addr, err := wallet.AddressFromPubKey(key.Public().(ed25519.PublicKey), version, subwallet)
+		if err != nil {
+			t.Fatalf("AddressFromPubKey() error = %v", err)
+		}
+		return addr
+	}
+	v3 := addressOf(wallet.V3R2, wallet.DefaultSubwallet)
+	v4 := addressOf(wallet.V4R2, wallet.DefaultSubwallet)
+	v5 := addressOf(wallet.ConfigV5R1{NetworkGlobalID: wallet.MainnetGlobalID}, 0)
+
+	chain := &accountChain{accounts: map[string]*tlb.Account{
+		accountKey(v3): activeAccount(walletCode(t, wallet.V3R2)),
+		accountKey(v5): activeAccount(walletCode(t, wallet.ConfigV5R1{})),
+	}}
+
+	tests := []struct {
+		name    string
+		from    string
+		wantErr *types.Error
+	}{
+		{name: "deployed v3r2", from: v3.String()},
+		{name: "deployed v5r1", from: v5.String()},
+		{name: "undeployed default version", from: v4.String()},
+		// the V4R2 wallet of the key is at another address
+		{name: "wallet of another key", from: newTestAddress(2).String(), wantErr: types.ErrInvalidAddress},
+		{name: "malformed address", from: "EQ-not-an-address", wantErr: types.ErrInvalidAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ton.WalletFromSigner(ctx, chain, signer, tt.from)
+			if tt.wantErr != nil {
+				var typed *types.Error
+				if !errors.As(err, &typed) || typed.Code != tt.wantErr.Code {
+					t.Errorf("WalletFromSigner() error = %v, want %s", err, tt.wantErr.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WalletFromSigner() error = %v", err)
+			}
+
+			if got := w.WalletAddress(); !got.Equals(address.MustParseAddr(tt.from)) {
+				t.Errorf("WalletAddress() = %s, want %s", got, tt.from)
+			}
+		})
+	}
+}
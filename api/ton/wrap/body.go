@@ -0,0 +1,161 @@
+package ton
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// op codes of the message bodies ParseMessageBody decodes
+const (
+	opTextComment          = 0x00000000
+	opEncryptedComment     = 0x2167da4b
+	opJettonTransfer       = 0x0f8a7ea5
+	opJettonNotify         = 0x7362d09c
+	opJettonInternal       = 0x178d4519
+	opExcess               = 0xd53276db
+	opJettonBurn           = 0x595f07bc
+	opNftTransfer          = 0x5fcc3d14
+	opNftOwnershipAssigned = 0x05138d91
+)
+
+// MessageBody is the body of an internal message decoded by its op code, which fields are set depends on the op.
+type MessageBody struct {
+	// name tonapi decodes the op with, empty for messages without a body, DecodedOpNameUnknown for ops
+	// that are not decoded
+	OpName string
+	OpCode uint32
+	// query id of jetton and NFT ops
+	QueryID uint64
+	// jetton amount of jetton transfers, internal transfers, notifications and burns
+	Amount *big.Int
+	// recipient of a jetton transfer, new owner of an NFT transfer
+	Destination *address.Address
+	// sender of a jetton notification or internal transfer, previous owner of an NFT ownership assignment
+	Sender              *address.Address
+	ResponseDestination *address.Address
+	ForwardAmount       *big.Int
+	// text comment of the body or of its forward payload
+	Comment string
+}
+
+// ParseMessageBody decodes text comments and the jetton and NFT ops, bodies with other op codes, bounces
+// included, are returned as DecodedOpNameUnknown with the op code only.
+func ParseMessageBody(body *cell.Cell) (*MessageBody, error) {
+	if body == nil || (body.BitsSize() == 0 && body.RefsNum() == 0) {
+		return &MessageBody{}, nil
+	}
+	if body.BitsSize() < 32 {
+		return &MessageBody{OpName: DecodedOpNameUnknown}, nil
+	}
+
+	slc := body.BeginParse()
+	op := uint32(slc.MustLoadUInt(32))
+	b := &MessageBody{OpCode: op}
+
+	var err error
+	switch op {
+	case opTextComment:
+		b.OpName = DecodedOpNameTextComment
+		b.Comment, err = slc.LoadStringSnake()
+	case opEncryptedComment:
+		b.OpName = DecodedOpNameEncryptedTextComment
+	case opJettonTransfer:
+		b.OpName = DecodedOpNameJettonTransfer
+		err = parseBody(slc, b,
+			loadQueryID, loadAmount(&b.Amount), loadAddr(&b.Destination), loadAddr(&b.ResponseDestination),
+			skipMaybeRef, loadAmount(&b.ForwardAmount), loadForwardPayload)
+	case opJettonNotify:
+		b.OpName = DecodedOpNameJettonNotify
+		err = parseBody(slc, b, loadQueryID, loadAmount(&b.Amount), loadAddr(&b.Sender), loadForwardPayload)
+	case opJettonInternal:
+		b.OpName = DecodedOpNameJettonInternalTransfer
+		err = parseBody(slc, b,
+			loadQueryID, loadAmount(&b.Amount), loadAddr(&b.Sender), loadAddr(&b.ResponseDestination),
+			loadAmount(&b.ForwardAmount), loadForwardPayload)
+	case opExcess:
+		b.OpName = DecodedOpNameExcess
+		err = parseBody(slc, b, loadQueryID)
+	case opJettonBurn:
+		b.OpName = DecodedOpNameJettonBurn
+		err = parseBody(slc, b, loadQueryID, loadAmount(&b.Amount), loadAddr(&b.ResponseDestination))
+	case opNftTransfer:
+		b.OpName = DecodedOpNameNftTransfer
+		err = parseBody(slc, b,
+			loadQueryID, loadAddr(&b.Destination), loadAddr(&b.ResponseDestination),
+			skipMaybeRef, loadAmount(&b.ForwardAmount), loadForwardPayload)
+	case opNftOwnershipAssigned:
+		b.OpName = DecodedOpNameNftOwnershipAssigned
+		err = parseBody(slc, b, loadQueryID, loadAddr(&b.Sender), loadForwardPayload)
+	default:
+		b.OpName = DecodedOpNameUnknown
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s body: %w", b.OpName, err)
+	}
+
+	return b, nil
+}
+
+type bodyField func(slc *cell.Slice, b *MessageBody) error
+
+func parseBody(slc *cell.Slice, b *MessageBody, fields ...bodyField) error {
+	for _, field := range fields {
+		if err := field(slc, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadQueryID(slc *cell.Slice, b *MessageBody) (err error) {
+	b.QueryID, err = slc.LoadUInt(64)
+	return
+}
+
+func loadAmount(dst **big.Int) bodyField {
+	return func(slc *cell.Slice, _ *MessageBody) (err error) {
+		*dst, err = slc.LoadBigCoins()
+		return
+	}
+}
+
+func loadAddr(dst **address.Address) bodyField {
+	return func(slc *cell.Slice, _ *MessageBody) (err error) {
+		*dst, err = slc.LoadAddr()
+		return
+	}
+}
+
+func skipMaybeRef(slc *cell.Slice, _ *MessageBody) error {
+	_, err := slc.LoadMaybeRef()
+	return err
+}
+
+// loadForwardPayload reads the text comment of the forward payload, stored in the rest of the body
+// or in a ref.
+func loadForwardPayload(slc *cell.Slice, b *MessageBody) error {
+	isRef, err := slc.LoadBoolBit()
+	if err != nil {
+		// some senders omit the payload
+		return nil
+	}
+
+	payload := slc
+	if isRef {
+		ref, err := slc.LoadRef()
+		if err != nil {
+			return err
+		}
+		payload = ref
+	}
+
+	if payload.BitsLeft() < 32 || payload.MustLoadUInt(32) != opTextComment {
+		return nil
+	}
+
+	b.Comment, err = payload.LoadStringSnake()
+	return err
+}
@@ -0,0 +1,239 @@
+package ton
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+	"go.uber.org/zap"
+)
+
+// LiteTransactionWrapper exposes the same view of a transaction as TransactionWrapper for transactions
+// fetched from a liteserver, the message bodies are decoded with ParseMessageBody instead of tonapi.
+type LiteTransactionWrapper struct {
+	*tlb.Transaction
+}
+
+func (tx *LiteTransactionWrapper) GetDirection() (direction TransactionDirection) {
+	addresses, err := tx.GetTxAddresses()
+	if err != nil {
+		return ""
+	}
+
+	accountAddr, err := tx.GetAccountAddress()
+	if err != nil {
+		return ""
+	}
+
+	if addresses.From == accountAddr {
+		return TransactionDirectionOut
+	}
+
+	return TransactionDirectionIn
+}
+
+// GetAccountAddress returns the address of the account of the transaction, the transaction only holds its
+// hash so the workchain is taken from the in message.
+func (tx *LiteTransactionWrapper) GetAccountAddress() (string, error) {
+	if tx.IO.In == nil {
+		return "", fmt.Errorf("in_msg not found")
+	}
+
+	dst := tx.IO.In.Msg.DestAddr()
+	if dst == nil || dst.Type() != address.StdAddress {
+		return "", fmt.Errorf("in_msg has no destination")
+	}
+
+	return friendlyAddress(address.NewAddress(0, byte(dst.Workchain()), tx.AccountAddr)), nil
+}
+
+func (tx *LiteTransactionWrapper) GetInMsgHash() (string, error) {
+	if tx.IO.In == nil {
+		return "", fmt.Errorf("in_msg not found")
+	}
+
+	switch tx.IO.In.MsgType {
+	case tlb.MsgTypeInternal:
+		c, err := tlb.ToCell(tx.IO.In.Msg)
+		if err != nil {
+			zap.S().Error("build cell from in_msg failed", zap.Error(err))
+			return "", err
+		}
+
+		return hex.EncodeToString(c.Hash()), nil
+	case tlb.MsgTypeExternalIn:
+		// external messages are identified by their normalized hash, the same one Transfer returns
+		return hex.EncodeToString(NormalizedExtMessageHash(tx.IO.In.AsExternalIn())), nil
+	}
+
+	return "", fmt.Errorf("unknown msg type: %v", tx.IO.In.MsgType)
+}
+
+func (tx *LiteTransactionWrapper) GetAmount() (amount string) {
+	amount = "0"
+
+	msg, body, err := tx.transferMessage()
+	if err != nil {
+		return
+	}
+
+	switch body.OpName {
+	case "", DecodedOpNameTextComment, DecodedOpNameEncryptedTextComment, DecodedOpNameExcess:
+		amount = msg.Amount.Nano().String()
+	case DecodedOpNameJettonTransfer, DecodedOpNameJettonInternalTransfer, DecodedOpNameJettonNotify, DecodedOpNameJettonBurn:
+		amount = body.Amount.String()
+	case DecodedOpNameNftTransfer, DecodedOpNameNftOwnershipAssigned:
+		// an nft is a single item
+		amount = "1"
+	}
+
+	return
+}
+
+func (tx *LiteTransactionWrapper) GetComment() (memo string) {
+	_, body, err := tx.transferMessage()
+	if err != nil {
+		return ""
+	}
+
+	return body.Comment
+}
+
+// GetEncryptedComment decrypts the encrypted comment of an incoming TON transfer with the signer of the
+// recipient, it returns an empty string when the in message has no encrypted comment.
+func (tx *LiteTransactionWrapper) GetEncryptedComment(ctx context.Context, client wallet.TonAPI, signer api.Signer) (string, error) {
+	if tx.IO.In == nil || tx.IO.In.MsgType != tlb.MsgTypeInternal {
+		return "", nil
+	}
+
+	inMsg := tx.IO.In.AsInternal()
+	if inMsg.Body == nil || inMsg.Body.BitsSize() < 32 || inMsg.Body.BeginParse().MustLoadUInt(32) != opEncryptedComment {
+		return "", nil
+	}
+
+	senderKey, err := wallet.GetPublicKey(ctx, client, inMsg.SrcAddr)
+	if err != nil {
+		zap.S().Error("get sender public key failed", zap.Error(err), zap.String("address", inMsg.SrcAddr.String()))
+		return "", err
+	}
+
+	comment, err := wallet.DecryptCommentCellWithSigner(ctx, inMsg.Body, inMsg.SrcAddr, signer, senderKey)
+	if err != nil {
+		zap.S().Error("decrypt comment failed", zap.Error(err))
+		return "", err
+	}
+
+	return string(comment), nil
+}
+
+func (tx *LiteTransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error) {
+	msg, body, err := tx.transferMessage()
+	if err != nil {
+		return
+	}
+
+	var to, from, jetton, nft *address.Address
+	switch body.OpName {
+	case "", DecodedOpNameTextComment, DecodedOpNameEncryptedTextComment:
+		// ton transfer
+		to, from = msg.DstAddr, msg.SrcAddr
+	case DecodedOpNameJettonTransfer:
+		to, from, jetton = body.Destination, msg.SrcAddr, msg.DstAddr
+	case DecodedOpNameJettonInternalTransfer:
+		// the owner of the receiving jetton wallet is not part of the message
+		to, from, jetton = msg.DstAddr, body.Sender, msg.DstAddr
+	case DecodedOpNameJettonNotify:
+		jetton, to, from = msg.SrcAddr, msg.DstAddr, body.Sender
+	case DecodedOpNameExcess:
+		to, from, jetton = msg.DstAddr, msg.SrcAddr, msg.SrcAddr
+	case DecodedOpNameJettonBurn:
+		// the burnt jettons leave to the jetton wallet, which reports the burn to its master
+		to, from, jetton = msg.DstAddr, msg.SrcAddr, msg.DstAddr
+	case DecodedOpNameNftTransfer:
+		to, from, nft = body.Destination, msg.SrcAddr, msg.DstAddr
+	case DecodedOpNameNftOwnershipAssigned:
+		nft, to, from = msg.SrcAddr, msg.DstAddr, body.Sender
+	default:
+		zap.S().Error("not supported op_code, ignore", zap.Uint32("op_code", body.OpCode))
+		err = fmt.Errorf("not supported op_code 0x%08x, ignore", body.OpCode)
+		return
+	}
+
+	addresses = TxAddresses{
+		To:     friendlyAddress(to),
+		From:   friendlyAddress(from),
+		Jetton: friendlyAddress(jetton),
+		Nft:    friendlyAddress(nft),
+	}
+
+	return
+}
+
+// transferMessage returns the internal message the transaction is about with its decoded body, the in
+// message or, for transactions started by an external message, the first internal message sent.
+func (tx *LiteTransactionWrapper) transferMessage() (*tlb.InternalMessage, *MessageBody, error) {
+	if tx.IO.In == nil {
+		return nil, nil, fmt.Errorf("in_msg not found")
+	}
+
+	var msg *tlb.InternalMessage
+	if tx.IO.In.MsgType == tlb.MsgTypeInternal {
+		msg = tx.IO.In.AsInternal()
+	} else if tx.IO.Out != nil {
+		outMsgs, err := tx.IO.Out.ToSlice()
+		if err != nil {
+			zap.S().Error("parse out_msgs failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		for _, out := range outMsgs {
+			if out.MsgType == tlb.MsgTypeInternal {
+				msg = out.AsInternal()
+				break
+			}
+		}
+	}
+
+	if msg == nil {
+		return nil, nil, fmt.Errorf("int_msg not found")
+	}
+
+	body, err := ParseMessageBody(msg.Body)
+	if err != nil {
+		zap.S().Error("parse message body failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	return msg, body, nil
+}
+
+// friendlyAddress formats the address like the address parser does for tonapi addresses, bounceable
+// for mainnet, an empty string when there is no address.
+func friendlyAddress(addr *address.Address) string {
+	if addr == nil || addr.Type() != address.StdAddress {
+		return ""
+	}
+
+	return address.NewAddress(0, byte(addr.Workchain()), addr.Data()).String()
+}
+
+// LoadLiteTransaction loads a transaction from its BOC, as liteservers and tonapi return it.
+func LoadLiteTransaction(boc []byte) (*LiteTransactionWrapper, error) {
+	c, err := cell.FromBOC(boc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transaction boc: %w", err)
+	}
+
+	var tx tlb.Transaction
+	if err = tlb.LoadFromCell(&tx, c.BeginParse()); err != nil {
+		return nil, fmt.Errorf("failed to load transaction: %w", err)
+	}
+	tx.Hash = c.Hash()
+
+	return &LiteTransactionWrapper{Transaction: &tx}, nil
+}
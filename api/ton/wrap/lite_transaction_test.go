@@ -0,0 +1,222 @@
+package ton_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
+	ton "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/tonkeeper/tonapi-go"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func TestLiteTransactionWrapper(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   tonapi.Transaction
+	}{
+		{name: "ton send tx", tx: tonSendTx},
+		{name: "jetton send tx", tx: jettonSendTx},
+		{name: "ton recv tx", tx: tonRecvTx},
+		{name: "jetton recv tx", tx: jettonRecvTx},
+		{name: "jetton excess tx", tx: jettonExcessTx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			boc, err := hex.DecodeString(tt.tx.Raw)
+			if err != nil {
+				t.Fatalf("decode raw transaction: %v", err)
+			}
+
+			tx, err := ton.LoadLiteTransaction(boc)
+			if err != nil {
+				t.Fatalf("LoadLiteTransaction() error = %v", err)
+			}
+			if got := hex.EncodeToString(tx.Hash); got != tt.tx.Hash {
+				t.Errorf("Hash = %v, want %v", got, tt.tx.Hash)
+			}
+
+			// the tonapi wrapper is the reference, both have to read the fixtures the same way
+			want := &ton.TransactionWrapper{Transaction: tt.tx}
+
+			wantAccount, _ := want.GetAccountAddress()
+			if got, err := tx.GetAccountAddress(); err != nil || got != wantAccount {
+				t.Errorf("GetAccountAddress() = %v, %v, want %v", got, err, wantAccount)
+			}
+
+			wantHash, _ := want.GetInMsgHash()
+			if got, err := tx.GetInMsgHash(); err != nil || got != wantHash {
+				t.Errorf("GetInMsgHash() = %v, %v, want %v", got, err, wantHash)
+			}
+
+			if got, want := tx.GetAmount(), want.GetAmount(); got != want {
+				t.Errorf("GetAmount() = %v, want %v", got, want)
+			}
+
+			if got, want := tx.GetComment(), want.GetComment(); got != want {
+				t.Errorf("GetComment() = %v, want %v", got, want)
+			}
+
+			wantAddresses, _ := want.GetTxAddresses()
+			if got, err := tx.GetTxAddresses(); err != nil || got != wantAddresses {
+				t.Errorf("GetTxAddresses() = %+v, %v, want %+v", got, err, wantAddresses)
+			}
+
+			if got, want := tx.GetDirection(), want.GetDirection(); got != want {
+				t.Errorf("GetDirection() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// internalTransfer is the message a jetton wallet sends to the jetton wallet of the recipient.
+type internalTransfer struct {
+	_               tlb.Magic        `tlb:"#178d4519"`
+	QueryID         uint64           `tlb:"## 64"`
+	Amount          tlb.Coins        `tlb:"."`
+	From            *address.Address `tlb:"addr"`
+	ResponseAddress *address.Address `tlb:"addr"`
+	ForwardAmount   tlb.Coins        `tlb:"."`
+	ForwardPayload  *cell.Cell       `tlb:"either . ^"`
+}
+
+func TestParseMessageBody(t *testing.T) {
+	owner := address.MustParseAddr("EQCYqk93_LQf4sDuTQk0yfmTpJARwvEv9eD2lHa5rYNmNZSF")
+	newOwner := address.MustParseAddr("EQCEm4lyCj-hujHyF9-GvprOQ84szIP5iF_rBlo0V3TdC_8X")
+	comment, _ := wallet.CreateCommentCell("nft")
+	memo, _ := wallet.CreateCommentCell("user 42")
+
+	tests := []struct {
+		name    string
+		payload any
+		want    ton.MessageBody
+	}{
+		{
+			name: "jetton burn",
+			payload: jetton.BurnPayload{
+				QueryID:             7,
+				Amount:              tlb.MustFromNano(big.NewInt(100000), 0),
+				ResponseDestination: owner,
+			},
+			want: ton.MessageBody{OpName: ton.DecodedOpNameJettonBurn, QueryID: 7, Amount: big.NewInt(100000)},
+		},
+		{
+			name: "jetton internal transfer",
+			payload: internalTransfer{
+				QueryID:         9,
+				Amount:          tlb.MustFromNano(big.NewInt(250), 0),
+				From:            owner,
+				ResponseAddress: owner,
+				ForwardAmount:   tlb.MustFromNano(big.NewInt(1), 9),
+				ForwardPayload:  memo,
+			},
+			want: ton.MessageBody{OpName: ton.DecodedOpNameJettonInternalTransfer, QueryID: 9, Amount: big.NewInt(250), Comment: "user 42"},
+		},
+		{
+			name: "nft transfer",
+			payload: nft.TransferPayload{
+				QueryID:             8,
+				NewOwner:            newOwner,
+				ResponseDestination: owner,
+				ForwardAmount:       tlb.MustFromNano(big.NewInt(1), 9),
+				ForwardPayload:      comment,
+			},
+			want: ton.MessageBody{OpName: ton.DecodedOpNameNftTransfer, QueryID: 8, Destination: newOwner, Comment: "nft"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tlb.ToCell(tt.payload)
+			if err != nil {
+				t.Fatalf("ToCell() error = %v", err)
+			}
+
+			got, err := ton.ParseMessageBody(c)
+			if err != nil {
+				t.Fatalf("ParseMessageBody() error = %v", err)
+			}
+			if got.OpName != tt.want.OpName || got.QueryID != tt.want.QueryID || got.Comment != tt.want.Comment {
+				t.Errorf("ParseMessageBody() = %+v, want %+v", got, tt.want)
+			}
+			if tt.want.Amount != nil && (got.Amount == nil || got.Amount.Cmp(tt.want.Amount) != 0) {
+				t.Errorf("Amount = %v, want %v", got.Amount, tt.want.Amount)
+			}
+			if tt.want.Destination != nil && (got.Destination == nil || !got.Destination.Equals(tt.want.Destination)) {
+				t.Errorf("Destination = %v, want %v", got.Destination, tt.want.Destination)
+			}
+		})
+	}
+}
+
+func TestLiteTransactionWrapperUnknownOp(t *testing.T) {
+	from := address.MustParseAddr("EQCYqk93_LQf4sDuTQk0yfmTpJARwvEv9eD2lHa5rYNmNZSF")
+	to := address.MustParseAddr("EQCEm4lyCj-hujHyF9-GvprOQ84szIP5iF_rBlo0V3TdC_8X")
+
+	tests := []struct {
+		name     string
+		body     *cell.Cell
+		opName   string
+		amount   string
+		transfer bool
+	}{
+		{name: "empty body", opName: "", amount: "1500000000", transfer: true},
+		{
+			name:   "contract call",
+			body:   cell.BeginCell().MustStoreUInt(0x12345678, 32).MustStoreUInt(1, 64).EndCell(),
+			opName: ton.DecodedOpNameUnknown,
+			amount: "0",
+		},
+		{
+			name:   "bounce",
+			body:   cell.BeginCell().MustStoreUInt(0xffffffff, 32).MustStoreUInt(0x0f8a7ea5, 32).EndCell(),
+			opName: ton.DecodedOpNameUnknown,
+			amount: "0",
+		},
+		{
+			name:   "body shorter than an op",
+			body:   cell.BeginCell().MustStoreUInt(1, 8).EndCell(),
+			opName: ton.DecodedOpNameUnknown,
+			amount: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ton.ParseMessageBody(tt.body)
+			if err != nil {
+				t.Fatalf("ParseMessageBody() error = %v", err)
+			}
+			if body.OpName != tt.opName {
+				t.Errorf("OpName = %q, want %q", body.OpName, tt.opName)
+			}
+
+			tx := &ton.LiteTransactionWrapper{Transaction: &tlb.Transaction{AccountAddr: to.Data()}}
+			tx.IO.In = &tlb.Message{MsgType: tlb.MsgTypeInternal, Msg: &tlb.InternalMessage{
+				SrcAddr: from,
+				DstAddr: to,
+				Amount:  tlb.MustFromTON("1.5"),
+				Body:    tt.body,
+			}}
+
+			if got := tx.GetAmount(); got != tt.amount {
+				t.Errorf("GetAmount() = %v, want %v", got, tt.amount)
+			}
+
+			// only messages without a body or with a comment are TON transfers
+			addresses, err := tx.GetTxAddresses()
+			if tt.transfer && (err != nil || addresses.To != to.String() || addresses.From != from.String()) {
+				t.Errorf("GetTxAddresses() = %+v, %v, want a transfer from %s to %s", addresses, err, from, to)
+			}
+			if !tt.transfer && err == nil {
+				t.Errorf("GetTxAddresses() = %+v, want an unsupported op error", addresses)
+			}
+		})
+	}
+}
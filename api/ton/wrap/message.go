@@ -0,0 +1,31 @@
+package ton
+
+import (
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// NormalizedExtMessageHash returns the TEP-467 hash of an external in message, the hash of the message with
+// no source, zero import fee, no state init and the body in a ref. Unlike the hash of the message itself it
+// does not change with how the sender filled those fields, so it identifies the message on every explorer.
+func NormalizedExtMessageHash(msg *tlb.ExternalMessage) []byte {
+	return normalizedExtMessageHash(msg.DstAddr, msg.Body)
+}
+
+func normalizedExtMessageHash(dst *address.Address, body *cell.Cell) []byte {
+	if body == nil {
+		body = cell.BeginCell().EndCell()
+	}
+
+	return cell.BeginCell().
+		MustStoreUInt(0b10, 2). // ext_in_msg_info$10
+		MustStoreUInt(0b00, 2). // src:addr_none$00
+		MustStoreAddr(dst).
+		MustStoreCoins(0).       // import_fee
+		MustStoreBoolBit(false). // init:nothing
+		MustStoreBoolBit(true).  // body:right
+		MustStoreRef(body).
+		EndCell().
+		Hash()
+}
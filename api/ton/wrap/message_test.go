@@ -0,0 +1,34 @@
+package ton_test
+
+import (
+	"bytes"
+	"testing"
+
+	ton "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+func TestNormalizedExtMessageHash(t *testing.T) {
+	dst := address.MustParseRawAddr("0:98aa4f77fcb41fe2c0ee4d0934c9f993a49011c2f12ff5e0f69476b9ad836635")
+	body := cell.BeginCell().MustStoreUInt(0xdeadbeef, 32).EndCell()
+
+	plain := &tlb.ExternalMessage{DstAddr: dst, Body: body}
+	withInit := &tlb.ExternalMessage{
+		DstAddr:   dst,
+		ImportFee: tlb.MustFromTON("0.01"),
+		StateInit: &tlb.StateInit{Code: cell.BeginCell().EndCell(), Data: cell.BeginCell().EndCell()},
+		Body:      body,
+	}
+
+	want := ton.NormalizedExtMessageHash(plain)
+	if got := ton.NormalizedExtMessageHash(withInit); !bytes.Equal(got, want) {
+		t.Errorf("NormalizedExtMessageHash() with state init and import fee = %x, want %x", got, want)
+	}
+
+	other := &tlb.ExternalMessage{DstAddr: dst, Body: cell.BeginCell().MustStoreUInt(0xcafe, 32).EndCell()}
+	if got := ton.NormalizedExtMessageHash(other); bytes.Equal(got, want) {
+		t.Errorf("NormalizedExtMessageHash() of another body = %x, want a different hash", got)
+	}
+}
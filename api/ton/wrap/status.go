@@ -0,0 +1,162 @@
+package ton
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/tonkeeper/tonapi-go"
+	"github.com/xssnick/tonutils-go/tlb"
+	"go.uber.org/zap"
+)
+
+type TransactionOutcome string
+
+const (
+	TransactionOutcomeSuccess TransactionOutcome = "success"
+	// the transaction was aborted and kept the value of the in message
+	TransactionOutcomeFailed TransactionOutcome = "failed"
+	// the transaction failed and sent the value back to the sender, or it received such a bounce
+	TransactionOutcomeBounced TransactionOutcome = "bounced"
+	// the transaction succeeded but some of the messages it had to send were skipped
+	TransactionOutcomePartial TransactionOutcome = "partial"
+)
+
+// TxStatus is the result of the phases of a transaction.
+type TxStatus struct {
+	Outcome TransactionOutcome
+	// changes of the transaction were rolled back
+	Aborted bool
+	// no code ran, the account is not deployed or the message cannot pay for gas
+	ComputeSkipped   bool
+	ComputeExitCode  int32
+	ActionResultCode int32
+	// actions that failed and were skipped because they were sent with the ignore errors mode
+	SkippedActions int32
+	// the in message asks to be sent back when the transaction fails
+	Bounce bool
+	// the in message is a failed message of the account sent back to it
+	Bounced bool
+	// nanotons sent back by the bounce of the in message, or received with a bounced in message
+	BouncedAmount string
+}
+
+// classify sets the outcome from the phases. A message to an account without code is credited even though
+// the transaction is aborted, it only fails when it bounces back.
+func (s *TxStatus) classify() {
+	switch {
+	case s.Bounced:
+		s.Outcome = TransactionOutcomeBounced
+	case s.Aborted && s.BouncedAmount != "0":
+		s.Outcome = TransactionOutcomeBounced
+	case s.Aborted && !s.ComputeSkipped:
+		s.Outcome = TransactionOutcomeFailed
+	case s.ActionResultCode != 0 || s.SkippedActions > 0:
+		s.Outcome = TransactionOutcomePartial
+	default:
+		s.Outcome = TransactionOutcomeSuccess
+	}
+}
+
+// GetStatus returns the result of the phases of the transaction and classifies it, only successful
+// transactions should be credited.
+func (tx *TransactionWrapper) GetStatus() (status TxStatus, err error) {
+	if !tx.InMsg.Set {
+		err = fmt.Errorf("in_msg not found")
+		return
+	}
+
+	status.Aborted = tx.Aborted
+
+	if phase, ok := tx.ComputePhase.Get(); ok {
+		status.ComputeSkipped = phase.Skipped
+		status.ComputeExitCode = phase.ExitCode.Value
+	}
+
+	if phase, ok := tx.ActionPhase.Get(); ok {
+		status.ActionResultCode = phase.ResultCode
+		status.SkippedActions = phase.SkippedActions
+	}
+
+	inMsg := tx.InMsg.Value
+	bouncedAmount := new(big.Int)
+	if inMsg.MsgType == tonapi.MessageMsgTypeIntMsg {
+		status.Bounce = inMsg.Bounce
+		status.Bounced = inMsg.Bounced
+
+		if inMsg.Bounced {
+			bouncedAmount.SetInt64(inMsg.Value)
+		}
+	}
+
+	if !status.Bounced {
+		for _, msg := range tx.OutMsgs {
+			if msg.MsgType == tonapi.MessageMsgTypeIntMsg && msg.Bounced {
+				bouncedAmount.Add(bouncedAmount, big.NewInt(msg.Value))
+			}
+		}
+	}
+
+	status.BouncedAmount = bouncedAmount.String()
+	status.classify()
+
+	return
+}
+
+// GetStatus returns the result of the phases of the transaction and classifies it, only successful
+// transactions should be credited.
+func (tx *LiteTransactionWrapper) GetStatus() (status TxStatus, err error) {
+	if tx.IO.In == nil {
+		err = fmt.Errorf("in_msg not found")
+		return
+	}
+
+	description, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok {
+		err = fmt.Errorf("not an ordinary transaction")
+		return
+	}
+
+	status.Aborted = description.Aborted
+
+	switch phase := description.ComputePhase.Phase.(type) {
+	case tlb.ComputePhaseVM:
+		status.ComputeExitCode = phase.Details.ExitCode
+	case tlb.ComputePhaseSkipped:
+		status.ComputeSkipped = true
+	}
+
+	if phase := description.ActionPhase; phase != nil {
+		status.ActionResultCode = phase.ResultCode
+		status.SkippedActions = int32(phase.SkippedActions)
+	}
+
+	bouncedAmount := new(big.Int)
+	if tx.IO.In.MsgType == tlb.MsgTypeInternal {
+		inMsg := tx.IO.In.AsInternal()
+		status.Bounce = inMsg.Bounce
+		status.Bounced = inMsg.Bounced
+
+		if inMsg.Bounced {
+			bouncedAmount.Set(inMsg.Amount.Nano())
+		}
+	}
+
+	if !status.Bounced && tx.IO.Out != nil {
+		outMsgs, err := tx.IO.Out.ToSlice()
+		if err != nil {
+			zap.S().Error("parse out_msgs failed", zap.Error(err))
+			return status, err
+		}
+
+		for _, msg := range outMsgs {
+			if msg.MsgType == tlb.MsgTypeInternal && msg.AsInternal().Bounced {
+				bouncedAmount.Add(bouncedAmount, msg.AsInternal().Amount.Nano())
+			}
+		}
+	}
+
+	status.BouncedAmount = bouncedAmount.String()
+	status.classify()
+
+	return
+}
@@ -0,0 +1,110 @@
+package ton_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	ton "github.com/openweb3-io/blockchain/api/ton/wrap"
+	"github.com/tonkeeper/tonapi-go"
+)
+
+func TestTransactionWrapper_GetStatus(t *testing.T) {
+	intMsg := func(value int64, bounce, bounced bool) tonapi.Message {
+		return tonapi.Message{MsgType: tonapi.MessageMsgTypeIntMsg, Value: value, Bounce: bounce, Bounced: bounced}
+	}
+	vm := func(exitCode int32) tonapi.OptComputePhase {
+		return tonapi.NewOptComputePhase(tonapi.ComputePhase{ExitCode: tonapi.NewOptInt32(exitCode)})
+	}
+
+	tests := []struct {
+		name string
+		tx   tonapi.Transaction
+		want ton.TxStatus
+	}{
+		{
+			name: "ton recv tx",
+			tx:   tonRecvTx,
+			want: ton.TxStatus{Outcome: ton.TransactionOutcomeSuccess, BouncedAmount: "0"},
+		},
+		{
+			name: "jetton transfer bounced by the jetton wallet",
+			tx: tonapi.Transaction{
+				Aborted:      true,
+				InMsg:        tonapi.NewOptMessage(intMsg(50000000, true, false)),
+				ComputePhase: vm(706),
+				OutMsgs:      []tonapi.Message{intMsg(45000000, false, true)},
+			},
+			want: ton.TxStatus{
+				Outcome: ton.TransactionOutcomeBounced, Aborted: true, ComputeExitCode: 706,
+				Bounce: true, BouncedAmount: "45000000",
+			},
+		},
+		{
+			name: "bounce received back",
+			tx: tonapi.Transaction{
+				InMsg:        tonapi.NewOptMessage(intMsg(45000000, false, true)),
+				ComputePhase: vm(0),
+			},
+			want: ton.TxStatus{Outcome: ton.TransactionOutcomeBounced, Bounced: true, BouncedAmount: "45000000"},
+		},
+		{
+			name: "non-bounceable message failed",
+			tx: tonapi.Transaction{
+				Aborted:      true,
+				InMsg:        tonapi.NewOptMessage(intMsg(10000000, false, false)),
+				ComputePhase: vm(65535),
+			},
+			want: ton.TxStatus{Outcome: ton.TransactionOutcomeFailed, Aborted: true, ComputeExitCode: 65535, BouncedAmount: "0"},
+		},
+		{
+			name: "deposit to an undeployed wallet",
+			tx: tonapi.Transaction{
+				Aborted:      true,
+				InMsg:        tonapi.NewOptMessage(intMsg(10000000, false, false)),
+				ComputePhase: tonapi.NewOptComputePhase(tonapi.ComputePhase{Skipped: true}),
+			},
+			want: ton.TxStatus{Outcome: ton.TransactionOutcomeSuccess, Aborted: true, ComputeSkipped: true, BouncedAmount: "0"},
+		},
+		{
+			name: "send skipped by the wallet",
+			tx: tonapi.Transaction{
+				InMsg:        tonapi.NewOptMessage(tonapi.Message{MsgType: tonapi.MessageMsgTypeExtInMsg}),
+				ComputePhase: vm(0),
+				ActionPhase:  tonapi.NewOptActionPhase(tonapi.ActionPhase{TotalActions: 1, SkippedActions: 1}),
+			},
+			want: ton.TxStatus{Outcome: ton.TransactionOutcomePartial, SkippedActions: 1, BouncedAmount: "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &ton.TransactionWrapper{Transaction: tt.tx}
+			got, err := tx.GetStatus()
+			if err != nil {
+				t.Fatalf("GetStatus() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiteTransactionWrapper_GetStatus(t *testing.T) {
+	for _, fixture := range []tonapi.Transaction{tonSendTx, tonRecvTx, jettonSendTx, jettonRecvTx, jettonExcessTx} {
+		boc, _ := hex.DecodeString(fixture.Raw)
+		tx, err := ton.LoadLiteTransaction(boc)
+		if err != nil {
+			t.Fatalf("LoadLiteTransaction() error = %v", err)
+		}
+
+		want, _ := (&ton.TransactionWrapper{Transaction: fixture}).GetStatus()
+		got, err := tx.GetStatus()
+		if err != nil {
+			t.Fatalf("GetStatus() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("GetStatus() of %s = %+v, want %+v", fixture.Hash, got, want)
+		}
+	}
+}
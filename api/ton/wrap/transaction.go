@@ -1,12 +1,16 @@
 package ton
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
+	"github.com/openweb3-io/blockchain/api"
+	"github.com/openweb3-io/blockchain/api/ton/wallet"
 	addr "github.com/openweb3-io/blockchain/pkg/address"
 	"github.com/tonkeeper/tonapi-go"
+	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tvm/cell"
 	"go.uber.org/zap"
 )
@@ -77,7 +81,14 @@ func (tx *TransactionWrapper) GetInMsgHash() (string, error) {
 			return "", err
 		}
 
-		return hex.EncodeToString(c.Hash()), nil
+		dst, err := address.ParseRawAddr(inMsg.Destination.Value.Address)
+		if err != nil {
+			zap.S().Error("parse destination failed", zap.Error(err))
+			return "", err
+		}
+
+		// external messages are identified by their normalized hash, the same one Transfer returns
+		return hex.EncodeToString(normalizedExtMessageHash(dst, c)), nil
 	}
 
 	return "", fmt.Errorf("unknown msg type: %v", inMsg.MsgType)
@@ -105,7 +116,7 @@ func (tx *TransactionWrapper) GetAmount() (amount string) {
 		}
 	}
 
-	if inMsg.OpCode.Value == OpCodeTonTransfer {
+	if inMsg.OpCode.Value == OpCodeTonTransfer || inMsg.OpCode.Value == OpCodeEncryptedComment {
 		amount = fmt.Sprintf("%v", inMsg.Value)
 		return
 	}
@@ -135,6 +146,9 @@ func (tx *TransactionWrapper) GetAmount() (amount string) {
 		case DecodedOpNameExcess:
 			// excess
 			amount = fmt.Sprintf("%v", inMsg.Value)
+		case DecodedOpNameNftTransfer, DecodedOpNameNftOwnershipAssigned:
+			// an nft is a single item
+			amount = "1"
 		}
 	}
 
@@ -186,6 +200,28 @@ func (tx *TransactionWrapper) GetComment() (memo string) {
 			if payload.ForwardPayload.Value.SumType == ForwardPayloadValueSumTypeTextComment {
 				memo = payload.ForwardPayload.Value.Value["text"].(string)
 			}
+		case DecodedOpNameNftTransfer:
+			// decode nft transfer
+			var payload NftTransferPayload
+			err := json.Unmarshal(inMsg.DecodedBody, &payload)
+			if err != nil {
+				zap.S().Error("parse nft transfer payload failed", zap.Error(err))
+				return
+			}
+			if payload.ForwardPayload.Value.SumType == ForwardPayloadValueSumTypeTextComment {
+				memo = payload.ForwardPayload.Value.Value["text"].(string)
+			}
+		case DecodedOpNameNftOwnershipAssigned:
+			// decode nft ownership assigned
+			var payload NftOwnershipAssignedPayload
+			err := json.Unmarshal(inMsg.DecodedBody, &payload)
+			if err != nil {
+				zap.S().Error("parse nft ownership assigned payload failed", zap.Error(err))
+				return
+			}
+			if payload.ForwardPayload.Value.SumType == ForwardPayloadValueSumTypeTextComment {
+				memo = payload.ForwardPayload.Value.Value["text"].(string)
+			}
 		case DecodedOpNameTextComment:
 			// decode text comment
 			var comment TextComment
@@ -201,6 +237,52 @@ func (tx *TransactionWrapper) GetComment() (memo string) {
 	return
 }
 
+// GetEncryptedComment decrypts the encrypted comment of a TON transfer to the account, the signer holds
+// the key of the account and the public key of the sender is read from its wallet. It returns an empty
+// string when the transfer has no encrypted comment.
+func (tx *TransactionWrapper) GetEncryptedComment(ctx context.Context, client wallet.TonAPI, signer api.Signer) (string, error) {
+	if !tx.InMsg.Set {
+		return "", nil
+	}
+
+	inMsg := tx.InMsg.Value
+	if inMsg.MsgType != tonapi.MessageMsgTypeIntMsg || inMsg.OpCode.Value != OpCodeEncryptedComment || !inMsg.RawBody.IsSet() {
+		return "", nil
+	}
+
+	bytes, err := hex.DecodeString(inMsg.RawBody.Value)
+	if err != nil {
+		zap.S().Error("decode rawbody to bytes failed", zap.Error(err))
+		return "", err
+	}
+
+	body, err := cell.FromBOC(bytes)
+	if err != nil {
+		zap.S().Error("build cell from boc failed", zap.Error(err))
+		return "", err
+	}
+
+	sender, err := address.ParseRawAddr(inMsg.Source.Value.Address)
+	if err != nil {
+		zap.S().Error("parse source failed", zap.Error(err))
+		return "", err
+	}
+
+	senderKey, err := wallet.GetPublicKey(ctx, client, sender)
+	if err != nil {
+		zap.S().Error("get sender public key failed", zap.Error(err), zap.String("address", inMsg.Source.Value.Address))
+		return "", err
+	}
+
+	comment, err := wallet.DecryptCommentCellWithSigner(ctx, body, sender, signer, senderKey)
+	if err != nil {
+		zap.S().Error("decrypt comment failed", zap.Error(err))
+		return "", err
+	}
+
+	return string(comment), nil
+}
+
 func (tx *TransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error) {
 	if !tx.InMsg.Set {
 		err = fmt.Errorf("in_msg not found")
@@ -209,6 +291,7 @@ func (tx *TransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error
 	var toAddressRaw string
 	var fromAddressRaw string
 	var jettonAddressRaw string
+	var nftAddressRaw string
 
 	inMsg := tx.InMsg.Value
 	if inMsg.MsgType != tonapi.MessageMsgTypeIntMsg {
@@ -230,7 +313,7 @@ func (tx *TransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error
 	}
 
 	// ton transfer
-	if inMsg.OpCode.Value == OpCodeTonTransfer {
+	if inMsg.OpCode.Value == OpCodeTonTransfer || inMsg.OpCode.Value == OpCodeEncryptedComment {
 		toAddressRaw = inMsg.Destination.Value.Address
 		fromAddressRaw = inMsg.Source.Value.Address
 	} else {
@@ -263,6 +346,28 @@ func (tx *TransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error
 				toAddressRaw = inMsg.Destination.Value.Address
 				fromAddressRaw = inMsg.Source.Value.Address
 				jettonAddressRaw = inMsg.Source.Value.Address
+			case DecodedOpNameNftTransfer:
+				// nft transfer
+				var payload NftTransferPayload
+				err = json.Unmarshal(inMsg.DecodedBody, &payload)
+				if err != nil {
+					zap.S().Error("parse nft transfer payload failed", zap.Error(err))
+					return
+				}
+				toAddressRaw = payload.NewOwner
+				fromAddressRaw = inMsg.Source.Value.Address
+				nftAddressRaw = inMsg.Destination.Value.Address
+			case DecodedOpNameNftOwnershipAssigned:
+				// nft ownership assigned
+				var payload NftOwnershipAssignedPayload
+				err = json.Unmarshal(inMsg.DecodedBody, &payload)
+				if err != nil {
+					zap.S().Error("parse nft ownership assigned payload failed", zap.Error(err))
+					return
+				}
+				nftAddressRaw = inMsg.Source.Value.Address
+				toAddressRaw = inMsg.Destination.Value.Address
+				fromAddressRaw = payload.PrevOwner
 			default:
 				zap.S().Error("not supported op_code, ignore", zap.String("op_code", inMsg.OpCode.Value))
 				err = fmt.Errorf("not supported op_code %s, ignore", inMsg.OpCode.Value)
@@ -303,10 +408,21 @@ func (tx *TransactionWrapper) GetTxAddresses() (addresses TxAddresses, err error
 		}
 	}
 
+	nftAddress := ""
+	if nftAddressRaw != "" {
+		nftAddress, err = parser.ParseRawAddress(nftAddressRaw)
+		if err != nil {
+			zap.S().Error("nftAddress parse failed",
+				zap.Error(err), zap.String("address", nftAddressRaw))
+			return
+		}
+	}
+
 	addresses = TxAddresses{
 		To:     toAddress,
 		From:   fromAddress,
 		Jetton: jettonAddress,
+		Nft:    nftAddress,
 	}
 
 	return
@@ -2,12 +2,14 @@ package ton_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	ton "github.com/openweb3-io/blockchain/api/ton/wrap"
 	"github.com/tonkeeper/tonapi-go"
+	"github.com/xssnick/tonutils-go/address"
 )
 
 const (
@@ -49,12 +51,12 @@ func TestTransactionWrapper_GetInMsgHash(t *testing.T) {
 		{
 			name: "ton send tx",
 			tx:   tonSendTx,
-			want: "672e150a1cb104d94eca79763a77061abc5144dbd4634ae95783e31c5d237b5c",
+			want: "550ed6a6e602af7a2340be86e0409d54657c8dea11219da0ee8f995f121244ef",
 		},
 		{
 			name: "jetton send tx",
 			tx:   jettonSendTx,
-			want: "71ecfb1983c950c841c48f161b856225c9459a924e6669783c07aeb32336e46d",
+			want: "f58a77690b16c1c3de8add9fd1b2b983a1d2a919f2cdb0ac2aeb34f72c9afce8",
 		},
 		{
 			name: "ton recv tx",
@@ -251,3 +253,69 @@ func TestTransactionWrapper_GetFromToAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionWrapper_Nft(t *testing.T) {
+	const (
+		owner    = "EQCYqk93_LQf4sDuTQk0yfmTpJARwvEv9eD2lHa5rYNmNZSF"
+		newOwner = "EQCEm4lyCj-hujHyF9-GvprOQ84szIP5iF_rBlo0V3TdC_8X"
+		item     = "EQCcoiXh-f3qjc2-QDjLh3XmwiNZmqRd2l5IX-_loNspojTy"
+		comment  = `{"is_right":false,"value":{"sum_type":"TextComment","op_code":0,"value":{"text":"nft"}}}`
+	)
+	raw := func(addr string) string {
+		a := address.MustParseAddr(addr)
+		return fmt.Sprintf("%d:%x", a.Workchain(), a.Data())
+	}
+
+	message := func(src, dst, opName, body string) tonapi.Message {
+		msg := tonapi.Message{
+			MsgType:       tonapi.MessageMsgTypeIntMsg,
+			Source:        tonapi.NewOptAccountAddress(tonapi.AccountAddress{Address: raw(src)}),
+			Destination:   tonapi.NewOptAccountAddress(tonapi.AccountAddress{Address: raw(dst)}),
+			DecodedOpName: tonapi.NewOptString(opName),
+		}
+		msg.DecodedBody = append(msg.DecodedBody, body...)
+		return msg
+	}
+
+	tests := []struct {
+		name string
+		msg  tonapi.Message
+		want ton.TxAddresses
+	}{
+		{
+			name: "nft transfer",
+			msg: message(owner, item, ton.DecodedOpNameNftTransfer, fmt.Sprintf(
+				`{"query_id":1,"new_owner":%q,"response_destination":%q,"custom_payload":null,"forward_amount":"1","forward_payload":%s}`,
+				raw(newOwner), raw(owner), comment)),
+			want: ton.TxAddresses{From: owner, To: newOwner, Nft: item},
+		},
+		{
+			name: "nft ownership assigned",
+			msg: message(item, newOwner, ton.DecodedOpNameNftOwnershipAssigned, fmt.Sprintf(
+				`{"query_id":1,"prev_owner":%q,"forward_payload":%s}`, raw(owner), comment)),
+			want: ton.TxAddresses{From: owner, To: newOwner, Nft: item},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &ton.TransactionWrapper{
+				Transaction: tonapi.Transaction{InMsg: tonapi.NewOptMessage(tt.msg)},
+			}
+
+			got, err := tx.GetTxAddresses()
+			if err != nil {
+				t.Fatalf("GetTxAddresses() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTxAddresses() = %+v, want %+v", got, tt.want)
+			}
+			if amount := tx.GetAmount(); amount != "1" {
+				t.Errorf("GetAmount() = %v, want 1", amount)
+			}
+			if memo := tx.GetComment(); memo != "nft" {
+				t.Errorf("GetComment() = %v, want nft", memo)
+			}
+		})
+	}
+}
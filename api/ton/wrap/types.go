@@ -8,11 +8,21 @@ type TransactionDirection string
 
 const (
 	OpCodeTonTransfer = "0x00000000"
+	// TON transfer with a comment only the recipient can read
+	OpCodeEncryptedComment = "0x2167da4b"
 
-	DecodedOpNameJettonTransfer = "jetton_transfer"
-	DecodedOpNameJettonNotify   = "jetton_notify"
-	DecodedOpNameTextComment    = "text_comment"
-	DecodedOpNameExcess         = "excess"
+	DecodedOpNameJettonTransfer         = "jetton_transfer"
+	DecodedOpNameJettonNotify           = "jetton_notify"
+	DecodedOpNameJettonInternalTransfer = "jetton_internal_transfer"
+	DecodedOpNameTextComment            = "text_comment"
+	DecodedOpNameExcess                 = "excess"
+	DecodedOpNameJettonBurn             = "jetton_burn"
+	DecodedOpNameEncryptedTextComment   = "encrypted_text_comment"
+	// TEP-62 transfer request sent by the owner to the item, and the notification the item sends to the new owner
+	DecodedOpNameNftTransfer          = "nft_transfer"
+	DecodedOpNameNftOwnershipAssigned = "nft_ownership_assigned"
+	// ParseMessageBody does not decode the op, like bounces and calls of other contracts
+	DecodedOpNameUnknown = "unknown"
 
 	ForwardPayloadValueSumTypeTextComment = "TextComment"
 
@@ -37,6 +47,21 @@ type JettonNotifyPayload struct {
 	ForwardPayload ForwardPayload `json:"forward_payload"`
 }
 
+type NftTransferPayload struct {
+	QueryID             uint64         `json:"query_id"`
+	NewOwner            string         `json:"new_owner"`
+	ResponseDestination string         `json:"response_destination"`
+	CustomPayload       *cell.Cell     `json:"custom_payload"`
+	ForwardAmount       string         `json:"forward_amount"`
+	ForwardPayload      ForwardPayload `json:"forward_payload"`
+}
+
+type NftOwnershipAssignedPayload struct {
+	QueryID        uint64         `json:"query_id"`
+	PrevOwner      string         `json:"prev_owner"`
+	ForwardPayload ForwardPayload `json:"forward_payload"`
+}
+
 type ForwardPayload struct {
 	IsRight bool                `json:"is_right"`
 	Value   ForwardPayloadValue `json:"value"`
@@ -56,4 +81,5 @@ type TxAddresses struct {
 	From   string `json:"from"`
 	To     string `json:"to"`
 	Jetton string `json:"jetton"` // jetton involved address, it is source address when jetton_notify or destination address when jetton_transfer
+	Nft    string `json:"nft"`    // nft item address, it is source address when nft_ownership_assigned or destination address when nft_transfer
 }
@@ -41,6 +41,36 @@ var (
 		Code:    12, //nolint
 		Message: "Invalid address",
 	}
+
+	ErrInsufficientBalance = &Error{
+		Code:    13, //nolint
+		Message: "Insufficient balance",
+	}
+
+	ErrBelowRentExemption = &Error{
+		Code:    14, //nolint
+		Message: "Account balance below rent exemption",
+	}
+
+	ErrInvalidMemo = &Error{
+		Code:    15, //nolint
+		Message: "Invalid memo",
+	}
+
+	ErrNotOwner = &Error{
+		Code:    16, //nolint
+		Message: "Not the owner",
+	}
+
+	ErrInvalidAmount = &Error{
+		Code:    17, //nolint
+		Message: "Invalid amount",
+	}
+
+	ErrUnsupportedFeePayer = &Error{
+		Code:    18, //nolint
+		Message: "Fee payer not supported",
+	}
 )
 
 // wrapErr adds details to the types.Error provided. We use a function
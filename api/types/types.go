@@ -9,9 +9,13 @@ type TokenSymbol string
 const (
 	TOKEN_TYPE_NONE TokenSymbol = ""
 	TOKEN_TYPE_TON  TokenSymbol = "TON"
+	TOKEN_TYPE_SOL  TokenSymbol = "SOL"
+	// TON NFT item (TEP-62), the contract address is the address of the item
+	TOKEN_TYPE_NFT TokenSymbol = "NFT"
 
-	// need to be adjusted according to the actual situation
-	JettonForwardAmount             = "0.01"
+	// default TON forwarded to the recipient of a jetton transfer with the transfer notification
+	JettonForwardAmount = "0.01"
+	// Deprecated: the attached TON is derived from the gas the jetton wallets need, or set by JettonTransferOptions
 	JettonTransferAttachedTonAmount = "0.05"
 )
 
@@ -30,6 +34,48 @@ type TransferInput struct {
 	GasLimit        *big.Int
 
 	FeePayer string
+	// the fee payer signs later, PrepareTransaction leaves its signature empty for a sponsor to add,
+	// otherwise it signs with the signer of the fee payer
+	SponsoredFee bool
+	// durable nonce account used instead of a recent blockhash, its authority must be the fee payer
+	NonceAccount string
+
+	// options of TON jetton transfers, nil keeps the defaults
+	Jetton *JettonTransferOptions
+	// options of TON NFT transfers, nil keeps the defaults
+	Nft *NftTransferOptions
+}
+
+// JettonTransferOptions tunes a TON jetton transfer, zero values keep the defaults.
+type JettonTransferOptions struct {
+	// TON forwarded to the recipient with the transfer notification, JettonForwardAmount when nil,
+	// no notification is sent when it is zero
+	ForwardAmount *big.Int
+	// TON attached to the transfer, what the jetton wallets do not spend comes back to the response
+	// destination. By default it is derived from the gas the jetton wallets need, it cannot be less
+	AttachedAmount *big.Int
+	// receives the excess TON, the sender by default
+	ResponseDestination string
+	// BOC of the custom payload passed to the sender jetton wallet
+	CustomPayload []byte
+	// BOC of the forward payload passed to the recipient, it replaces the memo comment
+	ForwardPayload []byte
+}
+
+// NftTransferOptions tunes a TON NFT transfer, zero values keep the defaults.
+type NftTransferOptions struct {
+	// TON forwarded to the new owner with the ownership_assigned notification, one nanoton when nil,
+	// no notification is sent when it is zero
+	ForwardAmount *big.Int
+	// TON attached to the transfer, what the item does not spend comes back to the response destination.
+	// By default it is the forward amount plus the gas of the item, it cannot be less
+	AttachedAmount *big.Int
+	// receives the excess TON, the sender by default
+	ResponseDestination string
+	// BOC of the custom payload passed to the item
+	CustomPayload []byte
+	// BOC of the forward payload passed to the new owner, it replaces the memo comment
+	ForwardPayload []byte
 }
 
 type TransferOutput struct {
@@ -46,4 +92,5 @@ type WalletData struct {
 	OwnerAddress        string
 	JettonMasterAddress string
 	JettonTokenName     string
+	JettonDecimals      int32
 }
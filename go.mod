@@ -7,6 +7,7 @@ toolchain go1.22.7
 require (
 	github.com/ethereum/go-ethereum v1.14.9
 	github.com/joho/godotenv v1.5.1
+	github.com/mr-tron/base58 v1.2.0
 	github.com/openweb3-io/solana-go-sdk v0.0.0-20240718105718-bed0045ca49f
 	github.com/openweb3-io/tonapi-go v0.0.0-20240708055252-d4f10935fc18
 	github.com/stretchr/testify v1.9.0
@@ -47,7 +48,6 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
-	github.com/mr-tron/base58 v1.2.0 // indirect
 	github.com/oasisprotocol/curve25519-voi v0.0.0-20220328075252-7dd334e3daae // indirect
 	github.com/ogen-go/ogen v0.77.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect